@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"lexin-sqlite/internal/dictd"
+	"lexin-sqlite/pkg/lexin"
+)
+
+// runDictd implements "lexin dictd", the DICT protocol (RFC 2229) server
+func runDictd(args []string) error {
+	fs := flag.NewFlagSet("dictd", flag.ExitOnError)
+	dbPath := fs.String("db", "lexin.db", "Path to the SQLite database file")
+	addr := fs.String("addr", ":2628", "Address to listen on")
+	hostname := fs.String("hostname", "", "Host name announced to clients (the system host name when empty)")
+	matchLimit := fs.Int("match-limit", 200, "Maximum number of MATCH results per database")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage of %s dictd:\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "  %s dictd [-db <database-path>] [-addr <address>]\n\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "Each dictionary is served as a DICT database named after its languages,\n")
+		fmt.Fprintf(fs.Output(), "such as swe-eng. Query it with any dictd client:\n")
+		fmt.Fprintf(fs.Output(), "  dict -h localhost -d swe-eng huset\n")
+		fmt.Fprintf(fs.Output(), "  dict -h localhost -s prefix -m bo\n\n")
+		fmt.Fprintln(fs.Output(), "Flags:")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	db, err := lexin.OpenReadOnly(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	l, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		log.Printf("Shutting down")
+		l.Close()
+	}()
+
+	srv := dictd.New(db, dictd.Options{
+		Hostname:   *hostname,
+		Info:       fmt.Sprintf("lexin-sqlite %s serving %s", Version, *dbPath),
+		MatchLimit: *matchLimit,
+	})
+	log.Printf("Serving %s over DICT on %s", *dbPath, l.Addr())
+	return srv.Serve(l)
+}
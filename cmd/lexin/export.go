@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"lexin-sqlite/internal/export"
+	"lexin-sqlite/pkg/lexin"
+)
+
+// exportJob is what an export format needs to write its output
+type exportJob struct {
+	db     *lexin.DB
+	dict   *lexin.Dictionary
+	filter lexin.EntryFilter
+	// output is the -o flag, "-" for standard output
+	output string
+	anki   export.AnkiOptions
+	// stardict configures the stardict format
+	stardict export.StarDictOptions
+	// kindle configures the kindle format
+	kindle export.KindleOptions
+	// yomitan configures the yomitan format
+	yomitan export.YomitanOptions
+	// csv configures the csv and tsv formats
+	csv export.CSVOptions
+}
+
+// exportFormat is a format of lexin export
+type exportFormat struct {
+	name        string
+	description string
+	run         func(ctx context.Context, job exportJob) error
+}
+
+// exportFormats lists the formats of lexin export
+var exportFormats = []exportFormat{
+	{"xml", "Lexin XML, the format dictionaries are imported from", func(ctx context.Context, job exportJob) error {
+		return job.stream(func(w io.Writer) error {
+			return export.XML(ctx, w, job.db, job.dict, job.filter)
+		})
+	}},
+	{"json", "A JSON array with one object per headword", func(ctx context.Context, job exportJob) error {
+		return job.stream(func(w io.Writer) error {
+			return export.JSON(ctx, w, job.db, job.dict, job.filter)
+		})
+	}},
+	{"jsonl", "JSON Lines, one object per headword and line", func(ctx context.Context, job exportJob) error {
+		return job.stream(func(w io.Writer) error {
+			return export.JSONLines(ctx, w, job.db, job.dict, job.filter)
+		})
+	}},
+	{"csv", "A table with a row per meaning, for spreadsheets", func(ctx context.Context, job exportJob) error {
+		return job.stream(func(w io.Writer) error {
+			return export.CSV(ctx, w, job.db, job.dict, job.filter, job.csv)
+		})
+	}},
+	{"tsv", "The csv table separated by tabs", func(ctx context.Context, job exportJob) error {
+		if job.csv.Delimiter == 0 {
+			job.csv.Delimiter = '\t'
+		}
+		return job.stream(func(w io.Writer) error {
+			return export.CSV(ctx, w, job.db, job.dict, job.filter, job.csv)
+		})
+	}},
+	{"tei", "TEI Lex-0 XML for academic interchange", func(ctx context.Context, job exportJob) error {
+		return job.stream(func(w io.Writer) error {
+			return export.TEI(ctx, w, job.db, job.dict, job.filter)
+		})
+	}},
+	{"anki", "Anki package (.apkg) with a deck per dictionary", func(ctx context.Context, job exportJob) error {
+		return job.stream(func(w io.Writer) error {
+			return export.Anki(ctx, w, job.db, job.dict, job.filter, job.anki)
+		})
+	}},
+	{"stardict", "StarDict files (.ifo, .idx, .dict.dz, .syn), -o is the path without extension", func(ctx context.Context, job exportJob) error {
+		return export.StarDict(ctx, job.basePath(), job.db, job.dict, job.filter, job.stardict)
+	}},
+	{"yomitan", "Yomitan dictionary zip for reading in the browser", func(ctx context.Context, job exportJob) error {
+		return job.stream(func(w io.Writer) error {
+			return export.Yomitan(ctx, w, job.db, job.dict, job.filter, job.yomitan)
+		})
+	}},
+	{"kindle", "Kindle dictionary source (content.opf and XHTML), -o is the directory", func(ctx context.Context, job exportJob) error {
+		return export.Kindle(ctx, job.basePath(), job.db, job.dict, job.filter, job.kindle)
+	}},
+}
+
+// runExport implements "lexin export"
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	dbPath := fs.String("db", "lexin.db", "Path to the SQLite database file")
+	format := fs.String("format", "xml", "Output format")
+	target := fs.String("target", "", "Target language code of the dictionary to export (required when the database holds several, except for json and jsonl)")
+	output := fs.String("o", "-", "Output file, - for standard output")
+	wordType := fs.String("type", "", "Only export words of this type, such as subst.")
+	prefix := fs.String("prefix", "", "Only export headwords starting with this prefix")
+	ankiDirection := fs.String("direction", export.AnkiRecognition, "anki: card direction, recognition, production or both")
+	ankiDeck := fs.String("deck", "Lexin {base}-{target}", "anki: deck name, {base}, {target} and {version} are replaced")
+	ankiMedia := fs.String("media", "", "anki: directory holding the audio files named in the phonetics")
+	title := fs.String("title", "Lexin {base}-{target}", "stardict, kindle, yomitan: dictionary title, {base}, {target} and {version} are replaced")
+	author := fs.String("author", "Lexin", "kindle: author in the book metadata")
+	entriesPerFile := fs.Int("entries-per-file", 1000, "kindle: headwords in each XHTML file")
+	synonyms := fs.Bool("syn", true, "stardict: write a .syn file leading inflected forms to their headword")
+	columns := fs.String("columns", strings.Join(export.CSVColumns, ","), "csv, tsv: comma separated columns, from dictionary, id, variant_id, headword, variant, type, meaning, graminfo, inflections, phonetic, translation, synonym, example and example_translation")
+	delimiter := fs.String("delimiter", "", "csv, tsv: field delimiter, a single character or \"tab\" (default \",\" for csv and tab for tsv)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage of %s export:\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "  %s export -format <format> [-db <database-path>] [-target <language-code>] [-o <output>]\n\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "Formats:\n")
+		for _, f := range exportFormats {
+			fmt.Fprintf(fs.Output(), "  %-10s %s\n", f.name, f.description)
+		}
+		fmt.Fprintf(fs.Output(), "\nExamples:\n")
+		fmt.Fprintf(fs.Output(), "  %s export -format xml -target english -o swedishenglish.xml\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "  %s export -format jsonl -type verb -prefix ö > verbs.jsonl\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "  %s export -format anki -direction both -type subst. -o nouns.apkg\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "  %s export -format stardict -target english -o stardict/lexin-swe-eng\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "  %s export -format kindle -target english -o kindle-swe-eng\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "  %s export -format csv -columns headword,type,translation -delimiter ';' -o words.csv\n\n", os.Args[0])
+		fmt.Fprintln(fs.Output(), "Flags:")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	var chosen *exportFormat
+	for i := range exportFormats {
+		if exportFormats[i].name == strings.ToLower(*format) {
+			chosen = &exportFormats[i]
+		}
+	}
+	if chosen == nil {
+		fs.Usage()
+		return fmt.Errorf("unknown export format: %s", *format)
+	}
+
+	csvOptions := export.CSVOptions{Columns: strings.Split(*columns, ",")}
+	if *delimiter != "" {
+		d, err := parseDelimiter(*delimiter)
+		if err != nil {
+			return err
+		}
+		csvOptions.Delimiter = d
+	}
+
+	db, err := openLexin(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	dict, err := selectDictionary(ctx, db, *target)
+	if err != nil {
+		return err
+	}
+	if dict == nil {
+		// A database holding a single dictionary needs no -target
+		dictionaries, err := db.Dictionaries(ctx)
+		if err != nil {
+			return err
+		}
+		if len(dictionaries) == 1 {
+			dict = &dictionaries[0]
+		}
+	}
+
+	return chosen.run(ctx, exportJob{
+		db:     db,
+		dict:   dict,
+		filter: lexin.EntryFilter{Type: *wordType, Prefix: *prefix},
+		output: *output,
+		anki: export.AnkiOptions{
+			Direction: *ankiDirection,
+			DeckName:  *ankiDeck,
+			MediaDir:  *ankiMedia,
+		},
+		stardict: export.StarDictOptions{
+			BookName: *title,
+			Synonyms: *synonyms,
+		},
+		kindle: export.KindleOptions{
+			Title:          *title,
+			Author:         *author,
+			EntriesPerFile: *entriesPerFile,
+		},
+		yomitan: export.YomitanOptions{Title: *title},
+		csv:     csvOptions,
+	})
+}
+
+// parseDelimiter reads the -delimiter flag, a single character or "tab"
+func parseDelimiter(value string) (rune, error) {
+	if value == "tab" || value == `\t` {
+		return '\t', nil
+	}
+	runes := []rune(value)
+	if len(runes) != 1 {
+		return 0, fmt.Errorf("delimiter must be a single character: %q", value)
+	}
+	return runes[0], nil
+}
+
+// stream runs write on the output file, or on standard output for "-"
+func (job exportJob) stream(write func(w io.Writer) error) error {
+	if job.output == "" || job.output == "-" {
+		return write(os.Stdout)
+	}
+
+	file, err := os.Create(job.output)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// basePath is the output path of formats made of several files: the path
+// the stardict files add their extensions to, or the kindle directory.
+// Without -o it is "lexin-" and the dictionary name.
+func (job exportJob) basePath() string {
+	if job.output != "" && job.output != "-" {
+		return job.output
+	}
+	if job.dict == nil {
+		return "lexin"
+	}
+	return "lexin-" + job.dict.Name()
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"lexin-sqlite/internal/config"
+	"lexin-sqlite/internal/database"
+	"lexin-sqlite/internal/parser"
+	"lexin-sqlite/internal/repository"
+)
+
+// runImport implements "lexin import", which is also what runs when only
+// flags are given
+func runImport(args []string) error {
+	// Load configuration
+	cfg, err := config.Load(args)
+	if err != nil {
+		return fmt.Errorf("loading configuration: %w", err)
+	}
+
+	if cfg.ShowVersion {
+		printVersion()
+		return nil
+	}
+
+	// Open database
+	db, err := database.New(cfg.DBPath)
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer db.Close()
+
+	// Create repository
+	repo := repository.New(db)
+
+	// Open XML file for streaming
+	log.Printf("Reading XML file: %s", cfg.XMLFile)
+	reader, err := parser.OpenXMLFile(cfg.XMLFile)
+	if err != nil {
+		return fmt.Errorf("parsing XML file: %w", err)
+	}
+	defer reader.Close()
+	header := reader.Header()
+
+	// Verify target language
+	if header.TargetLang != cfg.TargetLang {
+		log.Printf("Warning: XML file has target language '%s', but you specified '%s'", header.TargetLang, cfg.TargetLang)
+	}
+
+	// Store data in database while the file is being decoded
+	log.Printf("Storing data in SQLite database: %s", cfg.DBPath)
+	startTime := time.Now()
+	var stored int
+	switch cfg.Mode {
+	case config.ModeUpdate:
+		report, err := repo.UpdateSource(context.Background(), reader)
+		if err != nil {
+			return fmt.Errorf("updating dictionary: %w", err)
+		}
+		stored = len(report.Added) + len(report.Changed) + report.Unchanged
+		if err := report.WriteSummary(os.Stdout); err != nil {
+			return fmt.Errorf("writing change report: %w", err)
+		}
+		if cfg.ReportPath != "" {
+			if err := writeReport(cfg.ReportPath, report); err != nil {
+				return fmt.Errorf("writing change report: %w", err)
+			}
+			log.Printf("Change report written to %s", cfg.ReportPath)
+		}
+	default:
+		stats, err := repo.UpsertSource(context.Background(), reader)
+		if err != nil {
+			return fmt.Errorf("storing dictionary: %w", err)
+		}
+		stored = stats.Total() - stats.Repeated
+		log.Printf("Inserted %d, replaced %d, unchanged %d words, skipped %d repeated", stats.Inserted, stats.Replaced, stats.Unchanged, stats.Repeated)
+	}
+
+	dictID := getDictID(db, header.BaseLang, header.TargetLang)
+
+	// Resolve references, antonyms and MatchingIDs now that every word is
+	// stored
+	links, err := repo.LinkDictionary(context.Background(), dictID)
+	if err != nil {
+		return fmt.Errorf("linking dictionary: %w", err)
+	}
+	log.Printf("Resolved %d links, %d unresolved", links.Resolved, len(links.Unresolved))
+	if cfg.LinkReportPath != "" {
+		if err := writeReport(cfg.LinkReportPath, links); err != nil {
+			return fmt.Errorf("writing link report: %w", err)
+		}
+		log.Printf("Link report written to %s", cfg.LinkReportPath)
+	}
+
+	// Get entry count
+	entryCount, err := db.CountDictionaryEntries(context.Background(), dictID)
+	if err != nil {
+		log.Printf("Error counting entries: %v", err)
+		entryCount = int64(stored)
+	}
+
+	log.Printf("Successfully imported %d entries in %v", entryCount, time.Since(startTime))
+	log.Printf("Dictionary from %s to %s is now available in %s", header.BaseLang, header.TargetLang, cfg.DBPath)
+
+	return nil
+}
+
+func getDictID(db *database.DB, baseLang, targetLang string) int64 {
+	dictID, _, _, _, err := db.GetDictionaryByLanguages(context.Background(), baseLang, targetLang)
+	if err != nil {
+		return 0
+	}
+	return dictID
+}
+
+// writeReport writes an update change report or a link report as indented
+// JSON
+func writeReport(path string, report any) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
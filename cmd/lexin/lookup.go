@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"lexin-sqlite/pkg/lexin"
+)
+
+// runLookup implements "lexin lookup", printing the entries of a Swedish
+// word or, with -search, the full-text matches of a query
+func runLookup(args []string) error {
+	fs := flag.NewFlagSet("lookup", flag.ExitOnError)
+	dbPath := fs.String("db", "lexin.db", "Path to the SQLite database file")
+	target := fs.String("target", "", "Target language code of the dictionary to search (all when empty)")
+	asJSON := fs.Bool("json", false, "Print the entries as JSON, or when none are found, an object with the empty entries and suggestions")
+	fullText := fs.Bool("search", false, "Run a full-text search instead of a headword lookup")
+	limit := fs.Int("limit", 20, "Maximum number of full-text search results")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage of %s lookup:\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "  %s lookup [-db <database-path>] [-target <language-code>] [-json] <word>\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "  %s lookup -search [-db <database-path>] [-target <language-code>] <query>\n\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "Examples:\n")
+		fmt.Fprintf(fs.Output(), "  %s lookup -target english huset\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "  %s lookup -search 'bo*'\n\n", os.Args[0])
+		fmt.Fprintln(fs.Output(), "Flags:")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	word := strings.Join(fs.Args(), " ")
+	if word == "" {
+		fs.Usage()
+		return fmt.Errorf("a word to look up is required")
+	}
+
+	db, err := openLexin(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	dict, err := selectDictionary(ctx, db, *target)
+	if err != nil {
+		return err
+	}
+
+	if *fullText {
+		hits, err := db.Search(ctx, dict, word, lexin.SearchOptions{Limit: *limit})
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			return printJSON(hits)
+		}
+		if len(hits) == 0 {
+			fmt.Printf("No matches for %q\n", word)
+			return nil
+		}
+		for _, hit := range hits {
+			fmt.Printf("%-20s %-10s %-12s %s\n", hit.Word, hit.WordType, hit.Source, hit.Snippet)
+		}
+		return nil
+	}
+
+	entries, err := db.Lookup(ctx, dict, word)
+	if err != nil {
+		return err
+	}
+
+	var suggestions []lexin.Suggestion
+	if len(entries) == 0 {
+		suggestions, err = db.Suggest(ctx, dict, word, lexin.SuggestOptions{})
+		if err != nil {
+			return err
+		}
+	}
+
+	if *asJSON {
+		return writeLookupJSON(os.Stdout, entries, suggestions)
+	}
+	if len(entries) == 0 {
+		fmt.Printf("No entries found for %q\n", word)
+		if len(suggestions) > 0 {
+			values := make([]string, 0, len(suggestions))
+			for _, s := range suggestions {
+				values = append(values, s.Value)
+			}
+			fmt.Printf("Did you mean: %s\n", strings.Join(values, ", "))
+		}
+		return nil
+	}
+
+	for i, entry := range entries {
+		if i > 0 {
+			fmt.Println()
+		}
+		printEntry(entry)
+	}
+
+	return nil
+}
+
+// printEntry prints an entry in a compact, human readable layout
+func printEntry(entry lexin.Entry) {
+	fmt.Printf("%s (%s)", entry.Value, entry.Type)
+	if entry.Form != nil {
+		fmt.Printf("  [%s: %s]", entry.Form.Source, entry.Form.Form)
+	}
+	fmt.Println()
+
+	for _, base := range entry.BaseLangs {
+		if base.Meaning.Content != "" {
+			fmt.Printf("  %s\n", base.Meaning.Content)
+		}
+		for _, inflection := range base.Inflections {
+			fmt.Printf("  böjning: %s\n", inflection.Content)
+		}
+		for _, example := range base.Examples {
+			fmt.Printf("  ex: %s\n", example.Content)
+		}
+		for _, idiom := range base.Idioms {
+			fmt.Printf("  uttryck: %s\n", idiom.Content)
+		}
+	}
+	for _, target := range entry.TargetLangs {
+		for _, translation := range target.Translations {
+			fmt.Printf("  = %s\n", translation)
+		}
+		for _, synonym := range target.Synonyms {
+			fmt.Printf("  ≈ %s\n", synonym)
+		}
+	}
+}
+
+// writeLookupJSON writes the entries found for a word as a JSON array, or
+// when there are none, an object holding the empty entries and the "did you
+// mean" suggestions
+func writeLookupJSON(w io.Writer, entries []lexin.Entry, suggestions []lexin.Suggestion) error {
+	if len(entries) > 0 {
+		return writeJSON(w, entries)
+	}
+	if suggestions == nil {
+		suggestions = []lexin.Suggestion{}
+	}
+	return writeJSON(w, struct {
+		Entries     []lexin.Entry      `json:"entries"`
+		Suggestions []lexin.Suggestion `json:"suggestions"`
+	}{[]lexin.Entry{}, suggestions})
+}
+
+// printJSON writes v to standard output as indented JSON
+func printJSON(v interface{}) error {
+	return writeJSON(os.Stdout, v)
+}
+
+// writeJSON writes v to w as indented JSON
+func writeJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
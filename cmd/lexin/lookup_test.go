@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"lexin-sqlite/pkg/lexin"
+)
+
+func TestWriteLookupJSON(t *testing.T) {
+	t.Run("entries", func(t *testing.T) {
+		var out bytes.Buffer
+		entries := []lexin.Entry{{ID: 1, Value: "hus", Type: "subst."}}
+		if err := writeLookupJSON(&out, entries, nil); err != nil {
+			t.Fatalf("writeLookupJSON failed: %v", err)
+		}
+
+		var got []lexin.Entry
+		if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+			t.Fatalf("output is not an array of entries: %v\n%s", err, out.String())
+		}
+		if len(got) != 1 || got[0].Value != "hus" {
+			t.Errorf("entries = %+v", got)
+		}
+	})
+
+	t.Run("suggestions", func(t *testing.T) {
+		var out bytes.Buffer
+		suggestions := []lexin.Suggestion{{WordID: 1, Value: "hus", Distance: 1}}
+		if err := writeLookupJSON(&out, nil, suggestions); err != nil {
+			t.Fatalf("writeLookupJSON failed: %v", err)
+		}
+
+		var got struct {
+			Entries     []lexin.Entry      `json:"entries"`
+			Suggestions []lexin.Suggestion `json:"suggestions"`
+		}
+		if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+			t.Fatalf("output is not an object: %v\n%s", err, out.String())
+		}
+		if got.Entries == nil || len(got.Entries) != 0 {
+			t.Errorf("entries = %v, want []", got.Entries)
+		}
+		if len(got.Suggestions) != 1 || got.Suggestions[0].Value != "hus" {
+			t.Errorf("suggestions = %+v", got.Suggestions)
+		}
+	})
+
+	t.Run("nothing", func(t *testing.T) {
+		var out bytes.Buffer
+		if err := writeLookupJSON(&out, nil, nil); err != nil {
+			t.Fatalf("writeLookupJSON failed: %v", err)
+		}
+		want := "{\n  \"entries\": [],\n  \"suggestions\": []\n}\n"
+		if out.String() != want {
+			t.Errorf("output = %q, want %q", out.String(), want)
+		}
+	})
+}
@@ -1,16 +1,10 @@
 package main
 
 import (
-	"context"
 	"fmt"
 	"log"
 	"os"
-	"time"
-
-	"lexin-sqlite/internal/config"
-	"lexin-sqlite/internal/database"
-	"lexin-sqlite/internal/parser"
-	"lexin-sqlite/internal/repository"
+	"strings"
 )
 
 // Version information
@@ -19,65 +13,97 @@ const (
 	BuildTime = "dev"
 )
 
+// command is a lexin subcommand
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+// commands lists the subcommands in the order they are shown in the usage
+var commands = []command{
+	{"import", "Import a Lexin XML file into the database", runImport},
+	{"lookup", "Look up a Swedish word", runLookup},
+	{"reverse", "Find Swedish words from a target language term", runReverse},
+	{"export", "Export a dictionary to another format", runExport},
+	{"stats", "Show statistics about the stored dictionaries", runStats},
+	{"validate", "Check that a Lexin XML file can be imported", runValidate},
+	{"serve", "Serve the database as a read-only JSON API over HTTP", runServe},
+	{"dictd", "Serve the database over the DICT protocol (RFC 2229)", runDictd},
+	{"migrate", "Show or apply database schema migrations", runMigrate},
+	{"version", "Show version information", runVersion},
+}
+
 func main() {
-	// Load configuration
-	cfg, err := config.Load()
-	if err != nil {
-		log.Fatalf("Error loading configuration: %v", err)
-	}
+	args := os.Args[1:]
 
-	if cfg.ShowVersion {
-		fmt.Printf("lexin-sqlite version %s (built at %s)\n", Version, BuildTime)
-		os.Exit(0)
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "-help" || args[0] == "--help" {
+		if len(args) > 1 {
+			// "lexin help <command>" shows the help of that command
+			if cmd := findCommand(args[1]); cmd != nil {
+				runCommand(cmd, []string{"-h"})
+				return
+			}
+		}
+		usage()
+		if len(args) == 0 {
+			os.Exit(2)
+		}
+		return
 	}
 
-	// Open database
-	db, err := database.New(cfg.DBPath)
-	if err != nil {
-		log.Fatalf("Error opening database: %v", err)
+	cmd := findCommand(args[0])
+	if cmd == nil {
+		if !strings.HasPrefix(args[0], "-") {
+			usage()
+			log.Fatalf("Error: unknown command %q", args[0])
+		}
+		// Invocations from before subcommands existed only had flags;
+		// they keep working as an alias for import
+		runCommand(findCommand("import"), args)
+		return
 	}
-	defer db.Close()
-
-	// Create repository
-	repo := repository.New(db)
 
-	// Parse XML file
-	startTime := time.Now()
-	log.Printf("Parsing XML file: %s", cfg.XMLFile)
-	dict, err := parser.ParseXMLFile(cfg.XMLFile)
-	if err != nil {
-		log.Fatalf("Error parsing XML file: %v", err)
-	}
-	log.Printf("Parsed %d words in %v", len(dict.Words), time.Since(startTime))
+	runCommand(cmd, args[1:])
+}
 
-	// Verify target language
-	if dict.TargetLang != cfg.TargetLang {
-		log.Printf("Warning: XML file has target language '%s', but you specified '%s'", dict.TargetLang, cfg.TargetLang)
+// findCommand returns the subcommand with the given name, or nil
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
 	}
+	return nil
+}
 
-	// Store data in database
-	log.Printf("Storing data in SQLite database: %s", cfg.DBPath)
-	startTime = time.Now()
-	err = repo.StoreDictionary(context.Background(), dict)
-	if err != nil {
-		log.Fatalf("Error storing dictionary: %v", err)
+// runCommand runs a subcommand and exits on error
+func runCommand(cmd *command, args []string) {
+	if err := cmd.run(args); err != nil {
+		log.Fatalf("Error: %v", err)
 	}
+}
 
-	// Get entry count
-	entryCount, err := db.CountDictionaryEntries(context.Background(), getDictID(db, dict.BaseLang, dict.TargetLang))
-	if err != nil {
-		log.Printf("Error counting entries: %v", err)
-		entryCount = int64(len(dict.Words))
+// usage prints the list of subcommands
+func usage() {
+	out := os.Stderr
+	fmt.Fprintf(out, "Usage of %s:\n", os.Args[0])
+	fmt.Fprintf(out, "  %s <command> [flags] [arguments]\n\n", os.Args[0])
+	fmt.Fprintln(out, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "  %-10s %s\n", cmd.name, cmd.summary)
 	}
+	fmt.Fprintf(out, "\nRun '%s help <command>' or '%s <command> -h' for the flags of a command.\n", os.Args[0], os.Args[0])
+	fmt.Fprintf(out, "Flags given without a command, as in '%s -file lexin.xml -target english', run import.\n", os.Args[0])
+}
 
-	log.Printf("Successfully imported %d entries in %v", entryCount, time.Since(startTime))
-	log.Printf("Dictionary from %s to %s is now available in %s", dict.BaseLang, dict.TargetLang, cfg.DBPath)
+// runVersion implements "lexin version"
+func runVersion(args []string) error {
+	printVersion()
+	return nil
 }
 
-func getDictID(db *database.DB, baseLang, targetLang string) int64 {
-	dictID, _, _, _, err := db.GetDictionaryByLanguages(context.Background(), baseLang, targetLang)
-	if err != nil {
-		return 0
-	}
-	return dictID
+// printVersion prints the version information
+func printVersion() {
+	fmt.Printf("lexin-sqlite version %s (built at %s)\n", Version, BuildTime)
 }
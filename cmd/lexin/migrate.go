@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"lexin-sqlite/internal/database"
+)
+
+// runMigrate implements "lexin migrate status|up"
+func runMigrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dbPath := fs.String("db", "lexin.db", "Path to the SQLite database file")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage of %s migrate:\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "  %s migrate status [-db <database-path>]   list migrations and whether they are applied\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "  %s migrate up [-db <database-path>]       apply pending migrations\n\n", os.Args[0])
+		fmt.Fprintln(fs.Output(), "Flags:")
+		fs.PrintDefaults()
+	}
+
+	if len(args) == 0 {
+		fs.Usage()
+		return fmt.Errorf("migrate requires an action: status or up")
+	}
+	action := args[0]
+	fs.Parse(args[1:])
+
+	if _, err := os.Stat(*dbPath); os.IsNotExist(err) {
+		return fmt.Errorf("database does not exist: %s", *dbPath)
+	}
+
+	// Listing the status must not write, so it works on read-only files
+	// and leaves databases from before migrations untouched
+	open := database.Open
+	if action == "status" {
+		open = database.OpenReadOnly
+	}
+	db, err := open(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	switch action {
+	case "status":
+		statuses, err := db.MigrationStatus(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%4d  %-40s %s\n", status.Version, status.Name, state)
+		}
+
+	case "up":
+		applied, err := db.Migrate(ctx)
+		for _, status := range applied {
+			fmt.Printf("Applied migration %d: %s\n", status.Version, status.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("Database is up to date")
+		}
+
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown migrate action: %s", action)
+	}
+
+	return nil
+}
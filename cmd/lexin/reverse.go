@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"lexin-sqlite/pkg/lexin"
+)
+
+// runReverse implements "lexin reverse", looking up Swedish words from a
+// target language term
+func runReverse(args []string) error {
+	fs := flag.NewFlagSet("reverse", flag.ExitOnError)
+	dbPath := fs.String("db", "lexin.db", "Path to the SQLite database file")
+	target := fs.String("target", "", "Target language code of the dictionary to search (all when empty)")
+	match := fs.String("match", "token", "Loosest match to return: exact, prefix or token")
+	limit := fs.Int("limit", 20, "Maximum number of results")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage of %s reverse:\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "  %s reverse [-db <database-path>] [-target <language-code>] <term>\n\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "Examples:\n")
+		fmt.Fprintf(fs.Output(), "  %s reverse -target english house\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "  %s reverse -match exact walk\n\n", os.Args[0])
+		fmt.Fprintln(fs.Output(), "Flags:")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	term := strings.Join(fs.Args(), " ")
+	if term == "" {
+		fs.Usage()
+		return fmt.Errorf("a term to look up is required")
+	}
+
+	kind, err := lexin.ParseMatchKind(*match)
+	if err != nil {
+		return err
+	}
+
+	db, err := openLexin(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	dict, err := selectDictionary(ctx, db, *target)
+	if err != nil {
+		return err
+	}
+
+	hits, err := db.ReverseLookup(ctx, dict, term, lexin.ReverseOptions{Match: kind, Limit: *limit})
+	if err != nil {
+		return err
+	}
+
+	if len(hits) == 0 {
+		fmt.Printf("No Swedish words found for %q\n", term)
+		return nil
+	}
+	for _, hit := range hits {
+		fmt.Printf("%-20s %-10s %-40s [%s %s]\n", hit.Entry.Value, hit.Entry.Type, hit.Matched, hit.Match, hit.Source)
+	}
+
+	return nil
+}
+
+// openLexin opens an existing database for reading only. Read commands
+// never migrate the schema; a database with pending migrations has to be
+// upgraded with "lexin migrate up" or the next import first.
+func openLexin(dbPath string) (*lexin.DB, error) {
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("database does not exist: %s", dbPath)
+	}
+	db, err := lexin.OpenReadOnly(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w; run \"%s migrate up -db %s\" to upgrade it", err, os.Args[0], dbPath)
+	}
+	return db, nil
+}
+
+// selectDictionary finds the dictionary with the given target language. It
+// returns nil, meaning every dictionary, when target is empty.
+func selectDictionary(ctx context.Context, db *lexin.DB, target string) (*lexin.Dictionary, error) {
+	if target == "" {
+		return nil, nil
+	}
+
+	dictionaries, err := db.Dictionaries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range dictionaries {
+		if dictionaries[i].TargetLang == target {
+			return &dictionaries[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("no dictionary with target language %s", target)
+}
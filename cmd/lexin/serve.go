@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"lexin-sqlite/internal/server"
+	"lexin-sqlite/pkg/lexin"
+)
+
+// runServe implements "lexin serve", the read-only HTTP JSON API
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	dbPath := fs.String("db", "lexin.db", "Path to the SQLite database file")
+	addr := fs.String("addr", ":8080", "Address to listen on")
+	allowOrigin := fs.String("cors", "*", "Value of the Access-Control-Allow-Origin header")
+	maxLimit := fs.Int("max-limit", 100, "Largest page size a request can ask for")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage of %s serve:\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "  %s serve [-db <database-path>] [-addr <address>] [-cors <origin>]\n\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "Endpoints:\n")
+		fmt.Fprintf(fs.Output(), "  GET /api/dictionaries\n")
+		fmt.Fprintf(fs.Output(), "  GET /api/lookup?q=<word>[&dict=swe-eng][&limit=n][&offset=n]\n")
+		fmt.Fprintf(fs.Output(), "  GET /api/autocomplete?q=<prefix>[&dict=swe-eng][&limit=n][&offset=n]\n")
+		fmt.Fprintf(fs.Output(), "  GET /api/reverse?q=<term>[&dict=swe-eng][&match=exact|prefix|token][&limit=n][&offset=n]\n")
+		fmt.Fprintf(fs.Output(), "  GET /api/entries/<id>[/<variant-id>][?dict=swe-eng]\n\n")
+		fmt.Fprintln(fs.Output(), "Flags:")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	db, err := lexin.OpenReadOnly(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	srv := &http.Server{
+		Addr: *addr,
+		Handler: server.New(db, server.Options{
+			AllowOrigin: *allowOrigin,
+			MaxLimit:    *maxLimit,
+		}),
+		ReadHeaderTimeout: 10 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errc := make(chan error, 1)
+	go func() {
+		log.Printf("Serving %s on %s", *dbPath, *addr)
+		errc <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Printf("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
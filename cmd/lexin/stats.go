@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// runStats implements "lexin stats"
+func runStats(args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	dbPath := fs.String("db", "lexin.db", "Path to the SQLite database file")
+	asJSON := fs.Bool("json", false, "Print the statistics as JSON")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage of %s stats:\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "  %s stats [-db <database-path>] [-json]\n\n", os.Args[0])
+		fmt.Fprintln(fs.Output(), "Flags:")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	db, err := openLexin(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	stats, err := db.Stats(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return printJSON(stats)
+	}
+	if len(stats) == 0 {
+		fmt.Println("No dictionaries imported")
+		return nil
+	}
+	for _, s := range stats {
+		fmt.Printf("%s-%s (version %s, imported %s)\n", s.Dictionary.BaseLang, s.Dictionary.TargetLang, s.Dictionary.Version, s.Dictionary.CreatedAt.Format("2006-01-02 15:04:05"))
+		fmt.Printf("  words:        %d\n", s.Words)
+		fmt.Printf("  headwords:    %d\n", s.Headwords)
+		fmt.Printf("  meanings:     %d\n", s.Meanings)
+		fmt.Printf("  translations: %d\n", s.Translations)
+		fmt.Printf("  examples:     %d\n", s.Examples)
+		fmt.Printf("  idioms:       %d\n", s.Idioms)
+		fmt.Printf("  compounds:    %d\n", s.Compounds)
+		fmt.Printf("  forms:        %d\n", s.Forms)
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"lexin-sqlite/internal/parser"
+)
+
+// runValidate implements "lexin validate", checking a Lexin XML file
+// without touching a database
+func runValidate(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "Print the diagnostics as JSON")
+	audit := fs.Bool("audit", false, "Print a histogram of the XML paths the importer drops instead of each diagnostic")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage of %s validate:\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "  %s validate [-json] [-audit] <xml-file>\n\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "Reports malformed XML, elements and attributes the importer ignores or\n")
+		fmt.Fprintf(fs.Output(), "overwrites, words without ID, VariantID, Type or Value and reference\n")
+		fmt.Fprintf(fs.Output(), "types the database rejects, as file:line:col diagnostics.\n\n")
+		fmt.Fprintln(fs.Output(), "Flags:")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("an XML file to validate is required")
+	}
+	path := fs.Arg(0)
+
+	validation, err := parser.ValidateXMLFile(path)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		if err := printJSON(validation); err != nil {
+			return err
+		}
+	} else {
+		if *audit {
+			printAudit(validation.Unmapped)
+		} else {
+			for _, diagnostic := range validation.Diagnostics {
+				fmt.Println(diagnostic)
+			}
+		}
+		header := validation.Header
+		fmt.Printf("%s: %s-%s version %s, %d words, %d errors, %d warnings\n",
+			path, header.BaseLang, header.TargetLang, header.Version,
+			validation.Words, validation.Errors, validation.Warnings)
+	}
+
+	if validation.Errors > 0 {
+		return fmt.Errorf("%s: validation failed with %d errors", path, validation.Errors)
+	}
+	return nil
+}
+
+// printAudit prints the dropped paths, the most frequent first
+func printAudit(unmapped map[string]int) {
+	paths := make([]string, 0, len(unmapped))
+	for path := range unmapped {
+		paths = append(paths, path)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		if unmapped[paths[i]] != unmapped[paths[j]] {
+			return unmapped[paths[i]] > unmapped[paths[j]]
+		}
+		return paths[i] < paths[j]
+	})
+
+	if len(paths) == 0 {
+		fmt.Println("Every element and attribute is imported")
+		return
+	}
+	for _, path := range paths {
+		fmt.Printf("%8d  %s\n", unmapped[path], path)
+	}
+}
@@ -9,32 +9,59 @@ import (
 
 // Config holds application configuration
 type Config struct {
-	XMLFile     string
-	DBPath      string
-	TargetLang  string
-	ShowVersion bool
+	XMLFile        string
+	DBPath         string
+	TargetLang     string
+	Mode           string
+	ReportPath     string
+	LinkReportPath string
+	ShowVersion    bool
 }
 
-// Load loads configuration from command line arguments
-func Load() (*Config, error) {
+// Import modes
+const (
+	// ModeUpsert replaces changed words and skips unchanged ones
+	ModeUpsert = "upsert"
+	// ModeUpdate also deletes words missing from the file, bumps the
+	// dictionary version and reports the changes
+	ModeUpdate = "update"
+)
+
+// Load loads the import configuration from command line arguments, which
+// exclude the program and subcommand names
+func Load(args []string) (*Config, error) {
 	config := &Config{}
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+
+	fs.StringVar(&config.XMLFile, "file", "", "Path to the XML dictionary file")
+	fs.StringVar(&config.DBPath, "db", "lexin.db", "Path to the SQLite database file")
+	fs.StringVar(&config.TargetLang, "target", "", "Target language code")
+	fs.StringVar(&config.Mode, "mode", ModeUpsert, "Import mode: upsert or update")
+	fs.StringVar(&config.ReportPath, "report", "", "Write the update change report as JSON to this file")
+	fs.StringVar(&config.LinkReportPath, "link-report", "", "Write the references, antonyms and MatchingIDs left unresolved as JSON to this file")
+	fs.BoolVar(&config.ShowVersion, "version", false, "Show version information")
 
-	flag.StringVar(&config.XMLFile, "file", "", "Path to the XML dictionary file")
-	flag.StringVar(&config.DBPath, "db", "lexin.db", "Path to the SQLite database file")
-	flag.StringVar(&config.TargetLang, "target", "", "Target language code")
-	flag.BoolVar(&config.ShowVersion, "version", false, "Show version information")
-
-	flag.Usage = func() {
-		fmt.Fprintf(flag.CommandLine.Output(), "Usage of %s:\n", os.Args[0])
-		fmt.Fprintf(flag.CommandLine.Output(), "  %s -file <xml-file> -target <language-code> [-db <database-path>]\n\n", os.Args[0])
-		fmt.Fprintf(flag.CommandLine.Output(), "Examples:\n")
-		fmt.Fprintf(flag.CommandLine.Output(), "  %s -file swedishenglish.xml -target english\n", os.Args[0])
-		fmt.Fprintf(flag.CommandLine.Output(), "  %s -file swedisharabic.xml -target arabic -db custom.db\n\n", os.Args[0])
-		fmt.Fprintln(flag.CommandLine.Output(), "Flags:")
-		flag.PrintDefaults()
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage of %s import:\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "  %s import -file <xml-file> -target <language-code> [-db <database-path>]\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "  %s import -target <language-code> [-db <database-path>] <xml-file>\n\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "The import subcommand is the default, so the flags can also be given without it.\n\n")
+		fmt.Fprintf(fs.Output(), "Examples:\n")
+		fmt.Fprintf(fs.Output(), "  %s import -file swedishenglish.xml -target english\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "  %s import -file swedisharabic.xml -target arabic -db custom.db\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "  %s import -file swedishenglish.xml -target english -mode update -report changes.json\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "  %s import -file swedishenglish.xml -target english -link-report links.json\n\n", os.Args[0])
+		fmt.Fprintln(fs.Output(), "Flags:")
+		fs.PrintDefaults()
 	}
 
-	flag.Parse()
+	fs.Parse(args)
+
+	if config.XMLFile == "" && fs.NArg() == 1 {
+		config.XMLFile = fs.Arg(0)
+	} else if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
 
 	// Validate required parameters
 	if config.ShowVersion {
@@ -49,6 +76,16 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("target language code is required")
 	}
 
+	switch config.Mode {
+	case ModeUpsert, ModeUpdate:
+	default:
+		return nil, fmt.Errorf("unknown import mode: %s", config.Mode)
+	}
+
+	if config.ReportPath != "" && config.Mode != ModeUpdate {
+		return nil, fmt.Errorf("-report is only supported with -mode %s", ModeUpdate)
+	}
+
 	// Check if XML file exists
 	if _, err := os.Stat(config.XMLFile); os.IsNotExist(err) {
 		return nil, fmt.Errorf("XML file does not exist: %s", config.XMLFile)
@@ -5,12 +5,13 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
-	"strings"
+	"os"
 
 	_ "modernc.org/sqlite"
 )
 
-// Database schema
+// Database schema as it was before versioned migrations were introduced.
+// It is applied as the first migration; later changes go in migrations.go.
 const schema = `
 -- Dictionary metadata
 CREATE TABLE IF NOT EXISTS dictionaries (
@@ -272,54 +273,66 @@ type DB struct {
 	db *sql.DB
 }
 
-// New creates a new database connection
+// New creates a new database connection and applies pending migrations
 func New(dbPath string) (*DB, error) {
-	db, err := sql.Open("sqlite", dbPath)
+	d, err := Open(dbPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
+		return nil, err
+	}
+
+	if _, err := d.Migrate(context.Background()); err != nil {
+		d.Close()
+		return nil, err
 	}
 
-	// Set pragmas for performance
-	_, err = db.Exec(`
-		PRAGMA journal_mode = WAL;
-		PRAGMA synchronous = NORMAL;
-		PRAGMA foreign_keys = ON;
-	`)
+	return d, nil
+}
+
+// Open creates a new database connection without touching the schema
+func Open(dbPath string) (*DB, error) {
+	// Pragmas are passed in the DSN so that every pooled connection gets
+	// them; foreign_keys in particular is per connection and the cascading
+	// deletes rely on it
+	dsn := dbPath + "?_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)&_pragma=foreign_keys(ON)"
+
+	db, err := sql.Open("sqlite", dsn)
 	if err != nil {
-		return nil, fmt.Errorf("failed to set pragmas: %w", err)
+		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	// Create schema
-	if err := createSchema(db); err != nil {
-		return nil, err
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to set pragmas: %w", err)
 	}
 
 	return &DB{db: db}, nil
 }
 
-// Close closes the database connection
-func (d *DB) Close() error {
-	return d.db.Close()
-}
+// OpenReadOnly opens an existing database for reading only. The schema is
+// neither created nor migrated, so the database must already be up to date.
+func OpenReadOnly(dbPath string) (*DB, error) {
+	if _, err := os.Stat(dbPath); err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
 
-// createSchema creates the database schema
-func createSchema(db *sql.DB) error {
-	// Split statements by semicolon
-	statements := strings.Split(schema, ";")
+	dsn := "file:" + dbPath + "?mode=ro&_pragma=foreign_keys(ON)"
 
-	for _, statement := range statements {
-		statement = strings.TrimSpace(statement)
-		if statement == "" {
-			continue
-		}
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
 
-		_, err := db.Exec(statement)
-		if err != nil {
-			return fmt.Errorf("failed to execute schema statement: %w\nStatement: %s", err, statement)
-		}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to set pragmas: %w", err)
 	}
 
-	return nil
+	return &DB{db: db}, nil
+}
+
+// Close closes the database connection
+func (d *DB) Close() error {
+	return d.db.Close()
 }
 
 // GetDB returns the underlying database connection
@@ -0,0 +1,646 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"lexin-sqlite/internal/fuzzy"
+	"lexin-sqlite/internal/parser"
+)
+
+// migration is a single, ordered schema change. Migrations are never edited
+// once released; new changes are appended with the next version number.
+type migration struct {
+	version int
+	name    string
+	up      func(tx *sql.Tx) error
+}
+
+// migrations lists every schema change in the order it is applied
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "initial schema",
+		up:      execSQL(schema),
+	},
+	{
+		version: 2,
+		name:    "word checksums for upserts",
+		up: func(tx *sql.Tx) error {
+			// Databases built between the upsert change and this framework
+			// already have the column
+			if err := ensureColumn(tx, "words", "checksum", "TEXT"); err != nil {
+				return err
+			}
+			return execSQL(`
+				CREATE INDEX IF NOT EXISTS idx_word_original ON words(dictionary_id, original_id, variant_id);
+			`)(tx)
+		},
+	},
+	{
+		version: 3,
+		name:    "full-text search indexes",
+		up: func(tx *sql.Tx) error {
+			for _, source := range []struct{ table, column string }{
+				{"base_langs", "meaning"},
+				{"examples", "content"},
+				{"idioms", "content"},
+				{"explanations", "content"},
+				{"translations", "content"},
+			} {
+				if err := createFTSIndex(tx, source.table, source.column); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		version: 4,
+		name:    "reverse lookup indexes",
+		up: func(tx *sql.Tx) error {
+			if err := createFTSIndex(tx, "synonyms", "content"); err != nil {
+				return err
+			}
+			return execSQL(`
+				CREATE INDEX IF NOT EXISTS idx_translation_content_nocase ON translations(content COLLATE NOCASE);
+				CREATE INDEX IF NOT EXISTS idx_synonym_content_nocase ON synonyms(content COLLATE NOCASE);
+			`)(tx)
+		},
+	},
+	{
+		version: 5,
+		name:    "inflected form index",
+		up: func(tx *sql.Tx) error {
+			err := execSQL(`
+				-- Surface forms (inflections, variants, compounds and
+				-- derivations) that lead back to a headword
+				CREATE TABLE IF NOT EXISTS word_forms (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					word_id INTEGER NOT NULL,
+					form TEXT NOT NULL,
+					source TEXT NOT NULL CHECK (source IN ('inflection', 'variant', 'compound', 'derivation')),
+					description TEXT,
+					UNIQUE (word_id, form),
+					FOREIGN KEY (word_id) REFERENCES words(id) ON DELETE CASCADE
+				);
+
+				CREATE INDEX IF NOT EXISTS idx_word_forms_form ON word_forms(form);
+			`)(tx)
+			if err != nil {
+				return err
+			}
+			return backfillWordForms(tx)
+		},
+	},
+	{
+		version: 6,
+		name:    "headword trigram index",
+		up: func(tx *sql.Tx) error {
+			err := execSQL(`
+				-- Trigrams of the diacritic folded headwords, used to find
+				-- candidates for typo tolerant suggestions
+				CREATE TABLE IF NOT EXISTS word_trigrams (
+					trigram TEXT NOT NULL,
+					word_id INTEGER NOT NULL,
+					PRIMARY KEY (trigram, word_id),
+					FOREIGN KEY (word_id) REFERENCES words(id) ON DELETE CASCADE
+				) WITHOUT ROWID;
+
+				CREATE INDEX IF NOT EXISTS idx_word_trigrams_word ON word_trigrams(word_id);
+			`)(tx)
+			if err != nil {
+				return err
+			}
+			return backfillWordTrigrams(tx)
+		},
+	},
+	{
+		version: 7,
+		name:    "ordered target language texts",
+		up: func(tx *sql.Tx) error {
+			// Rows stored before hold at most one text per target
+			// language, so numbering them by id keeps their order.
+			// Reimporting restores the texts that used to be dropped.
+			for _, table := range []string{"translations", "synonyms", "target_comments", "target_explanations"} {
+				if err := ensureColumn(tx, table, "position", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+					return err
+				}
+				err := execSQL(fmt.Sprintf(`
+					UPDATE %[1]s SET position = (
+						SELECT COUNT(*) FROM %[1]s AS earlier
+						WHERE earlier.target_lang_id = %[1]s.target_lang_id AND earlier.id < %[1]s.id
+					);
+					CREATE INDEX IF NOT EXISTS idx_%[1]s_target ON %[1]s(target_lang_id, position);
+				`, table))(tx)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		version: 8,
+		name:    "element positions",
+		up: func(tx *sql.Tx) error {
+			// Rows were inserted in document order, so numbering them by id
+			// within their parent restores the order of the XML file
+			for _, child := range []struct {
+				table  string
+				owners []string
+			}{
+				{"base_langs", []string{"word_id"}},
+				{"target_langs", []string{"word_id"}},
+				{"word_references", []string{"base_lang_id"}},
+				{"comments", []string{"base_lang_id"}},
+				{"explanations", []string{"base_lang_id"}},
+				{"alternates", []string{"base_lang_id"}},
+				{"antonyms", []string{"base_lang_id", "target_lang_id"}},
+				{"usages", []string{"base_lang_id"}},
+				{"phonetics", []string{"base_lang_id"}},
+				{"illustrations", []string{"base_lang_id"}},
+				{"inflections", []string{"base_lang_id"}},
+				{"inflection_variants", []string{"inflection_id"}},
+				{"graminfos", []string{"base_lang_id"}},
+				{"examples", []string{"base_lang_id", "target_lang_id"}},
+				{"idioms", []string{"base_lang_id", "target_lang_id"}},
+				{"compounds", []string{"base_lang_id", "target_lang_id"}},
+				{"compound_inflections", []string{"compound_id"}},
+				{"derivations", []string{"base_lang_id", "target_lang_id"}},
+				{"derivation_inflections", []string{"derivation_id"}},
+				{"indexes", []string{"base_lang_id"}},
+			} {
+				if err := ensureColumn(tx, child.table, "position", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+					return err
+				}
+
+				for _, owner := range child.owners {
+					err := execSQL(fmt.Sprintf(`
+						CREATE INDEX IF NOT EXISTS idx_%[1]s_%[2]s_position ON %[1]s(%[2]s, position);
+					`, child.table, owner))(tx)
+					if err != nil {
+						return err
+					}
+				}
+
+				// Shared tables hang off either a base or a target
+				// language, the other column being NULL
+				sameOwner := make([]string, len(child.owners))
+				for i, owner := range child.owners {
+					sameOwner[i] = fmt.Sprintf("earlier.%[2]s IS %[1]s.%[2]s", child.table, owner)
+				}
+				err := execSQL(fmt.Sprintf(`
+					UPDATE %[1]s SET position = (
+						SELECT COUNT(*) FROM %[1]s AS earlier
+						WHERE %[2]s AND earlier.id < %[1]s.id
+					);
+				`, child.table, strings.Join(sameOwner, " AND ")))(tx)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		version: 9,
+		name:    "resolved links",
+		up: func(tx *sql.Tx) error {
+			// The links are filled by the linking pass that runs after
+			// every import, so they start out empty
+			for _, link := range []struct{ table, column, references string }{
+				{"word_references", "target_word_id", "words"},
+				{"antonyms", "target_word_id", "words"},
+				{"base_langs", "matched_target_lang_id", "target_langs"},
+				{"examples", "matched_example_id", "examples"},
+				{"idioms", "matched_idiom_id", "idioms"},
+				{"compounds", "matched_compound_id", "compounds"},
+			} {
+				definition := fmt.Sprintf("INTEGER REFERENCES %s(id) ON DELETE SET NULL", link.references)
+				if err := ensureColumn(tx, link.table, link.column, definition); err != nil {
+					return err
+				}
+				err := execSQL(fmt.Sprintf(`
+					CREATE INDEX IF NOT EXISTS idx_%[1]s_%[2]s ON %[1]s(%[2]s);
+				`, link.table, link.column))(tx)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		version: 10,
+		name:    "short headword deletion index",
+		up: func(tx *sql.Tx) error {
+			err := execSQL(`
+				-- Folded short headwords and their one letter deletions,
+				-- used to find suggestions the trigrams miss
+				CREATE TABLE IF NOT EXISTS word_deletes (
+					variant TEXT NOT NULL,
+					word_id INTEGER NOT NULL,
+					PRIMARY KEY (variant, word_id),
+					FOREIGN KEY (word_id) REFERENCES words(id) ON DELETE CASCADE
+				) WITHOUT ROWID;
+
+				CREATE INDEX IF NOT EXISTS idx_word_deletes_word ON word_deletes(word_id);
+			`)(tx)
+			if err != nil {
+				return err
+			}
+			return backfillWordDeletes(tx)
+		},
+	},
+}
+
+// createFTSIndex creates an external content FTS5 table named <table>_fts
+// over one column, keeps it in sync with triggers and fills it from the
+// rows already stored. Diacritics are kept so that å, ä and ö stay distinct
+// letters.
+func createFTSIndex(tx *sql.Tx, table, column string) error {
+	fts := table + "_fts"
+	statements := fmt.Sprintf(`
+		CREATE VIRTUAL TABLE %[1]s USING fts5(
+			%[3]s,
+			content = '%[2]s',
+			content_rowid = 'id',
+			tokenize = 'unicode61 remove_diacritics 0'
+		);
+
+		CREATE TRIGGER %[2]s_fts_insert AFTER INSERT ON %[2]s BEGIN
+			INSERT INTO %[1]s (rowid, %[3]s) VALUES (new.id, new.%[3]s);
+		END;
+
+		CREATE TRIGGER %[2]s_fts_delete AFTER DELETE ON %[2]s BEGIN
+			INSERT INTO %[1]s (%[1]s, rowid, %[3]s) VALUES ('delete', old.id, old.%[3]s);
+		END;
+
+		CREATE TRIGGER %[2]s_fts_update AFTER UPDATE OF %[3]s ON %[2]s BEGIN
+			INSERT INTO %[1]s (%[1]s, rowid, %[3]s) VALUES ('delete', old.id, old.%[3]s);
+			INSERT INTO %[1]s (rowid, %[3]s) VALUES (new.id, new.%[3]s);
+		END;
+
+		INSERT INTO %[1]s (%[1]s) VALUES ('rebuild');
+	`, fts, table, column)
+
+	if _, err := tx.Exec(statements); err != nil {
+		return fmt.Errorf("failed to create full-text index %s: %w", fts, err)
+	}
+	return nil
+}
+
+// backfillWordForms fills word_forms from the inflection, compound and
+// derivation rows already stored, splitting them into forms the same way
+// an import does
+func backfillWordForms(tx *sql.Tx) error {
+	insert, err := tx.Prepare(`
+		INSERT OR IGNORE INTO word_forms (word_id, form, source, description)
+		VALUES (?, ?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+	defer insert.Close()
+
+	type formRow struct {
+		wordID      int64
+		forms       []string
+		source      string
+		description sql.NullString
+	}
+
+	queries := []struct {
+		source string
+		query  string
+		split  func(content, inflection string) []string
+	}{
+		{parser.FormInflection, `
+			SELECT b.word_id, COALESCE(i.content, ''), NULL, ''
+			FROM inflections i
+			JOIN base_langs b ON b.id = i.base_lang_id
+			ORDER BY i.id
+		`, func(content, _ string) []string { return parser.InflectionForms(content) }},
+		{parser.FormVariant, `
+			SELECT b.word_id, v.content, v.description, ''
+			FROM inflection_variants v
+			JOIN inflections i ON i.id = v.inflection_id
+			JOIN base_langs b ON b.id = i.base_lang_id
+			ORDER BY v.id
+		`, func(content, _ string) []string { return parser.InflectionForms(content) }},
+		{parser.FormCompound, `
+			SELECT b.word_id, COALESCE(c.content, ''), c.description,
+				COALESCE((SELECT group_concat(ci.content, ' ') FROM compound_inflections ci WHERE ci.compound_id = c.id), '')
+			FROM compounds c
+			JOIN base_langs b ON b.id = c.base_lang_id
+			ORDER BY c.id
+		`, parser.AffixedForms},
+		{parser.FormDerivation, `
+			SELECT b.word_id, COALESCE(d.content, ''), d.description,
+				COALESCE((SELECT group_concat(di.content, ' ') FROM derivation_inflections di WHERE di.derivation_id = d.id), '')
+			FROM derivations d
+			JOIN base_langs b ON b.id = d.base_lang_id
+			ORDER BY d.id
+		`, parser.AffixedForms},
+	}
+
+	for _, q := range queries {
+		// Rows are read fully before inserting so that the transaction's
+		// connection is not used by two statements at once
+		var pending []formRow
+		rows, err := tx.Query(q.query)
+		if err != nil {
+			return fmt.Errorf("failed to read %s forms: %w", q.source, err)
+		}
+		for rows.Next() {
+			var row formRow
+			var content, inflection string
+			if err := rows.Scan(&row.wordID, &content, &row.description, &inflection); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to read %s forms: %w", q.source, err)
+			}
+			row.forms = q.split(content, inflection)
+			row.source = q.source
+			pending = append(pending, row)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read %s forms: %w", q.source, err)
+		}
+
+		for _, row := range pending {
+			for _, form := range row.forms {
+				if _, err := insert.Exec(row.wordID, form, row.source, row.description); err != nil {
+					return fmt.Errorf("failed to store %s form %s: %w", row.source, form, err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// backfillWordTrigrams indexes the headwords already stored
+func backfillWordTrigrams(tx *sql.Tx) error {
+	type word struct {
+		id    int64
+		value string
+	}
+
+	var words []word
+	rows, err := tx.Query(`SELECT id, value FROM words`)
+	if err != nil {
+		return fmt.Errorf("failed to read words: %w", err)
+	}
+	for rows.Next() {
+		var w word
+		if err := rows.Scan(&w.id, &w.value); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to read words: %w", err)
+		}
+		words = append(words, w)
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read words: %w", err)
+	}
+
+	insert, err := tx.Prepare(`INSERT OR IGNORE INTO word_trigrams (trigram, word_id) VALUES (?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer insert.Close()
+
+	for _, w := range words {
+		for _, trigram := range fuzzy.Trigrams(w.value) {
+			if _, err := insert.Exec(trigram, w.id); err != nil {
+				return fmt.Errorf("failed to index word %s: %w", w.value, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// backfillWordDeletes indexes the short headwords already stored
+func backfillWordDeletes(tx *sql.Tx) error {
+	type word struct {
+		id    int64
+		value string
+	}
+
+	var words []word
+	rows, err := tx.Query(`SELECT id, value FROM words`)
+	if err != nil {
+		return fmt.Errorf("failed to read words: %w", err)
+	}
+	for rows.Next() {
+		var w word
+		if err := rows.Scan(&w.id, &w.value); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to read words: %w", err)
+		}
+		words = append(words, w)
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read words: %w", err)
+	}
+
+	insert, err := tx.Prepare(`INSERT OR IGNORE INTO word_deletes (variant, word_id) VALUES (?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer insert.Close()
+
+	for _, w := range words {
+		if len([]rune(fuzzy.Fold(w.value))) > fuzzy.DeleteIndexLength {
+			continue
+		}
+		for _, variant := range fuzzy.Deletes(w.value) {
+			if _, err := insert.Exec(variant, w.id); err != nil {
+				return fmt.Errorf("failed to index word %s: %w", w.value, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// MigrationStatus describes whether a migration has been applied
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// execSQL returns a migration step that executes a block of SQL statements
+func execSQL(statements string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		_, err := tx.Exec(statements)
+		return err
+	}
+}
+
+// ensureColumn adds a column to a table unless it is already there
+func ensureColumn(tx *sql.Tx, table, column, definition string) error {
+	rows, err := tx.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return fmt.Errorf("failed to inspect table %s: %w", table, err)
+	}
+
+	found := false
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to inspect table %s: %w", table, err)
+		}
+		if name == column {
+			found = true
+		}
+	}
+	if err := rows.Close(); err != nil {
+		return fmt.Errorf("failed to inspect table %s: %w", table, err)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to inspect table %s: %w", table, err)
+	}
+
+	if found {
+		return nil
+	}
+
+	_, err = tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	if err != nil {
+		return fmt.Errorf("failed to add column %s.%s: %w", table, column, err)
+	}
+
+	return nil
+}
+
+// ensureMigrationsTable creates the table that records applied migrations
+func (d *DB) ensureMigrationsTable(ctx context.Context) error {
+	_, err := d.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+	return nil
+}
+
+// MigrationStatus lists every known migration and whether it is applied.
+// It only reads, so it works on read-only connections; a database without a
+// schema_migrations table has every migration pending.
+func (d *DB) MigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	var tables int
+	err := d.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'
+	`).Scan(&tables)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+
+	applied := make(map[int]time.Time)
+	if tables > 0 {
+		if err := d.loadAppliedMigrations(ctx, applied); err != nil {
+			return nil, err
+		}
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		appliedAt, ok := applied[m.version]
+		statuses = append(statuses, MigrationStatus{
+			Version:   m.version,
+			Name:      m.name,
+			Applied:   ok,
+			AppliedAt: appliedAt,
+		})
+	}
+
+	return statuses, nil
+}
+
+// loadAppliedMigrations reads the applied migrations into applied, keyed on
+// their version
+func (d *DB) loadAppliedMigrations(ctx context.Context, applied map[int]time.Time) error {
+	rows, err := d.db.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return fmt.Errorf("failed to read schema_migrations: %w", err)
+		}
+		applied[version] = appliedAt
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+
+	return nil
+}
+
+// Migrate applies pending migrations in order, each in its own transaction
+// together with its schema_migrations row. It returns the migrations that
+// were applied.
+func (d *DB) Migrate(ctx context.Context) ([]MigrationStatus, error) {
+	if err := d.ensureMigrationsTable(ctx); err != nil {
+		return nil, err
+	}
+
+	statuses, err := d.MigrationStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []MigrationStatus
+	for i, status := range statuses {
+		if status.Applied {
+			continue
+		}
+
+		m := migrations[i]
+		err := d.RunInTransaction(ctx, func(tx *sql.Tx) error {
+			if err := m.up(tx); err != nil {
+				return err
+			}
+			_, err := tx.Exec(`INSERT INTO schema_migrations (version, name) VALUES (?, ?)`, m.version, m.name)
+			return err
+		})
+		if err != nil {
+			return applied, fmt.Errorf("failed to apply migration %d (%s): %w", m.version, m.name, err)
+		}
+
+		status.Applied = true
+		status.AppliedAt = time.Now().UTC()
+		applied = append(applied, status)
+	}
+
+	return applied, nil
+}
@@ -0,0 +1,95 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+// createLegacyDB writes a database with the initial schema and no
+// schema_migrations table, as built before migrations existed
+func createLegacyDB(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "legacy.db")
+
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.GetDB().Exec(schema); err != nil {
+		t.Fatalf("failed to create schema: %v", err)
+	}
+
+	return path
+}
+
+func TestMigrationStatusDoesNotWrite(t *testing.T) {
+	ctx := context.Background()
+	path := createLegacyDB(t)
+
+	db, err := OpenReadOnly(path)
+	if err != nil {
+		t.Fatalf("failed to open database read-only: %v", err)
+	}
+	defer db.Close()
+
+	statuses, err := db.MigrationStatus(ctx)
+	if err != nil {
+		t.Fatalf("MigrationStatus on a read-only database failed: %v", err)
+	}
+	if len(statuses) != len(migrations) {
+		t.Fatalf("got %d statuses, want %d", len(statuses), len(migrations))
+	}
+	for _, status := range statuses {
+		if status.Applied {
+			t.Errorf("migration %d is applied in a legacy database", status.Version)
+		}
+	}
+
+	var tables int
+	err = db.GetDB().QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = 'schema_migrations'`).Scan(&tables)
+	if err != nil {
+		t.Fatalf("failed to inspect schema: %v", err)
+	}
+	if tables != 0 {
+		t.Error("MigrationStatus created schema_migrations")
+	}
+}
+
+func TestMigrateAppliesEveryMigrationOnce(t *testing.T) {
+	ctx := context.Background()
+	path := createLegacyDB(t)
+
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	applied, err := db.Migrate(ctx)
+	if err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if len(applied) != len(migrations) {
+		t.Errorf("applied %d migrations, want %d", len(applied), len(migrations))
+	}
+
+	applied, err = db.Migrate(ctx)
+	if err != nil {
+		t.Fatalf("second Migrate failed: %v", err)
+	}
+	if len(applied) != 0 {
+		t.Errorf("second Migrate applied %d migrations", len(applied))
+	}
+
+	statuses, err := db.MigrationStatus(ctx)
+	if err != nil {
+		t.Fatalf("MigrationStatus failed: %v", err)
+	}
+	for _, status := range statuses {
+		if !status.Applied {
+			t.Errorf("migration %d is pending after Migrate", status.Version)
+		}
+	}
+}
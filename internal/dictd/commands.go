@@ -0,0 +1,429 @@
+package dictd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"lexin-sqlite/internal/render"
+	"lexin-sqlite/pkg/lexin"
+)
+
+// strategy is a MATCH strategy
+type strategy struct {
+	name        string
+	description string
+	match       func(ctx context.Context, db *lexin.DB, dict *lexin.Dictionary, word string, limit int) ([]string, error)
+}
+
+// defaultStrategy is used for the "." strategy, as dictd does
+const defaultStrategy = "lev"
+
+// strategies lists the supported MATCH strategies in SHOW STRAT order
+var strategies = []strategy{
+	{"exact", "Match headwords exactly", matchExact},
+	{"prefix", "Match prefixes", matchPrefix},
+	{"substring", "Match substring occurring anywhere in a headword", matchSubstring},
+	{"lev", "Match headwords within Levenshtein distance one", matchLevenshtein},
+}
+
+// dispatch runs one command line and reports whether the client quit
+func (sess *session) dispatch(ctx context.Context, line string) bool {
+	args, err := splitArgs(line)
+	if err != nil {
+		sess.status(501, "syntax error, illegal parameters")
+		return false
+	}
+	if len(args) == 0 {
+		sess.status(500, "syntax error, command not recognized")
+		return false
+	}
+
+	switch strings.ToUpper(args[0]) {
+	case "DEFINE", "D":
+		if len(args) != 3 {
+			sess.status(501, "syntax error, illegal parameters")
+			return false
+		}
+		sess.define(ctx, args[1], args[2])
+
+	case "MATCH", "M":
+		if len(args) != 4 {
+			sess.status(501, "syntax error, illegal parameters")
+			return false
+		}
+		sess.match(ctx, args[1], args[2], args[3])
+
+	case "SHOW":
+		if len(args) < 2 {
+			sess.status(501, "syntax error, illegal parameters")
+			return false
+		}
+		sess.show(ctx, args[1:])
+
+	case "CLIENT":
+		sess.status(250, "ok")
+
+	case "OPTION":
+		if len(args) == 2 && strings.EqualFold(args[1], "MIME") {
+			sess.mime = true
+			sess.status(250, "ok - using MIME headers")
+			return false
+		}
+		sess.status(501, "syntax error, illegal parameters")
+
+	case "STATUS", "S":
+		sess.status(210, "status [up %s, %d connections]",
+			time.Since(sess.server.started).Round(time.Second), sess.server.sessions.Load())
+
+	case "HELP", "H":
+		sess.status(113, "help text follows")
+		sess.text(helpText)
+		sess.status(250, "ok")
+
+	case "AUTH", "SASLAUTH":
+		sess.status(502, "command not implemented")
+
+	case "QUIT", "Q":
+		sess.status(221, "bye")
+		return true
+
+	default:
+		sess.status(500, "unknown command")
+	}
+
+	return false
+}
+
+// helpText is returned by HELP
+const helpText = `DEFINE database word         -- look up word in database
+MATCH database strategy word -- match word in database using strategy
+SHOW DB                      -- list all accessible databases
+SHOW DATABASES               -- list all accessible databases
+SHOW STRAT                   -- list available matching strategies
+SHOW STRATEGIES              -- list available matching strategies
+SHOW INFO database           -- provide information about the database
+SHOW SERVER                  -- provide site-specific information
+OPTION MIME                  -- use MIME headers
+CLIENT info                  -- identify client to server
+STATUS                       -- display timing information
+HELP                         -- display this help information
+QUIT                         -- terminate connection
+
+Databases are named after their languages, such as swe-eng. The database
+"*" searches every database and "!" stops at the first one with results.`
+
+// show runs SHOW DB, SHOW STRAT, SHOW INFO and SHOW SERVER
+func (sess *session) show(ctx context.Context, args []string) {
+	switch strings.ToUpper(args[0]) {
+	case "DB", "DATABASES":
+		dictionaries, err := sess.server.db.Dictionaries(ctx)
+		if err != nil {
+			sess.serverError(err)
+			return
+		}
+		if len(dictionaries) == 0 {
+			sess.status(554, "no databases present")
+			return
+		}
+
+		var b strings.Builder
+		for _, dict := range dictionaries {
+			fmt.Fprintf(&b, "%s %s\n", dict.Name(), quote(description(dict)))
+		}
+		sess.status(110, "%d databases present", len(dictionaries))
+		sess.text(b.String())
+		sess.status(250, "ok")
+
+	case "STRAT", "STRATEGIES":
+		var b strings.Builder
+		for _, strat := range strategies {
+			fmt.Fprintf(&b, "%s %s\n", strat.name, quote(strat.description))
+		}
+		sess.status(111, "%d strategies present", len(strategies))
+		sess.text(b.String())
+		sess.status(250, "ok")
+
+	case "INFO":
+		if len(args) != 2 {
+			sess.status(501, "syntax error, illegal parameters")
+			return
+		}
+		dict, err := sess.dictionary(ctx, args[1])
+		if err != nil {
+			sess.serverError(err)
+			return
+		}
+		if dict == nil {
+			sess.status(550, "invalid database, use SHOW DB for list of databases")
+			return
+		}
+		stats, err := sess.server.db.Stats(ctx)
+		if err != nil {
+			sess.serverError(err)
+			return
+		}
+
+		var b strings.Builder
+		fmt.Fprintf(&b, "%s\n\n", description(*dict))
+		for _, s := range stats {
+			if s.Dictionary.ID == dict.ID {
+				fmt.Fprintf(&b, "Words:        %d\n", s.Words)
+				fmt.Fprintf(&b, "Headwords:    %d\n", s.Headwords)
+				fmt.Fprintf(&b, "Translations: %d\n", s.Translations)
+			}
+		}
+		fmt.Fprintf(&b, "Imported:     %s\n", dict.CreatedAt.Format("2006-01-02"))
+		sess.status(112, "database information follows")
+		sess.text(b.String())
+		sess.status(250, "ok")
+
+	case "SERVER":
+		sess.status(114, "server information follows")
+		sess.text(sess.server.opts.Info)
+		sess.status(250, "ok")
+
+	default:
+		sess.status(501, "syntax error, illegal parameters")
+	}
+}
+
+// define runs DEFINE database word
+func (sess *session) define(ctx context.Context, database, word string) {
+	dictionaries, ok := sess.databases(ctx, database)
+	if !ok {
+		return
+	}
+
+	type definition struct {
+		dict  lexin.Dictionary
+		entry lexin.Entry
+	}
+	var definitions []definition
+	for _, dict := range dictionaries {
+		dict := dict
+		entries, err := sess.server.db.Lookup(ctx, &dict, word)
+		if err != nil {
+			sess.serverError(err)
+			return
+		}
+		for _, entry := range entries {
+			definitions = append(definitions, definition{dict: dict, entry: entry})
+		}
+		if database == "!" && len(entries) > 0 {
+			break
+		}
+	}
+
+	if len(definitions) == 0 {
+		sess.status(552, "no match")
+		return
+	}
+
+	sess.status(150, "%d definitions retrieved", len(definitions))
+	for _, def := range definitions {
+		sess.status(151, "%s %s %s", quote(def.entry.Value), def.dict.Name(), quote(description(def.dict)))
+		sess.text(render.Text(def.entry))
+	}
+	sess.status(250, "ok")
+}
+
+// match runs MATCH database strategy word
+func (sess *session) match(ctx context.Context, database, strategyName, word string) {
+	if strategyName == "." {
+		strategyName = defaultStrategy
+	}
+	var strat *strategy
+	for i := range strategies {
+		if strings.EqualFold(strategies[i].name, strategyName) {
+			strat = &strategies[i]
+		}
+	}
+	if strat == nil {
+		sess.status(551, "invalid strategy, use SHOW STRAT for a list of strategies")
+		return
+	}
+
+	dictionaries, ok := sess.databases(ctx, database)
+	if !ok {
+		return
+	}
+
+	var b strings.Builder
+	matches := 0
+	for _, dict := range dictionaries {
+		dict := dict
+		values, err := strat.match(ctx, sess.server.db, &dict, word, sess.server.opts.MatchLimit)
+		if err != nil {
+			sess.serverError(err)
+			return
+		}
+		for _, value := range values {
+			fmt.Fprintf(&b, "%s %s\n", dict.Name(), quote(value))
+		}
+		matches += len(values)
+		if database == "!" && len(values) > 0 {
+			break
+		}
+	}
+
+	if matches == 0 {
+		sess.status(552, "no match")
+		return
+	}
+
+	sess.status(152, "%d matches found", matches)
+	sess.text(b.String())
+	sess.status(250, "ok")
+}
+
+// databases resolves the database argument of DEFINE and MATCH. "*" and "!"
+// stand for every database. When the name is unknown it writes the error
+// response and returns false.
+func (sess *session) databases(ctx context.Context, name string) ([]lexin.Dictionary, bool) {
+	if name == "*" || name == "!" {
+		dictionaries, err := sess.server.db.Dictionaries(ctx)
+		if err != nil {
+			sess.serverError(err)
+			return nil, false
+		}
+		return dictionaries, true
+	}
+
+	dict, err := sess.dictionary(ctx, name)
+	if err != nil {
+		sess.serverError(err)
+		return nil, false
+	}
+	if dict == nil {
+		sess.status(550, "invalid database, use SHOW DB for list of databases")
+		return nil, false
+	}
+	return []lexin.Dictionary{*dict}, true
+}
+
+// dictionary returns the dictionary with the given DICT database name, or
+// nil when there is none
+func (sess *session) dictionary(ctx context.Context, name string) (*lexin.Dictionary, error) {
+	dictionaries, err := sess.server.db.Dictionaries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range dictionaries {
+		if dictionaries[i].Name() == name {
+			return &dictionaries[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// description is the human readable name of a DICT database
+func description(dict lexin.Dictionary) string {
+	desc := fmt.Sprintf("Lexin %s-%s", dict.BaseLang, dict.TargetLang)
+	if dict.Version != "" {
+		desc += " " + dict.Version
+	}
+	return desc
+}
+
+// matchExact returns the headwords of the entries a DEFINE of word would
+// return, which includes headwords reached through inflected forms
+func matchExact(ctx context.Context, db *lexin.DB, dict *lexin.Dictionary, word string, limit int) ([]string, error) {
+	entries, err := db.Lookup(ctx, dict, word)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	values := []string{}
+	for _, entry := range entries {
+		if !seen[entry.Value] && len(values) < limit {
+			seen[entry.Value] = true
+			values = append(values, entry.Value)
+		}
+	}
+	return values, nil
+}
+
+// matchPrefix returns the headwords starting with word
+func matchPrefix(ctx context.Context, db *lexin.DB, dict *lexin.Dictionary, word string, limit int) ([]string, error) {
+	return db.Complete(ctx, dict, word, lexin.CompleteOptions{Limit: limit})
+}
+
+// matchSubstring returns the headwords containing word
+func matchSubstring(ctx context.Context, db *lexin.DB, dict *lexin.Dictionary, word string, limit int) ([]string, error) {
+	return db.Contains(ctx, dict, word, lexin.CompleteOptions{Limit: limit})
+}
+
+// matchLevenshtein returns the headwords one edit away from word. Diacritic
+// confusions count as a fraction of an edit, so "vag" finds "väg" and "våg".
+func matchLevenshtein(ctx context.Context, db *lexin.DB, dict *lexin.Dictionary, word string, limit int) ([]string, error) {
+	suggestions, err := db.Suggest(ctx, dict, word, lexin.SuggestOptions{Limit: limit, MaxDistance: 1})
+	if err != nil {
+		return nil, err
+	}
+
+	// Suggestions come closest first, which is the order clients show
+	values := make([]string, 0, len(suggestions))
+	for _, s := range suggestions {
+		values = append(values, s.Value)
+	}
+	return values, nil
+}
+
+// errUnterminated is returned for a quoted argument without its closing
+// quote
+var errUnterminated = errors.New("unterminated string")
+
+// splitArgs splits a command line into words. Words are separated by spaces
+// and may be quoted with double or single quotes, inside which a backslash
+// escapes the next character.
+func splitArgs(line string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inWord := false
+	var quoteChar rune
+	escaped := false
+
+	for _, r := range line {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case quoteChar != 0 && r == '\\':
+			escaped = true
+		case quoteChar != 0 && r == quoteChar:
+			quoteChar = 0
+		case quoteChar != 0:
+			current.WriteRune(r)
+		case r == '"' || r == '\'':
+			quoteChar = r
+			inWord = true
+		case r == ' ' || r == '\t':
+			if inWord {
+				args = append(args, current.String())
+				current.Reset()
+				inWord = false
+			}
+		default:
+			current.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quoteChar != 0 || escaped {
+		return nil, errUnterminated
+	}
+	if inWord {
+		args = append(args, current.String())
+	}
+
+	return args, nil
+}
+
+// quote returns s as a DICT quoted string
+func quote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
@@ -0,0 +1,203 @@
+package dictd
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"lexin-sqlite/internal/database"
+	"lexin-sqlite/internal/parser"
+	"lexin-sqlite/internal/repository"
+	"lexin-sqlite/pkg/lexin"
+)
+
+const testXML = `<Dictionary BaseLang="swe" TargetLang="eng" Version="1">
+<Word Value="hus" Type="subst." ID="1" VariantID="1">
+  <BaseLang>
+    <Meaning>byggnad</Meaning>
+    <Inflection>huset</Inflection>
+  </BaseLang>
+  <TargetLang><Translation>house</Translation></TargetLang>
+</Word>
+<Word Value="husbil" Type="subst." ID="2" VariantID="1">
+  <TargetLang><Translation>camper</Translation></TargetLang>
+</Word>
+<Word Value="bil" Type="subst." ID="3" VariantID="1">
+  <TargetLang><Translation>car</Translation></TargetLang>
+</Word>
+</Dictionary>`
+
+// openTestDB imports testXML into a database in a temporary directory
+func openTestDB(t *testing.T) *lexin.DB {
+	t.Helper()
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "lexin.db")
+
+	db, err := database.New(path)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	dict, err := parser.ParseXML(strings.NewReader(testXML))
+	if err != nil {
+		t.Fatalf("failed to parse XML: %v", err)
+	}
+	if _, err := repository.New(db).UpsertDictionary(ctx, dict); err != nil {
+		t.Fatalf("failed to import: %v", err)
+	}
+
+	ldb, err := lexin.OpenReadOnly(path)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { ldb.Close() })
+	return ldb
+}
+
+// client is the client end of a session
+type client struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// connect starts a session on an in-memory connection and reads the banner
+func connect(t *testing.T, db *lexin.DB) *client {
+	t.Helper()
+	server := New(db, Options{Hostname: "test"})
+	serverConn, clientConn := net.Pipe()
+	done := make(chan struct{})
+	go func() {
+		server.handle(serverConn)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		clientConn.Close()
+		<-done
+	})
+
+	c := &client{t: t, conn: clientConn, r: bufio.NewReader(clientConn)}
+	if banner := c.readLine(); !strings.HasPrefix(banner, "220 test ") {
+		t.Fatalf("banner = %q", banner)
+	}
+	return c
+}
+
+// readLine reads one response line without its CRLF
+func (c *client) readLine() string {
+	c.t.Helper()
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		c.t.Fatalf("failed to read response: %v", err)
+	}
+	if !strings.HasSuffix(line, "\r\n") {
+		c.t.Fatalf("response line %q does not end in CRLF", line)
+	}
+	return strings.TrimSuffix(line, "\r\n")
+}
+
+// send sends a command and returns the response lines up to the first
+// status line that is not preliminary, skipping the text bodies of 1yz
+// responses that are followed by one
+func (c *client) send(command string) []string {
+	c.t.Helper()
+	if _, err := c.conn.Write([]byte(command + "\r\n")); err != nil {
+		c.t.Fatalf("failed to send %q: %v", command, err)
+	}
+
+	var lines []string
+	inText := false
+	for {
+		line := c.readLine()
+		lines = append(lines, line)
+		switch {
+		case inText:
+			inText = line != "."
+		case len(line) >= 3 && line[0] == '1':
+			// 150 announces definitions, each with its own 151 and body
+			inText = !strings.HasPrefix(line, "150 ")
+		default:
+			return lines
+		}
+	}
+}
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+		err  bool
+	}{
+		{`DEFINE swe-eng hus`, []string{"DEFINE", "swe-eng", "hus"}, false},
+		{"  MATCH \t * prefix  hu ", []string{"MATCH", "*", "prefix", "hu"}, false},
+		{`DEFINE * "hela huset"`, []string{"DEFINE", "*", "hela huset"}, false},
+		{`DEFINE * 'hela huset'`, []string{"DEFINE", "*", "hela huset"}, false},
+		{`CLIENT "say \"hej\""`, []string{"CLIENT", `say "hej"`}, false},
+		{`CLIENT 'it\'s'`, []string{"CLIENT", "it's"}, false},
+		{`CLIENT "back\\slash"`, []string{"CLIENT", `back\slash`}, false},
+		{`CLIENT ""`, []string{"CLIENT", ""}, false},
+		{`DEFINE swe"-"eng hus`, []string{"DEFINE", "swe-eng", "hus"}, false},
+		{`DEFINE * "hus`, nil, true},
+		{`DEFINE * "hus\`, nil, true},
+		{"", nil, false},
+	}
+
+	for _, tt := range tests {
+		got, err := splitArgs(tt.line)
+		if (err != nil) != tt.err {
+			t.Errorf("splitArgs(%q) error = %v, want error %v", tt.line, err, tt.err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitArgs(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestCommands(t *testing.T) {
+	c := connect(t, openTestDB(t))
+
+	tests := []struct {
+		command string
+		want    []string
+	}{
+		{
+			"DEFINE swe-eng hus",
+			[]string{
+				"150 1 definitions retrieved",
+				`151 "hus" swe-eng "Lexin swe-eng 1"`,
+				"hus subst.",
+				"  huset",
+				"  byggnad",
+				"  = house",
+				".",
+				"250 ok",
+			},
+		},
+		{"DEFINE swe-eng båt", []string{"552 no match"}},
+		{"DEFINE sv-xx hus", []string{"550 invalid database, use SHOW DB for list of databases"}},
+		{
+			"MATCH swe-eng exact huset",
+			[]string{"152 1 matches found", `swe-eng "hus"`, ".", "250 ok"},
+		},
+		{
+			"MATCH * prefix hus",
+			[]string{"152 2 matches found", `swe-eng "hus"`, `swe-eng "husbil"`, ".", "250 ok"},
+		},
+		{"MATCH swe-eng prefix båt", []string{"552 no match"}},
+		{"MATCH swe-eng exact båt", []string{"552 no match"}},
+		{"MATCH swe-eng soundex hus", []string{"551 invalid strategy, use SHOW STRAT for a list of strategies"}},
+		{`DEFINE swe-eng "hus`, []string{"501 syntax error, illegal parameters"}},
+		{"QUIT", []string{"221 bye"}},
+	}
+
+	for _, tt := range tests {
+		if got := c.send(tt.command); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s:\ngot  %q\nwant %q", tt.command, got, tt.want)
+		}
+	}
+}
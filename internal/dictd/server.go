@@ -0,0 +1,191 @@
+// Package dictd serves a lexin-sqlite database over the DICT protocol (RFC
+// 2229), so that dict, GoldenDict and other dictd clients can use it. Each
+// dictionary is a DICT database named after its languages, such as
+// "swe-eng".
+package dictd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"lexin-sqlite/pkg/lexin"
+)
+
+// maxLineLength is the longest command line accepted; RFC 2229 limits
+// commands to 1024 octets including the line ending
+const maxLineLength = 1024
+
+// Options configures the server
+type Options struct {
+	// Hostname is announced in the banner, the system host name when empty
+	Hostname string
+	// Info is the server description returned by SHOW SERVER
+	Info string
+	// MatchLimit caps the matches returned per database by MATCH, 200 when
+	// zero
+	MatchLimit int
+	// IdleTimeout closes connections that send nothing for this long, ten
+	// minutes when zero
+	IdleTimeout time.Duration
+}
+
+// Server answers DICT requests from a database
+type Server struct {
+	db       *lexin.DB
+	opts     Options
+	started  time.Time
+	sessions atomic.Int64
+	wg       sync.WaitGroup
+}
+
+// New creates a server reading from db, which should be opened with
+// lexin.OpenReadOnly
+func New(db *lexin.DB, opts Options) *Server {
+	if opts.Hostname == "" {
+		opts.Hostname, _ = os.Hostname()
+		if opts.Hostname == "" {
+			opts.Hostname = "localhost"
+		}
+	}
+	if opts.Info == "" {
+		opts.Info = "lexin-sqlite DICT server"
+	}
+	if opts.MatchLimit <= 0 {
+		opts.MatchLimit = 200
+	}
+	if opts.IdleTimeout <= 0 {
+		opts.IdleTimeout = 10 * time.Minute
+	}
+
+	return &Server{db: db, opts: opts, started: time.Now()}
+}
+
+// Serve accepts connections on l until it is closed, then waits for the
+// open sessions to end. Closing l is the way to stop the server; Serve then
+// returns nil.
+func (s *Server) Serve(l net.Listener) error {
+	defer s.wg.Wait()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+		}()
+	}
+}
+
+// session is the state of one client connection
+type session struct {
+	server *Server
+	conn   net.Conn
+	r      *bufio.Reader
+	w      *bufio.Writer
+	mime   bool
+}
+
+// handle runs the command loop of a connection
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+
+	id := s.sessions.Add(1)
+	sess := &session{
+		server: s,
+		conn:   conn,
+		r:      bufio.NewReaderSize(conn, maxLineLength),
+		w:      bufio.NewWriter(conn),
+	}
+
+	msgID := fmt.Sprintf("<%d.%d@%s>", os.Getpid(), id, s.opts.Hostname)
+	sess.status(220, "%s lexin-sqlite <mime> %s", s.opts.Hostname, msgID)
+	if err := sess.w.Flush(); err != nil {
+		return
+	}
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(s.opts.IdleTimeout))
+		line, err := sess.readLine()
+		if err != nil {
+			if errors.Is(err, errLineTooLong) {
+				sess.status(500, "line too long")
+				sess.w.Flush()
+			}
+			return
+		}
+
+		quit := sess.dispatch(context.Background(), line)
+		if err := sess.w.Flush(); err != nil || quit {
+			return
+		}
+	}
+}
+
+// errLineTooLong is returned for command lines over maxLineLength
+var errLineTooLong = errors.New("line too long")
+
+// readLine reads one command line without its line ending
+func (sess *session) readLine() (string, error) {
+	line, err := sess.r.ReadSlice('\n')
+	if errors.Is(err, bufio.ErrBufferFull) {
+		return "", errLineTooLong
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(line), "\r\n"), nil
+}
+
+// status writes a status response line
+func (sess *session) status(code int, format string, args ...interface{}) {
+	fmt.Fprintf(sess.w, "%03d %s\r\n", code, fmt.Sprintf(format, args...))
+}
+
+// text writes a text response body terminated by a line holding a single
+// period. Lines starting with a period are doubled as the protocol
+// requires.
+func (sess *session) text(body string) {
+	if sess.mime {
+		sess.w.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+		sess.w.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	}
+
+	body = strings.TrimRight(strings.ReplaceAll(body, "\r\n", "\n"), "\n")
+	if body != "" {
+		for _, line := range strings.Split(body, "\n") {
+			if strings.HasPrefix(line, ".") {
+				sess.w.WriteString(".")
+			}
+			sess.w.WriteString(line)
+			sess.w.WriteString("\r\n")
+		}
+	}
+	sess.w.WriteString(".\r\n")
+}
+
+// serverError reports a failure the client cannot do anything about
+func (sess *session) serverError(err error) {
+	log.Printf("Error: %v", err)
+	sess.status(420, "server temporarily unavailable")
+}
@@ -0,0 +1,585 @@
+package export
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"lexin-sqlite/internal/render"
+	"lexin-sqlite/pkg/lexin"
+
+	_ "modernc.org/sqlite"
+)
+
+// Card directions of an Anki export
+const (
+	// AnkiRecognition asks for the translation of a Swedish word
+	AnkiRecognition = "recognition"
+	// AnkiProduction asks for the Swedish word of a translation
+	AnkiProduction = "production"
+	// AnkiBoth makes a card in each direction
+	AnkiBoth = "both"
+)
+
+// AnkiOptions configures an Anki export
+type AnkiOptions struct {
+	// Direction is AnkiRecognition, AnkiProduction or AnkiBoth,
+	// AnkiRecognition when empty
+	Direction string
+	// DeckName names the deck of each dictionary. {base}, {target} and
+	// {version} are replaced by the dictionary languages and version. It is
+	// "Lexin {base}-{target}" when empty.
+	DeckName string
+	// MediaDir is where the audio files named in the phonetics are looked
+	// up. Files found there are packed into the deck; the others are still
+	// referenced, so that they play once copied into Anki's media folder.
+	MediaDir string
+}
+
+// ankiFields are the fields of the note type, in order
+var ankiFields = []string{"Word", "Type", "Translation", "Example", "ExampleTranslation", "Audio"}
+
+// Anki writes an Anki package (.apkg) with a note per headword of dict, or a
+// deck per dictionary when dict is nil. The front of a card is the
+// headword, the back its translations and one example. Entries without a
+// translation are skipped.
+func Anki(ctx context.Context, w io.Writer, db *lexin.DB, dict *lexin.Dictionary, filter lexin.EntryFilter, opts AnkiOptions) error {
+	if opts.Direction == "" {
+		opts.Direction = AnkiRecognition
+	}
+	if opts.DeckName == "" {
+		opts.DeckName = "Lexin {base}-{target}"
+	}
+	switch opts.Direction {
+	case AnkiRecognition, AnkiProduction, AnkiBoth:
+	default:
+		return fmt.Errorf("unknown card direction: %s", opts.Direction)
+	}
+
+	dictionaries := []lexin.Dictionary{}
+	if dict != nil {
+		dictionaries = append(dictionaries, *dict)
+	} else {
+		var err error
+		if dictionaries, err = db.Dictionaries(ctx); err != nil {
+			return err
+		}
+	}
+
+	// The collection is an SQLite database, which needs a file
+	tmp, err := os.MkdirTemp("", "lexin-anki-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	collectionPath := filepath.Join(tmp, "collection.anki2")
+
+	col, err := newAnkiCollection(collectionPath, opts)
+	if err != nil {
+		return err
+	}
+	for _, d := range dictionaries {
+		d := d
+		deckID := col.addDeck(ankiDeckName(opts.DeckName, d))
+		err := db.EachEntry(ctx, &d, filter, func(entry lexin.Entry) error {
+			return col.addNote(ctx, d, deckID, entry)
+		})
+		if err != nil {
+			col.abort()
+			return err
+		}
+	}
+	if err := col.finish(ctx); err != nil {
+		return err
+	}
+
+	return writeApkg(w, collectionPath, col.media)
+}
+
+// ankiDeckName fills in the deck name template of a dictionary
+func ankiDeckName(template string, dict lexin.Dictionary) string {
+	return strings.NewReplacer(
+		"{base}", dict.BaseLang,
+		"{target}", dict.TargetLang,
+		"{version}", dict.Version,
+	).Replace(template)
+}
+
+// ankiSchema is the schema of an Anki 2.1 collection in the version 11
+// layout, which every Anki release imports
+const ankiSchema = `
+CREATE TABLE col (
+    id integer primary key, crt integer not null, mod integer not null,
+    scm integer not null, ver integer not null, dty integer not null,
+    usn integer not null, ls integer not null, conf text not null,
+    models text not null, decks text not null, dconf text not null,
+    tags text not null
+);
+CREATE TABLE notes (
+    id integer primary key, guid text not null, mid integer not null,
+    mod integer not null, usn integer not null, tags text not null,
+    flds text not null, sfld integer not null, csum integer not null,
+    flags integer not null, data text not null
+);
+CREATE TABLE cards (
+    id integer primary key, nid integer not null, did integer not null,
+    ord integer not null, mod integer not null, usn integer not null,
+    type integer not null, queue integer not null, due integer not null,
+    ivl integer not null, factor integer not null, reps integer not null,
+    lapses integer not null, left integer not null, odue integer not null,
+    odid integer not null, flags integer not null, data text not null
+);
+CREATE TABLE revlog (
+    id integer primary key, cid integer not null, usn integer not null,
+    ease integer not null, ivl integer not null, lastIvl integer not null,
+    factor integer not null, time integer not null, type integer not null
+);
+CREATE TABLE graves (usn integer not null, oid integer not null, type integer not null);
+CREATE INDEX ix_notes_usn on notes (usn);
+CREATE INDEX ix_cards_usn on cards (usn);
+CREATE INDEX ix_revlog_usn on revlog (usn);
+CREATE INDEX ix_cards_nid on cards (nid);
+CREATE INDEX ix_cards_sched on cards (did, queue, due);
+CREATE INDEX ix_revlog_cid on revlog (cid);
+CREATE INDEX ix_notes_csum on notes (csum);
+`
+
+// ankiCollection builds collection.anki2
+type ankiCollection struct {
+	db       *sql.DB
+	tx       *sql.Tx
+	opts     AnkiOptions
+	now      time.Time
+	modelID  int64
+	nextID   int64
+	position int
+	decks    map[string]interface{}
+	// media maps the names of audio files to their paths on disk
+	media map[string]string
+}
+
+// newAnkiCollection creates an empty collection at path
+func newAnkiCollection(path string, opts AnkiOptions) (*ankiCollection, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(ankiSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create Anki collection: %w", err)
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	now := time.Now()
+	col := &ankiCollection{
+		db:    db,
+		tx:    tx,
+		opts:  opts,
+		now:   now,
+		decks: map[string]interface{}{"1": ankiDeck(1, "Default", now)},
+		media: make(map[string]string),
+	}
+	// Anki uses millisecond timestamps as ids; the model id is fixed so
+	// that re-importing an export updates the notes instead of adding a
+	// second note type
+	col.modelID = 1607392319000
+	col.nextID = now.UnixMilli()
+
+	return col, nil
+}
+
+// id returns a fresh note, card or deck id
+func (col *ankiCollection) id() int64 {
+	col.nextID++
+	return col.nextID
+}
+
+// addDeck adds a deck and returns its id
+func (col *ankiCollection) addDeck(name string) int64 {
+	id := col.id()
+	col.decks[strconv.FormatInt(id, 10)] = ankiDeck(id, name, col.now)
+	return id
+}
+
+// addNote adds the note and cards of an entry
+func (col *ankiCollection) addNote(ctx context.Context, dict lexin.Dictionary, deckID int64, entry lexin.Entry) error {
+	translations := render.Translations(entry)
+	if len(translations) == 0 {
+		return nil
+	}
+
+	var example, exampleTranslation, audio string
+	for _, base := range entry.BaseLangs {
+		if pairs := render.Examples(entry, base); example == "" && len(pairs) > 0 {
+			example, exampleTranslation = pairs[0].Swedish, pairs[0].Translation
+		}
+		if audio == "" && base.Phonetic != nil && base.Phonetic.File != "" {
+			audio = col.audio(base.Phonetic.File)
+		}
+	}
+
+	fields := []string{
+		html.EscapeString(entry.Value),
+		html.EscapeString(entry.Type),
+		html.EscapeString(strings.Join(translations, ", ")),
+		html.EscapeString(example),
+		html.EscapeString(exampleTranslation),
+		audio,
+	}
+
+	noteID := col.id()
+	mod := col.now.Unix()
+	_, err := col.tx.ExecContext(ctx, `
+		INSERT INTO notes (id, guid, mid, mod, usn, tags, flds, sfld, csum, flags, data)
+		VALUES (?, ?, ?, ?, -1, ?, ?, ?, ?, 0, '')
+	`,
+		noteID,
+		ankiGUID(dict.Name(), entry.OriginalID, entry.VariantID),
+		col.modelID,
+		mod,
+		ankiTags(entry.Type),
+		strings.Join(fields, "\x1f"),
+		entry.Value,
+		ankiChecksum(entry.Value),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to add note %s: %w", entry.Value, err)
+	}
+
+	col.position++
+	for _, ord := range col.templateOrds() {
+		_, err := col.tx.ExecContext(ctx, `
+			INSERT INTO cards (id, nid, did, ord, mod, usn, type, queue, due, ivl, factor, reps, lapses, left, odue, odid, flags, data)
+			VALUES (?, ?, ?, ?, ?, -1, 0, 0, ?, 0, 0, 0, 0, 0, 0, 0, 0, '')
+		`, col.id(), noteID, deckID, ord, mod, col.position)
+		if err != nil {
+			return fmt.Errorf("failed to add card %s: %w", entry.Value, err)
+		}
+	}
+
+	return nil
+}
+
+// audio returns the sound reference of an audio file and packs the file
+// when it is found in the media directory
+func (col *ankiCollection) audio(file string) string {
+	name := filepath.Base(file)
+	if col.opts.MediaDir != "" {
+		path := filepath.Join(col.opts.MediaDir, name)
+		if _, err := os.Stat(path); err == nil {
+			col.media[name] = path
+		}
+	}
+	return "[sound:" + name + "]"
+}
+
+// templateOrds returns the ordinals of the card templates in use
+func (col *ankiCollection) templateOrds() []int {
+	switch col.opts.Direction {
+	case AnkiProduction:
+		return []int{1}
+	case AnkiBoth:
+		return []int{0, 1}
+	default:
+		return []int{0}
+	}
+}
+
+// abort discards the collection after a failed export
+func (col *ankiCollection) abort() {
+	col.tx.Rollback()
+	col.db.Close()
+}
+
+// finish writes the col row and closes the collection
+func (col *ankiCollection) finish(ctx context.Context) error {
+	defer col.db.Close()
+
+	models, err := json.Marshal(map[string]interface{}{
+		strconv.FormatInt(col.modelID, 10): col.model(),
+	})
+	if err != nil {
+		return err
+	}
+	decks, err := json.Marshal(col.decks)
+	if err != nil {
+		return err
+	}
+	dconf, err := json.Marshal(map[string]interface{}{"1": ankiDeckConfig()})
+	if err != nil {
+		return err
+	}
+	conf, err := json.Marshal(map[string]interface{}{
+		"activeDecks":   []int64{1},
+		"curDeck":       1,
+		"newSpread":     0,
+		"collapseTime":  1200,
+		"timeLim":       0,
+		"estTimes":      true,
+		"dueCounts":     true,
+		"curModel":      strconv.FormatInt(col.modelID, 10),
+		"nextPos":       col.position + 1,
+		"sortType":      "noteFld",
+		"sortBackwards": false,
+		"addToCur":      true,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = col.tx.ExecContext(ctx, `
+		INSERT INTO col (id, crt, mod, scm, ver, dty, usn, ls, conf, models, decks, dconf, tags)
+		VALUES (1, ?, ?, ?, 11, 0, 0, 0, ?, ?, ?, ?, '{}')
+	`, col.now.Unix(), col.now.UnixMilli(), col.now.UnixMilli(), string(conf), string(models), string(decks), string(dconf))
+	if err != nil {
+		col.tx.Rollback()
+		return fmt.Errorf("failed to write Anki collection: %w", err)
+	}
+
+	return col.tx.Commit()
+}
+
+// model returns the note type. Both templates are always defined so that
+// notes keep their type whatever the direction; only the cards of the
+// chosen direction are generated.
+func (col *ankiCollection) model() map[string]interface{} {
+	fields := make([]map[string]interface{}, 0, len(ankiFields))
+	for i, name := range ankiFields {
+		fields = append(fields, map[string]interface{}{
+			"name":   name,
+			"ord":    i,
+			"sticky": false,
+			"rtl":    false,
+			"font":   "Arial",
+			"size":   20,
+			"media":  []string{},
+		})
+	}
+
+	word := `<div class="word">{{Word}}</div><div class="type">{{Type}}</div>{{Audio}}`
+	translation := `<div class="translation">{{Translation}}</div>`
+	example := `{{#Example}}<div class="example">{{Example}}{{#ExampleTranslation}}<br><i>{{ExampleTranslation}}</i>{{/ExampleTranslation}}</div>{{/Example}}`
+	templates := []map[string]interface{}{
+		{
+			"name":  "Recognition",
+			"ord":   0,
+			"qfmt":  word,
+			"afmt":  `{{FrontSide}}<hr id="answer">` + translation + example,
+			"did":   nil,
+			"bqfmt": "",
+			"bafmt": "",
+		},
+		{
+			"name":  "Production",
+			"ord":   1,
+			"qfmt":  translation,
+			"afmt":  `{{FrontSide}}<hr id="answer">` + word + example,
+			"did":   nil,
+			"bqfmt": "",
+			"bafmt": "",
+		},
+	}
+
+	return map[string]interface{}{
+		"id":        col.modelID,
+		"name":      "Lexin",
+		"type":      0,
+		"mod":       col.now.Unix(),
+		"usn":       -1,
+		"sortf":     0,
+		"did":       1,
+		"tmpls":     templates,
+		"flds":      fields,
+		"css":       ".card { font-family: arial; font-size: 20px; text-align: center; }\n.word { font-size: 32px; }\n.type, .example { color: #666; font-size: 16px; }\n",
+		"latexPre":  "\\documentclass[12pt]{article}\n\\special{papersize=3in,5in}\n\\usepackage[utf8]{inputenc}\n\\usepackage{amssymb,amsmath}\n\\pagestyle{empty}\n\\setlength{\\parindent}{0in}\n\\begin{document}\n",
+		"latexPost": "\\end{document}",
+		"tags":      []string{},
+		"vers":      []interface{}{},
+		// A card is generated when its question field is not empty
+		"req": []interface{}{
+			[]interface{}{0, "any", []int{0}},
+			[]interface{}{1, "any", []int{2}},
+		},
+	}
+}
+
+// ankiDeck returns the JSON of a deck
+func ankiDeck(id int64, name string, now time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"id":               id,
+		"name":             name,
+		"mod":              now.Unix(),
+		"usn":              -1,
+		"lrnToday":         []int{0, 0},
+		"revToday":         []int{0, 0},
+		"newToday":         []int{0, 0},
+		"timeToday":        []int{0, 0},
+		"collapsed":        false,
+		"browserCollapsed": false,
+		"desc":             "",
+		"dyn":              0,
+		"conf":             1,
+		"extendNew":        10,
+		"extendRev":        50,
+	}
+}
+
+// ankiDeckConfig returns Anki's default deck options
+func ankiDeckConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"id":       1,
+		"name":     "Default",
+		"mod":      0,
+		"usn":      0,
+		"maxTaken": 60,
+		"autoplay": true,
+		"timer":    0,
+		"replayq":  true,
+		"dyn":      false,
+		"new": map[string]interface{}{
+			"delays":        []float64{1, 10},
+			"ints":          []int{1, 4, 7},
+			"initialFactor": 2500,
+			"order":         1,
+			"perDay":        20,
+			"bury":          true,
+			"separate":      true,
+		},
+		"lapse": map[string]interface{}{
+			"delays":      []float64{10},
+			"mult":        0,
+			"minInt":      1,
+			"leechFails":  8,
+			"leechAction": 0,
+		},
+		"rev": map[string]interface{}{
+			"perDay":   200,
+			"ease4":    1.3,
+			"fuzz":     0.05,
+			"ivlFct":   1,
+			"maxIvl":   36500,
+			"minSpace": 1,
+			"bury":     true,
+		},
+	}
+}
+
+// ankiGUIDChars is the alphabet of note guids, the base91 set Anki uses
+const ankiGUIDChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789!#$%&()*+,-./:;<=>?@[]^_`{|}~"
+
+// ankiGUID derives a stable note guid from the identity of an entry, so
+// that importing a newer export updates the existing notes
+func ankiGUID(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x1f")))
+	n := binary.BigEndian.Uint64(sum[:8])
+
+	var b []byte
+	base := uint64(len(ankiGUIDChars))
+	for n > 0 {
+		b = append(b, ankiGUIDChars[n%base])
+		n /= base
+	}
+	return string(b)
+}
+
+// ankiTags returns the space separated tags of a note, wrapped in spaces as
+// Anki stores them. Tags cannot hold spaces, so those in word types become
+// underscores.
+func ankiTags(wordType string) string {
+	tags := []string{"lexin"}
+	if wordType = strings.TrimSpace(wordType); wordType != "" {
+		tags = append(tags, strings.ReplaceAll(wordType, " ", "_"))
+	}
+	return " " + strings.Join(tags, " ") + " "
+}
+
+// htmlTag matches the markup Anki strips before checksumming a field
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+// ankiChecksum is the checksum of the sort field Anki uses to find
+// duplicates: the first 8 hex digits of the SHA-1 of the field as text
+func ankiChecksum(field string) int64 {
+	sum := sha1.Sum([]byte(html.UnescapeString(htmlTag.ReplaceAllString(field, ""))))
+	n, _ := strconv.ParseInt(hex.EncodeToString(sum[:])[:8], 16, 64)
+	return n
+}
+
+// writeApkg zips the collection and the media files into an Anki package.
+// Media files are stored under numbers, with a "media" file mapping the
+// numbers to their names.
+func writeApkg(w io.Writer, collectionPath string, media map[string]string) error {
+	zw := zip.NewWriter(w)
+
+	if err := addZipFile(zw, "collection.anki2", collectionPath); err != nil {
+		return err
+	}
+
+	mapping := make(map[string]string, len(media))
+	i := 0
+	for name, path := range media {
+		key := strconv.Itoa(i)
+		if err := addZipFile(zw, key, path); err != nil {
+			return err
+		}
+		mapping[key] = name
+		i++
+	}
+
+	mediaJSON, err := json.Marshal(mapping)
+	if err != nil {
+		return err
+	}
+	f, err := createZipEntry(zw, "media")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(mediaJSON); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+// addZipFile copies the file at path into the archive under name
+func addZipFile(zw *zip.Writer, name, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	f, err := createZipEntry(zw, name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, file)
+	return err
+}
+
+// createZipEntry starts a compressed archive member stamped with the
+// current time
+func createZipEntry(zw *zip.Writer, name string) (io.Writer, error) {
+	return zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: time.Now(),
+	})
+}
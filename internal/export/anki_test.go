@@ -0,0 +1,153 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"lexin-sqlite/pkg/lexin"
+)
+
+// readZip reads the members of a zip archive by name
+func readZip(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("export is not a zip archive: %v", err)
+	}
+	members := make(map[string][]byte)
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatalf("failed to open %s: %v", f.Name, err)
+		}
+		content, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", f.Name, err)
+		}
+		members[f.Name] = content
+	}
+	return members
+}
+
+// readApkg unpacks an Anki package, returning the collection opened from a
+// temporary file and the members of the archive by name
+func readApkg(t *testing.T, data []byte) (*sql.DB, map[string][]byte) {
+	t.Helper()
+
+	members := readZip(t, data)
+	collection, ok := members["collection.anki2"]
+	if !ok {
+		t.Fatalf("package has no collection.anki2")
+	}
+	path := filepath.Join(t.TempDir(), "collection.anki2")
+	if err := os.WriteFile(path, collection, 0o644); err != nil {
+		t.Fatalf("failed to write the collection: %v", err)
+	}
+	col, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("failed to open the collection: %v", err)
+	}
+	t.Cleanup(func() { col.Close() })
+
+	return col, members
+}
+
+func TestAnki(t *testing.T) {
+	ctx := context.Background()
+	db, dict := importFixture(t)
+
+	media := t.TempDir()
+	if err := os.WriteFile(filepath.Join(media, "hus.swf"), []byte("sound"), 0o644); err != nil {
+		t.Fatalf("failed to write media file: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := Anki(ctx, &out, db, dict, lexin.EntryFilter{}, AnkiOptions{Direction: AnkiBoth, MediaDir: media}); err != nil {
+		t.Fatalf("Anki failed: %v", err)
+	}
+	col, members := readApkg(t, out.Bytes())
+
+	// The media file is packed under a number named in the media map
+	var mapping map[string]string
+	if err := json.Unmarshal(members["media"], &mapping); err != nil {
+		t.Fatalf("media is not a JSON object: %v", err)
+	}
+	if !reflect.DeepEqual(mapping, map[string]string{"0": "hus.swf"}) {
+		t.Errorf("media = %v, want hus.swf as 0", mapping)
+	}
+	if string(members["0"]) != "sound" {
+		t.Errorf("media file 0 = %q, want the content of hus.swf", members["0"])
+	}
+
+	// Entries without a translation, the second bostad, have no note
+	rows, err := col.Query(`SELECT id, flds, sfld, tags FROM notes ORDER BY id`)
+	if err != nil {
+		t.Fatalf("failed to read notes: %v", err)
+	}
+	defer rows.Close()
+	var notes [][]string
+	var noteIDs []int64
+	for rows.Next() {
+		var id int64
+		var flds, sfld, tags string
+		if err := rows.Scan(&id, &flds, &sfld, &tags); err != nil {
+			t.Fatalf("failed to read notes: %v", err)
+		}
+		fields := strings.Split(flds, "\x1f")
+		if len(fields) != len(ankiFields) {
+			t.Fatalf("note %s has %d fields, want %d", sfld, len(fields), len(ankiFields))
+		}
+		if sfld != fields[0] || tags != " lexin subst. " {
+			t.Errorf("note %s has sort field %q and tags %q", fields[0], sfld, tags)
+		}
+		notes = append(notes, fields)
+		noteIDs = append(noteIDs, id)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("failed to read notes: %v", err)
+	}
+	if len(notes) != 2 {
+		t.Fatalf("got %d notes, want 2", len(notes))
+	}
+
+	hus := notes[0]
+	if hus[0] != "hus" || hus[1] != "subst." || !strings.HasPrefix(hus[2], "house, building") {
+		t.Errorf("hus note = %q", hus)
+	}
+	if hus[3] != "bo i ett stort hus" || hus[4] != "live in a big house" {
+		t.Errorf("hus example = %q / %q", hus[3], hus[4])
+	}
+	if hus[5] != "[sound:hus.swf]" {
+		t.Errorf("hus audio = %q, want [sound:hus.swf]", hus[5])
+	}
+	if notes[1][0] != "bostad" || notes[1][2] != "dwelling" {
+		t.Errorf("bostad note = %q", notes[1])
+	}
+
+	// Both directions give each note two cards in the dictionary deck
+	var cards, decks int
+	if err := col.QueryRow(`SELECT COUNT(*), COUNT(DISTINCT did) FROM cards WHERE nid IN (?, ?)`, noteIDs[0], noteIDs[1]).Scan(&cards, &decks); err != nil {
+		t.Fatalf("failed to count cards: %v", err)
+	}
+	if cards != 4 || decks != 1 {
+		t.Errorf("got %d cards in %d decks, want 4 in 1", cards, decks)
+	}
+	var deckJSON string
+	if err := col.QueryRow(`SELECT decks FROM col`).Scan(&deckJSON); err != nil {
+		t.Fatalf("failed to read decks: %v", err)
+	}
+	if !strings.Contains(deckJSON, `"Lexin swe-eng"`) {
+		t.Errorf("decks = %s, want a Lexin swe-eng deck", deckJSON)
+	}
+}
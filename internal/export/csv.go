@@ -0,0 +1,128 @@
+package export
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	"lexin-sqlite/internal/render"
+	"lexin-sqlite/pkg/lexin"
+)
+
+// CSVColumns are the columns written when CSVOptions.Columns is empty
+var CSVColumns = []string{"headword", "variant", "type", "meaning", "graminfo", "translation", "synonym", "example"}
+
+// CSVOptions configures the CSV export
+type CSVOptions struct {
+	// Columns are the names of the columns to write, in order, CSVColumns
+	// when empty
+	Columns []string
+	// Delimiter separates the fields, a comma when zero
+	Delimiter rune
+}
+
+// csvRow is one base language of a word with its target language
+type csvRow struct {
+	dictionary string
+	entry      lexin.Entry
+	base       lexin.BaseLang
+	target     lexin.TargetLang
+}
+
+// csvColumns maps column names to the value they take from a row
+var csvColumns = map[string]func(row csvRow) string{
+	"dictionary": func(row csvRow) string { return row.dictionary },
+	"id":         func(row csvRow) string { return row.entry.OriginalID },
+	"variant_id": func(row csvRow) string { return row.entry.VariantID },
+	"headword":   func(row csvRow) string { return row.entry.Value },
+	"variant":    func(row csvRow) string { return row.entry.Variant },
+	"type":       func(row csvRow) string { return row.entry.Type },
+	"meaning":    func(row csvRow) string { return row.base.Meaning.Content },
+	"graminfo":   func(row csvRow) string { return row.base.Graminfo },
+	"inflections": func(row csvRow) string {
+		return render.Inflections(row.base)
+	},
+	"phonetic": func(row csvRow) string {
+		if row.base.Phonetic == nil {
+			return ""
+		}
+		return row.base.Phonetic.Content
+	},
+	"translation": func(row csvRow) string { return strings.Join(row.target.Translations, "; ") },
+	"synonym":     func(row csvRow) string { return strings.Join(row.target.Synonyms, "; ") },
+	"example": func(row csvRow) string {
+		if examples := render.Examples(row.entry, row.base); len(examples) > 0 {
+			return examples[0].Swedish
+		}
+		return ""
+	},
+	"example_translation": func(row csvRow) string {
+		if examples := render.Examples(row.entry, row.base); len(examples) > 0 {
+			return examples[0].Translation
+		}
+		return ""
+	},
+}
+
+// CSV writes the entries of dict, or of every dictionary when dict is nil,
+// as a table with a header line and a row per base language of each word.
+// The target language at the same position fills the translation columns.
+// Fields are quoted as RFC 4180 describes and lines end in CRLF.
+func CSV(ctx context.Context, w io.Writer, db *lexin.DB, dict *lexin.Dictionary, filter lexin.EntryFilter, opts CSVOptions) error {
+	columns := opts.Columns
+	if len(columns) == 0 {
+		columns = CSVColumns
+	}
+	values := make([]func(row csvRow) string, len(columns))
+	for i, column := range columns {
+		value, ok := csvColumns[strings.ToLower(strings.TrimSpace(column))]
+		if !ok {
+			return fmt.Errorf("unknown column: %s", column)
+		}
+		values[i] = value
+	}
+
+	names, err := dictionaryNames(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(w)
+	if opts.Delimiter != 0 {
+		cw.Comma = opts.Delimiter
+	}
+	cw.UseCRLF = true
+
+	if err := cw.Write(columns); err != nil {
+		return err
+	}
+
+	record := make([]string, len(columns))
+	err = db.EachEntry(ctx, dict, filter, func(entry lexin.Entry) error {
+		rows := max(len(entry.BaseLangs), len(entry.TargetLangs), 1)
+		for i := 0; i < rows; i++ {
+			row := csvRow{dictionary: names[entry.DictionaryID], entry: entry}
+			if i < len(entry.BaseLangs) {
+				row.base = entry.BaseLangs[i]
+				row.target, _ = render.Target(entry, row.base, i)
+			} else if i < len(entry.TargetLangs) {
+				row.target = entry.TargetLangs[i]
+			}
+			for j, value := range values {
+				record[j] = value(row)
+			}
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
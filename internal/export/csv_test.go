@@ -0,0 +1,113 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"reflect"
+	"strings"
+	"testing"
+
+	"lexin-sqlite/internal/parser"
+	"lexin-sqlite/pkg/lexin"
+)
+
+// csvDocument has meanings and translations that need quoting
+const csvDocument = `<Dictionary BaseLang="swe" TargetLang="eng" Version="1">
+<Word Value="hus" Type="subst." ID="1" VariantID="1">
+  <BaseLang><Meaning>byggnad, bostad</Meaning></BaseLang>
+  <BaseLang><Meaning>ett "hem"</Meaning></BaseLang>
+  <TargetLang><Translation>house</Translation></TargetLang>
+  <TargetLang><Translation>home</Translation><Translation>household</Translation></TargetLang>
+</Word>
+<Word Value="rad" Type="subst." ID="2" VariantID="1">
+  <BaseLang><Meaning>första
+andra</Meaning></BaseLang>
+</Word>
+</Dictionary>`
+
+// exportCSV exports csvDocument as CSV with opts
+func exportCSV(t *testing.T, opts CSVOptions) string {
+	t.Helper()
+	source, err := parser.ParseXML(strings.NewReader(csvDocument))
+	if err != nil {
+		t.Fatalf("failed to parse XML: %v", err)
+	}
+	db, dict := importDictionary(t, source)
+
+	var out bytes.Buffer
+	if err := CSV(context.Background(), &out, db, dict, lexin.EntryFilter{}, opts); err != nil {
+		t.Fatalf("CSV failed: %v", err)
+	}
+	return out.String()
+}
+
+func TestCSVQuoting(t *testing.T) {
+	got := exportCSV(t, CSVOptions{Columns: []string{"headword", "meaning", "translation"}})
+
+	// Line breaks inside quoted fields are written as CRLF too
+	want := "headword,meaning,translation\r\n" +
+		"hus,\"byggnad, bostad\",house\r\n" +
+		"hus,\"ett \"\"hem\"\"\",home; household\r\n" +
+		"rad,\"första\r\nandra\",\r\n"
+	if got != want {
+		t.Errorf("CSV =\n%q\nwant\n%q", got, want)
+	}
+
+	records, err := csv.NewReader(strings.NewReader(got)).ReadAll()
+	if err != nil {
+		t.Fatalf("export does not read back: %v", err)
+	}
+	wantRecords := [][]string{
+		{"headword", "meaning", "translation"},
+		{"hus", "byggnad, bostad", "house"},
+		{"hus", `ett "hem"`, "home; household"},
+		{"rad", "första\nandra", ""},
+	}
+	if !reflect.DeepEqual(records, wantRecords) {
+		t.Errorf("records = %q, want %q", records, wantRecords)
+	}
+}
+
+func TestCSVColumns(t *testing.T) {
+	tests := []struct {
+		name string
+		opts CSVOptions
+		want []string
+	}{
+		{"default", CSVOptions{}, CSVColumns},
+		{"chosen order", CSVOptions{Columns: []string{"translation", "dictionary", "id"}}, []string{"translation", "dictionary", "id"}},
+		{"case and spaces", CSVOptions{Columns: []string{" Headword", "TYPE "}}, []string{" Headword", "TYPE "}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := csv.NewReader(strings.NewReader(exportCSV(t, tt.opts))).ReadAll()
+			if err != nil {
+				t.Fatalf("export does not read back: %v", err)
+			}
+			if !reflect.DeepEqual(records[0], tt.want) {
+				t.Errorf("header = %q, want %q", records[0], tt.want)
+			}
+			for _, record := range records {
+				if len(record) != len(tt.want) {
+					t.Errorf("record %q has %d fields, want %d", record, len(record), len(tt.want))
+				}
+			}
+		})
+	}
+
+	got := exportCSV(t, CSVOptions{Columns: []string{"translation", "dictionary", "id"}, Delimiter: ';'})
+	if want := "translation;dictionary;id\r\nhouse;swe-eng;1\r\n\"home; household\";swe-eng;1\r\n;swe-eng;2\r\n"; got != want {
+		t.Errorf("CSV =\n%q\nwant\n%q", got, want)
+	}
+
+	source, err := parser.ParseXML(strings.NewReader(csvDocument))
+	if err != nil {
+		t.Fatalf("failed to parse XML: %v", err)
+	}
+	db, dict := importDictionary(t, source)
+	err = CSV(context.Background(), &bytes.Buffer{}, db, dict, lexin.EntryFilter{}, CSVOptions{Columns: []string{"headword", "colour"}})
+	if err == nil || !strings.Contains(err.Error(), "colour") {
+		t.Errorf("unknown column error = %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package export
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"time"
+)
+
+// dictzipChunkSize is the amount of uncompressed data in each dictzip
+// chunk, the value dictzip itself uses
+const dictzipChunkSize = 58315
+
+// writeDictzip compresses r into w in the dictzip format: a gzip file whose
+// deflate stream is cut into chunks that can be decompressed on their own,
+// with a table of the chunk sizes in the gzip header. Readers use it to
+// seek in the compressed file.
+func writeDictzip(w io.Writer, r io.Reader) error {
+	var compressed bytes.Buffer
+	var sizes []uint16
+	crc := crc32.NewIEEE()
+	var total uint32
+
+	fw, err := flate.NewWriter(nil, flate.BestCompression)
+	if err != nil {
+		return err
+	}
+
+	chunk := make([]byte, dictzipChunkSize)
+	for {
+		n, err := io.ReadFull(r, chunk)
+		last := err == io.EOF || err == io.ErrUnexpectedEOF
+		if err != nil && !last {
+			return err
+		}
+		if n == 0 && len(sizes) > 0 {
+			// The previous chunk was exactly full; close the stream with
+			// an empty final chunk
+			last = true
+		}
+
+		crc.Write(chunk[:n])
+		total += uint32(n)
+
+		// A fresh compressor for each chunk keeps back references inside
+		// the chunk, and flushing aligns the chunk end to a byte
+		start := compressed.Len()
+		fw.Reset(&compressed)
+		if _, err := fw.Write(chunk[:n]); err != nil {
+			return err
+		}
+		if last {
+			err = fw.Close()
+		} else {
+			err = fw.Flush()
+		}
+		if err != nil {
+			return err
+		}
+
+		size := compressed.Len() - start
+		if size > 0xffff {
+			return errors.New("dictzip chunk does not compress into 64 KiB")
+		}
+		sizes = append(sizes, uint16(size))
+
+		if last {
+			break
+		}
+	}
+
+	// The extra field holds the RA subfield: version, chunk length, chunk
+	// count and the compressed size of each chunk
+	if 10+2*len(sizes) > 0xffff {
+		return errors.New("dictionary too large for dictzip")
+	}
+	var extra bytes.Buffer
+	extra.WriteString("RA")
+	binary.Write(&extra, binary.LittleEndian, uint16(6+2*len(sizes)))
+	binary.Write(&extra, binary.LittleEndian, uint16(1))
+	binary.Write(&extra, binary.LittleEndian, uint16(dictzipChunkSize))
+	binary.Write(&extra, binary.LittleEndian, uint16(len(sizes)))
+	for _, size := range sizes {
+		binary.Write(&extra, binary.LittleEndian, size)
+	}
+
+	var header bytes.Buffer
+	header.Write([]byte{0x1f, 0x8b, 8, 0x04}) // magic, deflate, FEXTRA
+	binary.Write(&header, binary.LittleEndian, uint32(time.Now().Unix()))
+	header.Write([]byte{2, 3}) // best compression, Unix
+	binary.Write(&header, binary.LittleEndian, uint16(extra.Len()))
+	header.Write(extra.Bytes())
+
+	var trailer [8]byte
+	binary.LittleEndian.PutUint32(trailer[0:], crc.Sum32())
+	binary.LittleEndian.PutUint32(trailer[4:], total)
+
+	for _, part := range [][]byte{header.Bytes(), compressed.Bytes(), trailer[:]} {
+		if _, err := w.Write(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package export
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+// dictzipHeader is the RA subfield of a dictzip file
+type dictzipHeader struct {
+	version   uint16
+	chunkSize uint16
+	sizes     []uint16
+	dataStart int
+}
+
+// readDictzipHeader parses the gzip header of a dictzip file
+func readDictzipHeader(t *testing.T, data []byte) dictzipHeader {
+	t.Helper()
+	if len(data) < 12 || data[0] != 0x1f || data[1] != 0x8b || data[2] != 8 {
+		t.Fatalf("not a gzip file: % x", data[:min(len(data), 12)])
+	}
+	if data[3]&0x04 == 0 {
+		t.Fatalf("FEXTRA flag not set: %#x", data[3])
+	}
+
+	xlen := int(binary.LittleEndian.Uint16(data[10:]))
+	extra := data[12 : 12+xlen]
+	if string(extra[:2]) != "RA" {
+		t.Fatalf("extra subfield = %q, want RA", extra[:2])
+	}
+	length := int(binary.LittleEndian.Uint16(extra[2:]))
+	if length != xlen-4 {
+		t.Fatalf("RA length = %d, want %d", length, xlen-4)
+	}
+
+	h := dictzipHeader{
+		version:   binary.LittleEndian.Uint16(extra[4:]),
+		chunkSize: binary.LittleEndian.Uint16(extra[6:]),
+		dataStart: 12 + xlen,
+	}
+	count := int(binary.LittleEndian.Uint16(extra[8:]))
+	if length != 6+2*count {
+		t.Fatalf("RA length = %d for %d chunks", length, count)
+	}
+	for i := 0; i < count; i++ {
+		h.sizes = append(h.sizes, binary.LittleEndian.Uint16(extra[10+2*i:]))
+	}
+	return h
+}
+
+// dictzipInput returns n bytes of loosely compressible text
+func dictzipInput(n int) []byte {
+	words := []string{"hus ", "bil ", "båt ", "väg ", "house ", "car\n", "boat ", "road "}
+	rng := rand.New(rand.NewSource(1))
+	var buf bytes.Buffer
+	for buf.Len() < n {
+		buf.WriteString(words[rng.Intn(len(words))])
+	}
+	return buf.Bytes()[:n]
+}
+
+func TestWriteDictzip(t *testing.T) {
+	tests := []struct {
+		name   string
+		size   int
+		chunks int
+	}{
+		{"empty", 0, 1},
+		{"small", 100, 1},
+		{"one byte short of a chunk", dictzipChunkSize - 1, 1},
+		{"exactly one chunk", dictzipChunkSize, 2},
+		{"one byte over a chunk", dictzipChunkSize + 1, 2},
+		{"exactly three chunks", 3 * dictzipChunkSize, 4},
+		{"several chunks", 3*dictzipChunkSize + 1234, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := dictzipInput(tt.size)
+
+			var out bytes.Buffer
+			if err := writeDictzip(&out, bytes.NewReader(input)); err != nil {
+				t.Fatalf("writeDictzip failed: %v", err)
+			}
+			data := out.Bytes()
+
+			h := readDictzipHeader(t, data)
+			if h.version != 1 {
+				t.Errorf("version = %d, want 1", h.version)
+			}
+			if h.chunkSize != dictzipChunkSize {
+				t.Errorf("chunk size = %d, want %d", h.chunkSize, dictzipChunkSize)
+			}
+			if len(h.sizes) != tt.chunks {
+				t.Fatalf("chunk count = %d, want %d", len(h.sizes), tt.chunks)
+			}
+
+			// The whole file is an ordinary gzip file
+			zr, err := gzip.NewReader(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("failed to open as gzip: %v", err)
+			}
+			whole, err := io.ReadAll(zr)
+			if err != nil {
+				t.Fatalf("failed to decompress as gzip: %v", err)
+			}
+			if !bytes.Equal(whole, input) {
+				t.Fatalf("gzip content differs: %d bytes, want %d", len(whole), len(input))
+			}
+
+			// Each chunk decompresses on its own from the offset the
+			// header gives
+			offset := h.dataStart
+			for i, size := range h.sizes {
+				start := i * dictzipChunkSize
+				end := min(start+dictzipChunkSize, len(input))
+				want := input[start:end]
+
+				fr := flate.NewReader(bytes.NewReader(data[offset : offset+int(size)]))
+				got := make([]byte, len(want))
+				if _, err := io.ReadFull(fr, got); err != nil {
+					t.Fatalf("failed to decompress chunk %d: %v", i, err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Errorf("chunk %d content differs", i)
+				}
+				offset += int(size)
+			}
+
+			if trailer := len(data) - offset; trailer != 8 {
+				t.Fatalf("%d bytes after the chunks, want an 8 byte trailer", trailer)
+			}
+			if got := binary.LittleEndian.Uint32(data[offset+4:]); got != uint32(len(input)) {
+				t.Errorf("ISIZE = %d, want %d", got, len(input))
+			}
+		})
+	}
+}
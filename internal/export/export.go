@@ -0,0 +1,213 @@
+// Package export writes stored dictionaries in formats other programs read,
+// starting with the Lexin XML they were imported from
+package export
+
+import (
+	"errors"
+	"strings"
+
+	"lexin-sqlite/internal/parser"
+	"lexin-sqlite/pkg/lexin"
+)
+
+// errNoDictionary is returned by formats that hold a single dictionary when
+// none is given
+var errNoDictionary = errors.New("a dictionary is required for this format")
+
+// languageCodes maps the language codes and names used by Lexin
+// dictionaries to ISO 639-1 codes
+var languageCodes = map[string]string{
+	"swe": "sv", "swedish": "sv",
+	"eng": "en", "english": "en",
+	"sqi": "sq", "alb": "sq", "albanian": "sq",
+	"amh": "am", "amharic": "am",
+	"ara": "ar", "arabic": "ar",
+	"aze": "az", "azerbaijani": "az",
+	"bos": "bs", "bosnian": "bs",
+	"hrv": "hr", "croatian": "hr",
+	"fin": "fi", "finnish": "fi",
+	"ell": "el", "gre": "el", "greek": "el",
+	"kur": "ku", "kmr": "ku", "kurdish": "ku",
+	"pus": "ps", "pashto": "ps",
+	"fas": "fa", "per": "fa", "persian": "fa",
+	"rus": "ru", "russian": "ru",
+	"srp": "sr", "serbian": "sr",
+	"som": "so", "somali": "so",
+	"spa": "es", "spanish": "es",
+	"tir": "ti", "tigrinya": "ti",
+	"tur": "tr", "turkish": "tr",
+	"ukr": "uk", "ukrainian": "uk",
+}
+
+// languageTag returns the ISO 639-1 code of a Lexin language code, or the
+// code itself when it is not known. E-book readers and xml:lang expect
+// these tags.
+func languageTag(code string) string {
+	if iso, ok := languageCodes[strings.ToLower(code)]; ok {
+		return iso
+	}
+	return code
+}
+
+// toWord converts a hydrated entry back to the parser model it was stored
+// from
+func toWord(entry lexin.Entry) parser.Word {
+	word := parser.Word{
+		Value:      entry.Value,
+		Variant:    entry.Variant,
+		Type:       entry.Type,
+		ID:         entry.OriginalID,
+		VariantID:  entry.VariantID,
+		MatchingID: entry.MatchingID,
+	}
+
+	for _, base := range entry.BaseLangs {
+		word.BaseLangs = append(word.BaseLangs, toBaseLang(base))
+	}
+	for _, target := range entry.TargetLangs {
+		word.TargetLang = append(word.TargetLang, toTargetLang(target))
+	}
+
+	return word
+}
+
+// toBaseLang converts a hydrated base language to the parser model
+func toBaseLang(base lexin.BaseLang) parser.BaseLang {
+	out := parser.BaseLang{
+		Meaning:  parser.Meaning{Content: base.Meaning.Content, MatchingID: base.Meaning.MatchingID},
+		Graminfo: base.Graminfo,
+	}
+	if base.Phonetic != nil {
+		out.Phonetic = parser.Phonetic{Content: base.Phonetic.Content, File: base.Phonetic.File}
+	}
+
+	for _, ref := range base.References {
+		out.References = append(out.References, parser.Reference{Type: ref.Type, Value: ref.Value, MatchingID: ref.MatchingID})
+	}
+	for _, comment := range base.Comments {
+		out.Comments = append(out.Comments, parser.Comment{Content: comment.Content, MatchingID: comment.MatchingID})
+	}
+	for _, expl := range base.Explanations {
+		out.Explanations = append(out.Explanations, parser.Explanation{Content: expl.Content, MatchingID: expl.MatchingID})
+	}
+	for _, alt := range base.Alternates {
+		out.Alternates = append(out.Alternates, parser.Alternate{Content: alt.Content})
+	}
+	out.Antonyms = toAntonyms(base.Antonyms)
+	for _, usage := range base.Usages {
+		out.Usages = append(out.Usages, parser.Usage{Content: usage.Content, MatchingID: usage.MatchingID})
+	}
+	for _, ill := range base.Illustrations {
+		out.Illustrations = append(out.Illustrations, parser.Illustration{Type: ill.Type, Value: ill.Value, Norlexin: ill.Norlexin})
+	}
+	for _, infl := range base.Inflections {
+		inflection := parser.Inflection{Content: infl.Content}
+		for _, variant := range infl.Variants {
+			inflection.Variants = append(inflection.Variants, parser.Variant{Content: variant.Content, Description: variant.Description})
+		}
+		out.Inflections = append(out.Inflections, inflection)
+	}
+	out.Examples = toExamples(base.Examples)
+	out.Idioms = toIdioms(base.Idioms)
+	out.Compounds = toCompounds(base.Compounds)
+	out.Derivations = toDerivations(base.Derivations)
+	for _, index := range base.Indexes {
+		out.Indexes = append(out.Indexes, parser.Index{Value: index.Value, Type: index.Type})
+	}
+
+	return out
+}
+
+// toTargetLang converts a hydrated target language to the parser model
+func toTargetLang(target lexin.TargetLang) parser.TargetLang {
+	return parser.TargetLang{
+		Comment:      target.Comment,
+		Translations: target.Translations,
+		Synonyms:     target.Synonyms,
+		CommentElems: target.CommentElems,
+		Explanations: target.Explanations,
+		Antonyms:     toAntonyms(target.Antonyms),
+		Examples:     toExamples(target.Examples),
+		Idioms:       toIdioms(target.Idioms),
+		Compounds:    toCompounds(target.Compounds),
+		Derivations:  toDerivations(target.Derivations),
+	}
+}
+
+func toAntonyms(antonyms []lexin.Antonym) []parser.Antonym {
+	var out []parser.Antonym
+	for _, ant := range antonyms {
+		out = append(out, parser.Antonym{Value: ant.Value})
+	}
+	return out
+}
+
+func toExamples(examples []lexin.Example) []parser.Example {
+	var out []parser.Example
+	for _, example := range examples {
+		out = append(out, parser.Example{Content: example.Content, ID: example.ID, MatchingID: example.MatchingID})
+	}
+	return out
+}
+
+func toIdioms(idioms []lexin.Idiom) []parser.Idiom {
+	var out []parser.Idiom
+	for _, idiom := range idioms {
+		out = append(out, parser.Idiom{Content: idiom.Content, ID: idiom.ID, MatchingID: idiom.MatchingID})
+	}
+	return out
+}
+
+func toCompounds(compounds []lexin.Compound) []parser.Compound {
+	var out []parser.Compound
+	for _, compound := range compounds {
+		out = append(out, parser.Compound{
+			Content:     compound.Content,
+			ID:          compound.ID,
+			Description: compound.Description,
+			MatchingID:  compound.MatchingID,
+			Inflection:  compound.Inflection,
+		})
+	}
+	return out
+}
+
+func toDerivations(derivations []lexin.Derivation) []parser.Derivation {
+	var out []parser.Derivation
+	for _, derivation := range derivations {
+		out = append(out, parser.Derivation{
+			Content:     derivation.Content,
+			ID:          derivation.ID,
+			Description: derivation.Description,
+			Inflection:  derivation.Inflection,
+		})
+	}
+	return out
+}
+
+// inflectedForms returns the inflected forms of an entry and their
+// variants, lower case and without the headword itself
+func inflectedForms(entry lexin.Entry) []string {
+	var forms []string
+	for _, form := range inflections(entry) {
+		forms = append(forms, form.Value)
+	}
+	return forms
+}
+
+// inflections returns the inflection and variant forms of an entry with
+// their source and description, leaving out the headword itself
+func inflections(entry lexin.Entry) []parser.Form {
+	headword := parser.NormalizeForm(entry.Value)
+
+	var forms []parser.Form
+	for _, form := range parser.WordForms(toWord(entry)) {
+		if form.Source != parser.FormInflection && form.Source != parser.FormVariant {
+			continue
+		}
+		if form.Value != headword {
+			forms = append(forms, form)
+		}
+	}
+	return forms
+}
@@ -0,0 +1,71 @@
+package export
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"lexin-sqlite/internal/database"
+	"lexin-sqlite/internal/parser"
+	"lexin-sqlite/internal/repository"
+	"lexin-sqlite/pkg/lexin"
+)
+
+// fixture is a Lexin document using every element and attribute of the
+// parser model
+const fixture = "testdata/full.xml"
+
+// parseFixture parses the fixture
+func parseFixture(t *testing.T) *parser.Dictionary {
+	t.Helper()
+	dict, err := parser.ParseXMLFile(fixture)
+	if err != nil {
+		t.Fatalf("failed to parse %s: %v", fixture, err)
+	}
+	return dict
+}
+
+// importFixture imports the fixture into a database in a temporary
+// directory, the way lexin import does, and opens it for export
+func importFixture(t *testing.T) (*lexin.DB, *lexin.Dictionary) {
+	t.Helper()
+	return importDictionary(t, parseFixture(t))
+}
+
+// importDictionary imports a swe-eng dictionary into a database in a
+// temporary directory and opens it for export
+func importDictionary(t *testing.T, source *parser.Dictionary) (*lexin.DB, *lexin.Dictionary) {
+	t.Helper()
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "lexin.db")
+
+	db, err := database.New(path)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	repo := repository.New(db)
+	if _, err := repo.UpsertDictionary(ctx, source); err != nil {
+		t.Fatalf("failed to import: %v", err)
+	}
+	var dictID int64
+	if err := db.GetDB().QueryRow(`SELECT id FROM dictionaries`).Scan(&dictID); err != nil {
+		t.Fatalf("failed to find dictionary: %v", err)
+	}
+	if _, err := repo.LinkDictionary(ctx, dictID); err != nil {
+		t.Fatalf("failed to link: %v", err)
+	}
+
+	ldb, err := lexin.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { ldb.Close() })
+
+	dict, err := ldb.Dictionary(ctx, "swe", "eng")
+	if err != nil || dict == nil {
+		t.Fatalf("failed to find dictionary: %v", err)
+	}
+	return ldb, dict
+}
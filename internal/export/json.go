@@ -0,0 +1,94 @@
+package export
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+
+	"lexin-sqlite/pkg/lexin"
+)
+
+// jsonEntry is one exported headword. The nested base_langs and
+// target_langs sections mirror the BaseLang and TargetLang elements of the
+// Lexin XML.
+type jsonEntry struct {
+	// Dictionary is the name of the dictionary, such as "swe-eng"
+	Dictionary string `json:"dictionary"`
+	lexin.Entry
+}
+
+// JSON writes the entries of dict, or of every dictionary when dict is nil,
+// as a single JSON array. Entries are encoded one at a time, so the whole
+// array is never held in memory.
+func JSON(ctx context.Context, w io.Writer, db *lexin.DB, dict *lexin.Dictionary, filter lexin.EntryFilter) error {
+	names, err := dictionaryNames(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	if _, err := bw.WriteString("["); err != nil {
+		return err
+	}
+
+	first := true
+	err = db.EachEntry(ctx, dict, filter, func(entry lexin.Entry) error {
+		data, err := json.Marshal(jsonEntry{Dictionary: names[entry.DictionaryID], Entry: entry})
+		if err != nil {
+			return err
+		}
+		separator := ",\n"
+		if first {
+			separator = "\n"
+			first = false
+		}
+		if _, err := bw.WriteString(separator); err != nil {
+			return err
+		}
+		_, err = bw.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := bw.WriteString("\n]\n"); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// JSONLines writes the entries of dict, or of every dictionary when dict is
+// nil, as JSON Lines: one JSON object per line
+func JSONLines(ctx context.Context, w io.Writer, db *lexin.DB, dict *lexin.Dictionary, filter lexin.EntryFilter) error {
+	names, err := dictionaryNames(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	encoder := json.NewEncoder(bw)
+	err = db.EachEntry(ctx, dict, filter, func(entry lexin.Entry) error {
+		return encoder.Encode(jsonEntry{Dictionary: names[entry.DictionaryID], Entry: entry})
+	})
+	if err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+// dictionaryNames maps dictionary ids to their names
+func dictionaryNames(ctx context.Context, db *lexin.DB) (map[int64]string, error) {
+	dictionaries, err := db.Dictionaries(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[int64]string, len(dictionaries))
+	for _, dict := range dictionaries {
+		names[dict.ID] = dict.Name()
+	}
+	return names, nil
+}
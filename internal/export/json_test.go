@@ -0,0 +1,97 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"lexin-sqlite/pkg/lexin"
+)
+
+// decodedEntry is an exported entry read back
+type decodedEntry struct {
+	Dictionary string `json:"dictionary"`
+	lexin.Entry
+}
+
+func TestJSON(t *testing.T) {
+	ctx := context.Background()
+	db, dict := importFixture(t)
+
+	var out bytes.Buffer
+	if err := JSON(ctx, &out, db, dict, lexin.EntryFilter{}); err != nil {
+		t.Fatalf("JSON failed: %v", err)
+	}
+	var entries []decodedEntry
+	if err := json.Unmarshal(out.Bytes(), &entries); err != nil {
+		t.Fatalf("export is not a JSON array: %v\n%s", err, out.String())
+	}
+
+	var want []lexin.Entry
+	err := db.EachEntry(ctx, dict, lexin.EntryFilter{}, func(entry lexin.Entry) error {
+		want = append(want, entry)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("EachEntry failed: %v", err)
+	}
+	if len(entries) != len(want) || len(want) != 3 {
+		t.Fatalf("exported %d entries, want %d", len(entries), len(want))
+	}
+	for i, entry := range entries {
+		if entry.Dictionary != "swe-eng" {
+			t.Errorf("entry %d dictionary = %q, want swe-eng", i, entry.Dictionary)
+		}
+		if !reflect.DeepEqual(entry.Entry, want[i]) {
+			t.Errorf("entry %d differs:\ngot  %+v\nwant %+v", i, entry.Entry, want[i])
+		}
+	}
+
+	// A filter that keeps nothing still writes an array
+	out.Reset()
+	if err := JSON(ctx, &out, db, dict, lexin.EntryFilter{Prefix: "xyz"}); err != nil {
+		t.Fatalf("JSON failed: %v", err)
+	}
+	entries = nil
+	if err := json.Unmarshal(out.Bytes(), &entries); err != nil || entries == nil || len(entries) != 0 {
+		t.Errorf("empty export = %q, want an empty array", out.String())
+	}
+}
+
+func TestJSONLines(t *testing.T) {
+	ctx := context.Background()
+	db, dict := importFixture(t)
+
+	var array, lines bytes.Buffer
+	if err := JSON(ctx, &array, db, dict, lexin.EntryFilter{}); err != nil {
+		t.Fatalf("JSON failed: %v", err)
+	}
+	if err := JSONLines(ctx, &lines, db, dict, lexin.EntryFilter{Prefix: "bo"}); err != nil {
+		t.Fatalf("JSONLines failed: %v", err)
+	}
+	var all []decodedEntry
+	if err := json.Unmarshal(array.Bytes(), &all); err != nil {
+		t.Fatalf("failed to decode the array: %v", err)
+	}
+
+	var got []decodedEntry
+	scanner := bufio.NewScanner(&lines)
+	for scanner.Scan() {
+		var entry decodedEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("line %d is not a JSON object: %v\n%s", len(got)+1, err, scanner.Text())
+		}
+		got = append(got, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("failed to read lines: %v", err)
+	}
+
+	// The prefix keeps the two bostad variants, the same as in the array
+	if !reflect.DeepEqual(got, all[1:]) {
+		t.Errorf("lines = %+v, want %+v", got, all[1:])
+	}
+}
@@ -0,0 +1,196 @@
+package export
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"lexin-sqlite/internal/render"
+	"lexin-sqlite/pkg/lexin"
+)
+
+// KindleOptions configures the Kindle dictionary export. The title and
+// identifier are filled in from each dictionary row.
+type KindleOptions struct {
+	// Title is the book title, "Lexin {base}-{target}" when empty. {base},
+	// {target} and {version} are replaced as in deck names.
+	Title string
+	// Author is the creator in the book metadata, "Lexin" when empty
+	Author string
+	// EntriesPerFile is the number of headwords in each XHTML file, 1000
+	// when zero. Kindle tools handle many small files better than one
+	// large one.
+	EntriesPerFile int
+}
+
+// kindleNamespaces are the namespaces Kindle dictionary markup is written in
+const kindleNamespaces = `xmlns:mbp="https://kindlegen.s3.amazonaws.com/AmazonKindlePublishingGuidelines.pdf" ` +
+	`xmlns:idx="https://kindlegen.s3.amazonaws.com/AmazonKindlePublishingGuidelines.pdf"`
+
+// Kindle writes dict as the source of a Kindle dictionary in dir: a
+// content.opf manifest and XHTML files with an idx:entry section per
+// headword, its inflected forms listed as idx:infl so that looking up
+// "huset" finds "hus". Kindle Previewer or kindlegen turn the directory
+// into a book. When dict is nil, every dictionary is written to a
+// subdirectory of dir named after it.
+func Kindle(ctx context.Context, dir string, db *lexin.DB, dict *lexin.Dictionary, filter lexin.EntryFilter, opts KindleOptions) error {
+	if opts.Title == "" {
+		opts.Title = "Lexin {base}-{target}"
+	}
+	if opts.Author == "" {
+		opts.Author = "Lexin"
+	}
+	if opts.EntriesPerFile <= 0 {
+		opts.EntriesPerFile = 1000
+	}
+
+	if dict != nil {
+		return writeKindleBook(ctx, dir, db, *dict, filter, opts)
+	}
+
+	dictionaries, err := db.Dictionaries(ctx)
+	if err != nil {
+		return err
+	}
+	for _, d := range dictionaries {
+		if err := writeKindleBook(ctx, filepath.Join(dir, d.Name()), db, d, filter, opts); err != nil {
+			return fmt.Errorf("failed to export %s: %w", d.Name(), err)
+		}
+	}
+	return nil
+}
+
+// writeKindleBook writes the manifest and content files of one dictionary
+func writeKindleBook(ctx context.Context, dir string, db *lexin.DB, dict lexin.Dictionary, filter lexin.EntryFilter, opts KindleOptions) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	var files []string
+	var file *os.File
+	var bw *bufio.Writer
+	count := 0
+
+	closeFile := func() error {
+		if file == nil {
+			return nil
+		}
+		bw.WriteString("</mbp:frameset>\n</body>\n</html>\n")
+		err := bw.Flush()
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		file = nil
+		return err
+	}
+
+	err := db.EachEntry(ctx, &dict, filter, func(entry lexin.Entry) error {
+		if count%opts.EntriesPerFile == 0 {
+			if err := closeFile(); err != nil {
+				return err
+			}
+			name := fmt.Sprintf("entries-%04d.xhtml", len(files)+1)
+			var err error
+			if file, err = os.Create(filepath.Join(dir, name)); err != nil {
+				return err
+			}
+			files = append(files, name)
+			bw = bufio.NewWriter(file)
+			writeKindleHeader(bw, dict, opts)
+		}
+		count++
+		return writeKindleEntry(bw, entry, count)
+	})
+	if err != nil {
+		if file != nil {
+			file.Close()
+		}
+		return err
+	}
+	if err := closeFile(); err != nil {
+		return err
+	}
+
+	return writeFile(filepath.Join(dir, "content.opf"), func(w io.Writer) error {
+		return writeKindleOPF(w, dict, opts, files)
+	})
+}
+
+// writeKindleHeader starts an XHTML content file
+func writeKindleHeader(w io.Writer, dict lexin.Dictionary, opts KindleOptions) {
+	fmt.Fprintf(w, "<?xml version=\"1.0\" encoding=\"utf-8\"?>\n")
+	fmt.Fprintf(w, "<html xmlns=\"http://www.w3.org/1999/xhtml\" %s>\n", kindleNamespaces)
+	fmt.Fprintf(w, "<head>\n<meta http-equiv=\"Content-Type\" content=\"text/html; charset=utf-8\"/>\n")
+	fmt.Fprintf(w, "<title>%s</title>\n</head>\n", html.EscapeString(ankiDeckName(opts.Title, dict)))
+	fmt.Fprintf(w, "<body>\n<mbp:frameset>\n")
+}
+
+// writeKindleEntry writes the idx:entry section of one headword
+func writeKindleEntry(w *bufio.Writer, entry lexin.Entry, number int) error {
+	esc := html.EscapeString
+
+	fmt.Fprintf(w, "<idx:entry name=\"default\" scriptable=\"yes\" spell=\"yes\" id=\"e%d\">\n", number)
+	fmt.Fprintf(w, "<idx:orth value=\"%s\"><b>%s</b>", esc(entry.Value), esc(entry.Value))
+	if forms := inflectedForms(entry); len(forms) > 0 {
+		w.WriteString("\n<idx:infl>\n")
+		for _, form := range forms {
+			fmt.Fprintf(w, "<idx:iform value=\"%s\"/>\n", esc(form))
+		}
+		w.WriteString("</idx:infl>\n")
+	}
+	w.WriteString("</idx:orth>\n")
+	if entry.Variant != "" {
+		fmt.Fprintf(w, "<i>%s</i>\n", esc(entry.Variant))
+	}
+	w.WriteString(render.HTML(entry))
+	_, err := w.WriteString("\n</idx:entry>\n<hr/>\n")
+	return err
+}
+
+// writeKindleOPF writes the package manifest naming the content files and
+// the languages of the dictionary
+func writeKindleOPF(w io.Writer, dict lexin.Dictionary, opts KindleOptions, files []string) error {
+	esc := html.EscapeString
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "<?xml version=\"1.0\" encoding=\"utf-8\"?>\n")
+	fmt.Fprintf(bw, "<package version=\"2.0\" xmlns=\"http://www.idpf.org/2007/opf\" unique-identifier=\"uid\">\n")
+	fmt.Fprintf(bw, "  <metadata xmlns:dc=\"http://purl.org/dc/elements/1.1/\" xmlns:opf=\"http://www.idpf.org/2007/opf\">\n")
+	fmt.Fprintf(bw, "    <dc:title>%s</dc:title>\n", esc(ankiDeckName(opts.Title, dict)))
+	fmt.Fprintf(bw, "    <dc:creator opf:role=\"aut\">%s</dc:creator>\n", esc(opts.Author))
+	fmt.Fprintf(bw, "    <dc:language>%s</dc:language>\n", esc(languageTag(dict.BaseLang)))
+	fmt.Fprintf(bw, "    <dc:identifier id=\"uid\">lexin-%s-%s</dc:identifier>\n", esc(dict.Name()), esc(dict.Version))
+	fmt.Fprintf(bw, "    <dc:date>%s</dc:date>\n", time.Now().Format("2006-01-02"))
+	fmt.Fprintf(bw, "    <x-metadata>\n")
+	fmt.Fprintf(bw, "      <DictionaryInLanguage>%s</DictionaryInLanguage>\n", esc(languageTag(dict.BaseLang)))
+	fmt.Fprintf(bw, "      <DictionaryOutLanguage>%s</DictionaryOutLanguage>\n", esc(languageTag(dict.TargetLang)))
+	fmt.Fprintf(bw, "      <DefaultLookupIndex>default</DefaultLookupIndex>\n")
+	fmt.Fprintf(bw, "    </x-metadata>\n")
+	fmt.Fprintf(bw, "  </metadata>\n")
+
+	fmt.Fprintf(bw, "  <manifest>\n")
+	for _, file := range files {
+		fmt.Fprintf(bw, "    <item id=\"%s\" href=\"%s\" media-type=\"application/xhtml+xml\"/>\n", kindleItemID(file), esc(file))
+	}
+	fmt.Fprintf(bw, "  </manifest>\n")
+
+	fmt.Fprintf(bw, "  <spine>\n")
+	for _, file := range files {
+		fmt.Fprintf(bw, "    <itemref idref=\"%s\"/>\n", kindleItemID(file))
+	}
+	fmt.Fprintf(bw, "  </spine>\n")
+	fmt.Fprintf(bw, "</package>\n")
+
+	return bw.Flush()
+}
+
+// kindleItemID is the manifest id of a content file
+func kindleItemID(file string) string {
+	return strings.TrimSuffix(file, filepath.Ext(file))
+}
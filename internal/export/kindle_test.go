@@ -0,0 +1,123 @@
+package export
+
+import (
+	"context"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	"lexin-sqlite/pkg/lexin"
+)
+
+// descendants returns the elements below n with the local name name, in
+// document order
+func descendants(n teiNode, name string) []teiNode {
+	var nodes []teiNode
+	for _, child := range n.Children {
+		if child.XMLName.Local == name {
+			nodes = append(nodes, child)
+		}
+		nodes = append(nodes, descendants(child, name)...)
+	}
+	return nodes
+}
+
+// decodeXMLFile decodes the XML file at path
+func decodeXMLFile(t *testing.T, path string) teiNode {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+	var root teiNode
+	if err := xml.Unmarshal(data, &root); err != nil {
+		t.Fatalf("failed to decode %s: %v\n%s", path, err, data)
+	}
+	return root
+}
+
+func TestKindle(t *testing.T) {
+	db, dict := importFixture(t)
+	dir := t.TempDir()
+
+	if err := Kindle(context.Background(), dir, db, dict, lexin.EntryFilter{}, KindleOptions{EntriesPerFile: 2}); err != nil {
+		t.Fatalf("Kindle failed: %v", err)
+	}
+
+	// The manifest lists every content file, and the spine reads them in
+	// order
+	opf := decodeXMLFile(t, filepath.Join(dir, "content.opf"))
+	var hrefs, ids, idrefs []string
+	for _, item := range descendants(opf, "item") {
+		hrefs = append(hrefs, item.attr("href"))
+		ids = append(ids, item.attr("id"))
+		if item.attr("media-type") != "application/xhtml+xml" {
+			t.Errorf("item %s has media type %q", item.attr("href"), item.attr("media-type"))
+		}
+	}
+	for _, itemref := range descendants(opf, "itemref") {
+		idrefs = append(idrefs, itemref.attr("idref"))
+	}
+	want := []string{"entries-0001.xhtml", "entries-0002.xhtml"}
+	if !reflect.DeepEqual(hrefs, want) {
+		t.Errorf("manifest = %q, want %q", hrefs, want)
+	}
+	if !reflect.DeepEqual(idrefs, ids) {
+		t.Errorf("spine = %q, want the manifest ids %q", idrefs, ids)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.xhtml"))
+	if err != nil || len(files) != len(want) {
+		t.Errorf("directory holds %q, want %d content files", files, len(want))
+	}
+	metadata := map[string]string{}
+	for _, name := range []string{"title", "language", "DictionaryInLanguage", "DictionaryOutLanguage"} {
+		if nodes := descendants(opf, name); len(nodes) == 1 {
+			metadata[name] = nodes[0].Text
+		}
+	}
+	wantMetadata := map[string]string{
+		"title":                 "Lexin swe-eng",
+		"language":              "sv",
+		"DictionaryInLanguage":  "sv",
+		"DictionaryOutLanguage": "en",
+	}
+	if !reflect.DeepEqual(metadata, wantMetadata) {
+		t.Errorf("metadata = %v, want %v", metadata, wantMetadata)
+	}
+
+	// Each headword is an entry whose inflected forms are listed in
+	// idx:infl, without the headword itself
+	forms := make(map[string][]string)
+	var headwords []string
+	for _, file := range want {
+		root := decodeXMLFile(t, filepath.Join(dir, file))
+		for _, entry := range descendants(root, "entry") {
+			orths := descendants(entry, "orth")
+			if len(orths) != 1 {
+				t.Fatalf("entry %s has %d idx:orth elements, want 1", entry.attr("id"), len(orths))
+			}
+			headword := orths[0].attr("value")
+			headwords = append(headwords, headword)
+			for _, iform := range descendants(orths[0], "iform") {
+				forms[headword] = append(forms[headword], iform.attr("value"))
+			}
+		}
+	}
+	if !reflect.DeepEqual(headwords, []string{"hus", "bostad", "bostad"}) {
+		t.Errorf("headwords = %q", headwords)
+	}
+	for _, form := range []string{"huset", "husen", "husena"} {
+		if !slices.Contains(forms["hus"], form) {
+			t.Errorf("hus forms %q lack %s", forms["hus"], form)
+		}
+	}
+	if slices.Contains(forms["hus"], "hus") {
+		t.Errorf("hus forms %q hold the headword", forms["hus"])
+	}
+	if len(forms["bostad"]) != 0 {
+		t.Errorf("bostad forms = %q, want none", forms["bostad"])
+	}
+}
@@ -0,0 +1,254 @@
+package export
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"lexin-sqlite/internal/render"
+	"lexin-sqlite/pkg/lexin"
+)
+
+// StarDictOptions configures the StarDict export
+type StarDictOptions struct {
+	// BookName is the title readers show, "Lexin {base}-{target}" when empty.
+	// {base}, {target} and {version} are replaced as in deck names.
+	BookName string
+	// Synonyms writes a .syn file that leads the inflected forms of each
+	// headword to its entry
+	Synonyms bool
+}
+
+// starDictWord is a headword in the .idx file and the location of its
+// article in the .dict file
+type starDictWord struct {
+	word   string
+	offset uint32
+	size   uint32
+	forms  []string
+}
+
+// starDictSynonym is a form in the .syn file and the index of the headword
+// it leads to
+type starDictSynonym struct {
+	word  string
+	index uint32
+}
+
+// StarDict writes dict as a StarDict dictionary: basePath.ifo, basePath.idx,
+// basePath.dict.dz and, with opts.Synonyms, basePath.syn. Articles are HTML
+// with the meanings, grammar, translations, examples and idioms of an
+// entry.
+func StarDict(ctx context.Context, basePath string, db *lexin.DB, dict *lexin.Dictionary, filter lexin.EntryFilter, opts StarDictOptions) error {
+	if dict == nil {
+		return errNoDictionary
+	}
+	if opts.BookName == "" {
+		opts.BookName = "Lexin {base}-{target}"
+	}
+	if dir := filepath.Dir(basePath); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+
+	// Articles are written uncompressed first, dictzip needs the whole file
+	raw, err := os.CreateTemp("", "lexin-stardict-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(raw.Name())
+	defer raw.Close()
+
+	var words []starDictWord
+	var offset int64
+	bw := bufio.NewWriter(raw)
+	err = db.EachEntry(ctx, dict, filter, func(entry lexin.Entry) error {
+		article := render.HTML(entry)
+		if article == "" {
+			return nil
+		}
+		if offset+int64(len(article)) > 1<<32-1 {
+			return fmt.Errorf("dictionary too large for StarDict")
+		}
+		if _, err := bw.WriteString(article); err != nil {
+			return err
+		}
+
+		word := starDictWord{
+			word:   entry.Value,
+			offset: uint32(offset),
+			size:   uint32(len(article)),
+		}
+		if opts.Synonyms {
+			word.forms = inflectedForms(entry)
+		}
+		words = append(words, word)
+		offset += int64(len(article))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+
+	sort.SliceStable(words, func(i, j int) bool {
+		return starDictLess(words[i].word, words[j].word)
+	})
+
+	idxSize, err := writeStarDictIdx(basePath+".idx", words)
+	if err != nil {
+		return err
+	}
+
+	synCount := 0
+	if opts.Synonyms {
+		if synCount, err = writeStarDictSyn(basePath+".syn", words); err != nil {
+			return err
+		}
+	}
+
+	if _, err := raw.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := writeFile(basePath+".dict.dz", func(w io.Writer) error {
+		return writeDictzip(w, bufio.NewReader(raw))
+	}); err != nil {
+		return err
+	}
+
+	return writeFile(basePath+".ifo", func(w io.Writer) error {
+		return writeStarDictIfo(w, *dict, opts, len(words), synCount, idxSize)
+	})
+}
+
+// writeStarDictIdx writes the index of sorted headwords and returns its size
+func writeStarDictIdx(path string, words []starDictWord) (int64, error) {
+	var size int64
+	err := writeFile(path, func(w io.Writer) error {
+		bw := bufio.NewWriter(w)
+		var location [8]byte
+		for _, word := range words {
+			binary.BigEndian.PutUint32(location[0:], word.offset)
+			binary.BigEndian.PutUint32(location[4:], word.size)
+			bw.WriteString(word.word)
+			bw.WriteByte(0)
+			bw.Write(location[:])
+			size += int64(len(word.word)) + 1 + int64(len(location))
+		}
+		return bw.Flush()
+	})
+	return size, err
+}
+
+// writeStarDictSyn writes the sorted inflected forms of the headwords with
+// the index of their headword and returns how many were written
+func writeStarDictSyn(path string, words []starDictWord) (int, error) {
+	var synonyms []starDictSynonym
+	for i, word := range words {
+		for _, form := range word.forms {
+			synonyms = append(synonyms, starDictSynonym{word: form, index: uint32(i)})
+		}
+	}
+	sort.SliceStable(synonyms, func(i, j int) bool {
+		return starDictLess(synonyms[i].word, synonyms[j].word)
+	})
+
+	err := writeFile(path, func(w io.Writer) error {
+		bw := bufio.NewWriter(w)
+		var index [4]byte
+		for _, synonym := range synonyms {
+			binary.BigEndian.PutUint32(index[:], synonym.index)
+			bw.WriteString(synonym.word)
+			bw.WriteByte(0)
+			bw.Write(index[:])
+		}
+		return bw.Flush()
+	})
+	return len(synonyms), err
+}
+
+// writeStarDictIfo writes the description file readers open first
+func writeStarDictIfo(w io.Writer, dict lexin.Dictionary, opts StarDictOptions, wordCount, synCount int, idxSize int64) error {
+	// Values end at the line, so they cannot hold line breaks
+	clean := strings.NewReplacer("\r", " ", "\n", " ").Replace
+
+	lines := []string{
+		"StarDict's dict ifo file",
+		"version=2.4.2",
+		"bookname=" + clean(ankiDeckName(opts.BookName, dict)),
+		fmt.Sprintf("wordcount=%d", wordCount),
+	}
+	if opts.Synonyms {
+		lines = append(lines, fmt.Sprintf("synwordcount=%d", synCount))
+	}
+	lines = append(lines,
+		fmt.Sprintf("idxfilesize=%d", idxSize),
+		"sametypesequence=h",
+		"description="+clean(fmt.Sprintf("Lexin %s dictionary, version %s", dict.Name(), dict.Version)),
+		"date="+time.Now().Format("2006.01.02"),
+	)
+
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+// starDictLess orders words the way StarDict looks them up: ASCII letters
+// compared without case, ties broken by the bytes themselves
+func starDictLess(a, b string) bool {
+	if c := asciiFoldCompare(a, b); c != 0 {
+		return c < 0
+	}
+	return a < b
+}
+
+// asciiFoldCompare compares two strings byte by byte with ASCII letters
+// folded to lower case, like g_ascii_strcasecmp
+func asciiFoldCompare(a, b string) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		ca, cb := asciiLower(a[i]), asciiLower(b[i])
+		if ca != cb {
+			if ca < cb {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case len(a) < len(b):
+		return -1
+	case len(a) > len(b):
+		return 1
+	}
+	return 0
+}
+
+// asciiLower folds an ASCII upper case letter to lower case
+func asciiLower(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
+
+// writeFile creates path and runs write on it
+func writeFile(path string, write func(w io.Writer) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,56 @@
+package export
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestStarDictLess(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"a", "b", true},
+		{"b", "a", false},
+		{"a", "a", false},
+		// ASCII letters compare without case, ties by bytes
+		{"Bil", "bil", true},
+		{"bil", "Bil", false},
+		{"Bil", "båt", true},
+		{"apa", "Bil", true},
+		{"ZOO", "abc", false},
+		// A prefix comes first
+		{"hus", "husbil", true},
+		{"HUS", "husbil", true},
+		{"husbil", "hus", false},
+		// Bytes outside ASCII are not folded: Å (c3 85) sorts before å (c3 a5)
+		{"Åre", "år", true},
+		{"år", "Åre", false},
+		{"zebra", "ål", true},
+		// Punctuation keeps its byte value
+		{"a-b", "ab", true},
+		{"a b", "a-b", true},
+		{"", "a", true},
+		{"a", "", false},
+	}
+
+	for _, tt := range tests {
+		if got := starDictLess(tt.a, tt.b); got != tt.want {
+			t.Errorf("starDictLess(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestStarDictLessSorts(t *testing.T) {
+	words := []string{"ål", "Bil", "hus", "apa", "bil", "Åre", "HUS", "husbil", "Apa", "år"}
+	want := []string{"Apa", "apa", "Bil", "bil", "HUS", "hus", "husbil", "Åre", "ål", "år"}
+
+	sort.Slice(words, func(i, j int) bool {
+		return starDictLess(words[i], words[j])
+	})
+	for i := range want {
+		if words[i] != want[i] {
+			t.Fatalf("sorted = %q, want %q", words, want)
+		}
+	}
+}
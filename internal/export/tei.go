@@ -0,0 +1,332 @@
+package export
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+
+	"lexin-sqlite/internal/render"
+	"lexin-sqlite/pkg/lexin"
+)
+
+// teiNamespace is the namespace of TEI documents
+const teiNamespace = "http://www.tei-c.org/ns/1.0"
+
+// TEI writes dict as a TEI Lex-0 document. Each word becomes an <entry>
+// and each of its base languages a <sense>; graminfos and inflections are
+// written as <gramGrp> and <form>, translations, examples and idioms as
+// <cit>, references, antonyms and synonyms as <xr>, and references to
+// animation and sound files as <ref target>.
+func TEI(ctx context.Context, w io.Writer, db *lexin.DB, dict *lexin.Dictionary, filter lexin.EntryFilter) error {
+	if dict == nil {
+		return errNoDictionary
+	}
+
+	xw := &xmlWriter{enc: xml.NewEncoder(w)}
+	baseLang := languageTag(dict.BaseLang)
+
+	xw.token(xml.ProcInst{Target: "xml", Inst: []byte(`version="1.0" encoding="UTF-8"`)})
+	xw.newline(0)
+	xw.start("TEI", attr("xmlns", teiNamespace), attr("xml:lang", baseLang))
+	xw.teiHeader(*dict)
+	xw.newline(1)
+	xw.start("text")
+	xw.newline(2)
+	xw.start("body")
+
+	err := db.EachEntry(ctx, dict, filter, func(entry lexin.Entry) error {
+		xw.teiEntry(entry, languageTag(dict.TargetLang))
+		// Flush entry by entry so that the document streams
+		if xw.err == nil {
+			xw.err = xw.enc.Flush()
+		}
+		return xw.err
+	})
+	if err != nil {
+		return err
+	}
+
+	xw.newline(2)
+	xw.end("body")
+	xw.newline(1)
+	xw.end("text")
+	xw.newline(0)
+	xw.end("TEI")
+	xw.newline(0)
+	if xw.err != nil {
+		return xw.err
+	}
+	return xw.enc.Flush()
+}
+
+// teiHeader writes the <teiHeader> describing the dictionary
+func (xw *xmlWriter) teiHeader(dict lexin.Dictionary) {
+	xw.newline(1)
+	xw.start("teiHeader")
+	xw.newline(2)
+	xw.start("fileDesc")
+
+	xw.newline(3)
+	xw.start("titleStmt")
+	xw.element(4, "title", "Lexin "+dict.Name())
+	xw.newline(3)
+	xw.end("titleStmt")
+
+	if dict.Version != "" {
+		xw.newline(3)
+		xw.start("editionStmt")
+		xw.element(4, "edition", dict.Version)
+		xw.newline(3)
+		xw.end("editionStmt")
+	}
+
+	xw.newline(3)
+	xw.start("publicationStmt")
+	xw.element(4, "p", "Exported by lexin-sqlite")
+	xw.newline(3)
+	xw.end("publicationStmt")
+
+	xw.newline(3)
+	xw.start("sourceDesc")
+	xw.element(4, "p", "Lexin dictionary from "+dict.BaseLang+" to "+dict.TargetLang)
+	xw.newline(3)
+	xw.end("sourceDesc")
+
+	xw.newline(2)
+	xw.end("fileDesc")
+	xw.newline(1)
+	xw.end("teiHeader")
+}
+
+// teiEntry writes the <entry> of a word
+func (xw *xmlWriter) teiEntry(entry lexin.Entry, targetLang string) {
+	const depth = 4
+
+	xw.newline(3)
+	xw.start("entry", optional("xml:id", teiID("lexin", entry.OriginalID, entry.VariantID), "n", entry.Variant)...)
+
+	xw.newline(depth)
+	xw.start("form", attr("type", "lemma"))
+	xw.element(depth+1, "orth", entry.Value)
+	for _, base := range entry.BaseLangs {
+		if base.Phonetic != nil && base.Phonetic.Content != "" {
+			xw.element(depth+1, "pron", base.Phonetic.Content)
+			break
+		}
+	}
+	xw.newline(depth)
+	xw.end("form")
+
+	if entry.Type != "" {
+		xw.newline(depth)
+		xw.start("gramGrp")
+		xw.element(depth+1, "pos", entry.Type)
+		xw.newline(depth)
+		xw.end("gramGrp")
+	}
+
+	for i, base := range entry.BaseLangs {
+		xw.teiSense(entry, base, i, targetLang)
+	}
+
+	xw.newline(3)
+	xw.end("entry")
+}
+
+// teiSense writes the <sense> of a base language together with the target
+// language at the same position
+func (xw *xmlWriter) teiSense(entry lexin.Entry, base lexin.BaseLang, index int, targetLang string) {
+	const depth = 5
+
+	xw.newline(4)
+	xw.start("sense", attr("n", strconv.Itoa(index+1)))
+
+	if base.Graminfo != "" {
+		xw.newline(depth)
+		xw.start("gramGrp")
+		xw.element(depth+1, "gram", base.Graminfo, attr("type", "grammar"))
+		xw.newline(depth)
+		xw.end("gramGrp")
+	}
+	for _, infl := range base.Inflections {
+		xw.newline(depth)
+		xw.start("form", attr("type", "inflected"))
+		if infl.Content != "" {
+			xw.element(depth+1, "orth", infl.Content)
+		}
+		for _, variant := range infl.Variants {
+			xw.newline(depth + 1)
+			xw.start("form", attr("type", "variant"))
+			xw.element(depth+2, "orth", variant.Content)
+			if variant.Description != "" {
+				xw.element(depth+2, "usg", variant.Description, attr("type", "hint"))
+			}
+			xw.newline(depth + 1)
+			xw.end("form")
+		}
+		xw.newline(depth)
+		xw.end("form")
+	}
+	for _, alternate := range base.Alternates {
+		xw.newline(depth)
+		xw.start("form", attr("type", "variant"))
+		xw.element(depth+1, "orth", alternate.Content)
+		xw.newline(depth)
+		xw.end("form")
+	}
+
+	for _, usage := range base.Usages {
+		xw.element(depth, "usg", usage.Content, attr("type", "hint"))
+	}
+	if base.Meaning.Content != "" {
+		xw.element(depth, "def", base.Meaning.Content)
+	}
+	for _, explanation := range base.Explanations {
+		xw.element(depth, "note", explanation.Content, attr("type", "explanation"))
+	}
+	for _, comment := range base.Comments {
+		xw.element(depth, "note", comment.Content)
+	}
+
+	target, translated := render.Target(entry, base, index)
+	if translated {
+		for _, translation := range target.Translations {
+			if translation == "" {
+				continue
+			}
+			xw.newline(depth)
+			xw.start("cit", attr("type", "translation"), attr("xml:lang", targetLang))
+			xw.element(depth+1, "quote", translation)
+			if target.Comment != "" {
+				xw.element(depth+1, "usg", target.Comment, attr("type", "hint"))
+			}
+			xw.newline(depth)
+			xw.end("cit")
+		}
+		for _, synonym := range target.Synonyms {
+			if synonym != "" {
+				xw.teiXr(depth, "synonymy", synonym, attr("xml:lang", targetLang))
+			}
+		}
+		for _, explanation := range target.Explanations {
+			xw.element(depth, "note", explanation, attr("type", "explanation"), attr("xml:lang", targetLang))
+		}
+		for _, comment := range target.CommentElems {
+			xw.element(depth, "note", comment, attr("xml:lang", targetLang))
+		}
+	}
+
+	xw.teiExamples(depth, "", render.Examples(entry, base), targetLang)
+	xw.teiExamples(depth, "idiom", render.Idioms(entry, base), targetLang)
+	xw.teiRelated(depth, "compound", render.Compounds(entry, base), targetLang)
+	xw.teiRelated(depth, "derivative", render.Derivations(entry, base, index), targetLang)
+
+	for _, reference := range base.References {
+		switch reference.Type {
+		case "animation", "phonetic":
+			// These name a media file rather than a headword
+			xw.newline(depth)
+			xw.start("ref", attr("type", reference.Type), attr("target", reference.Value))
+			xw.end("ref")
+		default:
+			xw.teiXr(depth, reference.Type, reference.Value)
+		}
+	}
+	for _, antonym := range base.Antonyms {
+		xw.teiXr(depth, "antonymy", antonym.Value)
+	}
+	if translated {
+		for _, antonym := range target.Antonyms {
+			xw.teiXr(depth, "antonymy", antonym.Value, attr("xml:lang", targetLang))
+		}
+	}
+
+	xw.newline(4)
+	xw.end("sense")
+}
+
+// teiExamples writes examples or idioms as <cit type="example"> with their
+// translation nested
+func (xw *xmlWriter) teiExamples(depth int, subtype string, pairs []render.Pair, targetLang string) {
+	for _, pair := range pairs {
+		xw.newline(depth)
+		xw.start("cit", append([]xml.Attr{attr("type", "example")}, optional("subtype", subtype)...)...)
+		xw.element(depth+1, "quote", pair.Swedish)
+		xw.teiTranslation(depth+1, pair.Translation, targetLang)
+		xw.newline(depth)
+		xw.end("cit")
+	}
+}
+
+// teiRelated writes compounds and derivations as related entries
+func (xw *xmlWriter) teiRelated(depth int, relation string, pairs []render.Pair, targetLang string) {
+	for _, pair := range pairs {
+		xw.newline(depth)
+		xw.start("re", attr("type", relation))
+		xw.newline(depth + 1)
+		xw.start("form")
+		xw.element(depth+2, "orth", pair.Swedish)
+		if pair.Inflection != "" {
+			xw.newline(depth + 2)
+			xw.start("form", attr("type", "inflected"))
+			xw.element(depth+3, "orth", pair.Inflection)
+			xw.newline(depth + 2)
+			xw.end("form")
+		}
+		xw.newline(depth + 1)
+		xw.end("form")
+		if pair.Description != "" {
+			xw.element(depth+1, "usg", pair.Description, attr("type", "hint"))
+		}
+		xw.teiTranslation(depth+1, pair.Translation, targetLang)
+		xw.newline(depth)
+		xw.end("re")
+	}
+}
+
+// teiTranslation writes a <cit type="translation"> unless translation is
+// empty
+func (xw *xmlWriter) teiTranslation(depth int, translation, targetLang string) {
+	if translation == "" {
+		return
+	}
+	xw.newline(depth)
+	xw.start("cit", attr("type", "translation"), attr("xml:lang", targetLang))
+	xw.element(depth+1, "quote", translation)
+	xw.newline(depth)
+	xw.end("cit")
+}
+
+// teiXr writes a cross reference to another headword
+func (xw *xmlWriter) teiXr(depth int, relation, value string, attrs ...xml.Attr) {
+	xw.newline(depth)
+	xw.start("xr", append(optional("type", relation), attrs...)...)
+	xw.element(depth+1, "ref", value, attr("type", "entry"))
+	xw.newline(depth)
+	xw.end("xr")
+}
+
+// teiID builds an xml:id from parts, replacing characters an XML name
+// cannot hold. It returns "" when every part after the prefix is empty.
+func teiID(prefix string, parts ...string) string {
+	if strings.Join(parts, "") == "" {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(prefix)
+	for _, part := range parts {
+		b.WriteByte('-')
+		for _, r := range part {
+			switch {
+			case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+				b.WriteRune(r)
+			default:
+				b.WriteByte('_')
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,155 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"reflect"
+	"testing"
+
+	"lexin-sqlite/pkg/lexin"
+)
+
+// teiNode is an element of a decoded TEI document
+type teiNode struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Text     string     `xml:",chardata"`
+	Children []teiNode  `xml:",any"`
+}
+
+// attr returns the value of the attribute with the local name name
+func (n teiNode) attr(name string) string {
+	for _, a := range n.Attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// all returns the children named name whose type attribute is typ, or
+// every child named name when typ is empty
+func (n teiNode) all(name, typ string) []teiNode {
+	var nodes []teiNode
+	for _, child := range n.Children {
+		if child.XMLName.Local == name && (typ == "" || child.attr("type") == typ) {
+			nodes = append(nodes, child)
+		}
+	}
+	return nodes
+}
+
+// texts returns the text of the named child of each node
+func texts(nodes []teiNode, child string) []string {
+	var values []string
+	for _, node := range nodes {
+		for _, c := range node.all(child, "") {
+			values = append(values, c.Text)
+		}
+	}
+	return values
+}
+
+// exportTEI exports the fixture as TEI and decodes the document
+func exportTEI(t *testing.T) teiNode {
+	t.Helper()
+	db, dict := importFixture(t)
+
+	var out bytes.Buffer
+	if err := TEI(context.Background(), &out, db, dict, lexin.EntryFilter{}); err != nil {
+		t.Fatalf("TEI failed: %v", err)
+	}
+
+	var root teiNode
+	if err := xml.Unmarshal(out.Bytes(), &root); err != nil {
+		t.Fatalf("failed to decode the TEI document: %v\n%s", err, out.String())
+	}
+	return root
+}
+
+func TestTEIStructure(t *testing.T) {
+	root := exportTEI(t)
+
+	if root.XMLName.Space != teiNamespace || root.XMLName.Local != "TEI" {
+		t.Fatalf("root element = %v", root.XMLName)
+	}
+	if len(root.all("teiHeader", "")) != 1 {
+		t.Errorf("want one teiHeader")
+	}
+	text := root.all("text", "")
+	if len(text) != 1 || len(text[0].all("body", "")) != 1 {
+		t.Fatalf("want text/body")
+	}
+	entries := text[0].all("body", "")[0].all("entry", "")
+	if len(entries) != 3 {
+		t.Fatalf("exported %d entries, want 3", len(entries))
+	}
+
+	hus := entries[0]
+	if got := hus.attr("id"); got != "lexin-100-1" {
+		t.Errorf("xml:id = %q, want lexin-100-1", got)
+	}
+	if got := texts(hus.all("form", "lemma"), "orth"); !reflect.DeepEqual(got, []string{"hus"}) {
+		t.Errorf("lemma = %q", got)
+	}
+	if got := texts(hus.all("gramGrp", ""), "pos"); !reflect.DeepEqual(got, []string{"subst."}) {
+		t.Errorf("part of speech = %q", got)
+	}
+
+	senses := hus.all("sense", "")
+	if len(senses) != 2 {
+		t.Fatalf("exported %d senses, want 2", len(senses))
+	}
+	sense := senses[0]
+
+	tests := []struct {
+		name string
+		got  []string
+		want []string
+	}{
+		{"definition", texts([]teiNode{sense}, "def"), []string{"byggnad för boende"}},
+		{"translations", texts(sense.all("cit", "translation"), "quote"), []string{"house", "building"}},
+		{"synonyms", texts(sense.all("xr", "synonymy"), "ref"), []string{"home", "dwelling"}},
+		{"references", texts(sense.all("xr", "see"), "ref"), []string{"bostad"}},
+		{"comparisons", texts(sense.all("xr", "compare"), "ref"), []string{"stuga"}},
+		{"antonyms", texts(sense.all("xr", "antonymy"), "ref"), []string{"ute", "outside"}},
+		{"examples", texts(sense.all("cit", "example"), "quote"), []string{"bo i ett stort hus", "huset ligger vid sjön", "hålla hus"}},
+		{"compounds", texts(sense.all("re", "compound"), "usg"), []string{"subst."}},
+		{"derivations", texts(sense.all("re", "derivative"), "usg"), []string{"adj."}},
+		{"second sense translations", texts(senses[1].all("cit", "translation"), "quote"), []string{"household"}},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("%s = %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+
+	// Example translations are nested in their example
+	examples := sense.all("cit", "example")
+	if got := texts(examples[0].all("cit", "translation"), "quote"); !reflect.DeepEqual(got, []string{"live in a big house"}) {
+		t.Errorf("example translation = %q", got)
+	}
+
+	// Descriptions of compounds and derivations are usage notes, not parts
+	// of speech
+	for _, re := range append(sense.all("re", "compound"), sense.all("re", "derivative")...) {
+		if len(re.all("gramGrp", "")) != 0 {
+			t.Errorf("<re type=%q> holds a gramGrp", re.attr("type"))
+		}
+	}
+
+	// Media files are referenced by target, not as headwords
+	media := map[string]string{}
+	for _, ref := range sense.all("ref", "") {
+		media[ref.attr("type")] = ref.attr("target")
+	}
+	if want := map[string]string{"animation": "hus.swf", "phonetic": "hus.mp3"}; !reflect.DeepEqual(media, want) {
+		t.Errorf("media references = %v, want %v", media, want)
+	}
+	for _, typ := range []string{"animation", "phonetic"} {
+		if xrs := sense.all("xr", typ); len(xrs) != 0 {
+			t.Errorf("%s reference written as a cross reference", typ)
+		}
+	}
+}
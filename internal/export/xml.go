@@ -0,0 +1,270 @@
+package export
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+
+	"lexin-sqlite/internal/parser"
+	"lexin-sqlite/pkg/lexin"
+)
+
+// XML writes dict as a Lexin <Dictionary> document. Every element and
+// attribute of the parser model is reproduced, so parsing the output gives
+// the same words as parsing the file the dictionary was imported from.
+// Empty optional attributes and elements are left out, which the parser
+// reads the same way.
+func XML(ctx context.Context, w io.Writer, db *lexin.DB, dict *lexin.Dictionary, filter lexin.EntryFilter) error {
+	if dict == nil {
+		return errNoDictionary
+	}
+
+	xw := &xmlWriter{enc: xml.NewEncoder(w)}
+
+	xw.token(xml.ProcInst{Target: "xml", Inst: []byte(`version="1.0" encoding="UTF-8"`)})
+	xw.newline(0)
+	xw.start("Dictionary",
+		attr("BaseLang", dict.BaseLang),
+		attr("TargetLang", dict.TargetLang),
+		attr("Version", dict.Version),
+	)
+
+	err := db.EachEntry(ctx, dict, filter, func(entry lexin.Entry) error {
+		xw.newline(0)
+		xw.word(toWord(entry))
+		// Flush word by word so that the document streams
+		if xw.err == nil {
+			xw.err = xw.enc.Flush()
+		}
+		return xw.err
+	})
+	if err != nil {
+		return err
+	}
+
+	xw.newline(0)
+	xw.end("Dictionary")
+	xw.newline(0)
+	if xw.err != nil {
+		return xw.err
+	}
+	return xw.enc.Flush()
+}
+
+// xmlWriter writes tokens, keeping the first error. Indentation is written
+// by hand because several Lexin elements have mixed content, where the
+// whitespace added by xml.Encoder.Indent would change the text.
+type xmlWriter struct {
+	enc *xml.Encoder
+	err error
+}
+
+// attr returns an attribute that is always written
+func attr(name, value string) xml.Attr {
+	return xml.Attr{Name: xml.Name{Local: name}, Value: value}
+}
+
+// optional returns the attributes among name/value pairs whose value is not
+// empty
+func optional(pairs ...string) []xml.Attr {
+	var attrs []xml.Attr
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i+1] != "" {
+			attrs = append(attrs, attr(pairs[i], pairs[i+1]))
+		}
+	}
+	return attrs
+}
+
+func (xw *xmlWriter) token(t xml.Token) {
+	if xw.err == nil {
+		xw.err = xw.enc.EncodeToken(t)
+	}
+}
+
+func (xw *xmlWriter) start(name string, attrs ...xml.Attr) {
+	xw.token(xml.StartElement{Name: xml.Name{Local: name}, Attr: attrs})
+}
+
+func (xw *xmlWriter) end(name string) {
+	xw.token(xml.EndElement{Name: xml.Name{Local: name}})
+}
+
+func (xw *xmlWriter) text(s string) {
+	if s != "" {
+		xw.token(xml.CharData(s))
+	}
+}
+
+// newline starts a new line indented for the given depth
+func (xw *xmlWriter) newline(depth int) {
+	indent := make([]byte, 1+2*depth)
+	indent[0] = '\n'
+	for i := 1; i < len(indent); i++ {
+		indent[i] = ' '
+	}
+	xw.token(xml.CharData(indent))
+}
+
+// element writes an element holding only text on its own line
+func (xw *xmlWriter) element(depth int, name, content string, attrs ...xml.Attr) {
+	xw.newline(depth)
+	xw.start(name, attrs...)
+	xw.text(content)
+	xw.end(name)
+}
+
+// word writes a <Word> element
+func (xw *xmlWriter) word(word parser.Word) {
+	attrs := []xml.Attr{attr("Value", word.Value)}
+	attrs = append(attrs, optional("Variant", word.Variant)...)
+	attrs = append(attrs,
+		attr("Type", word.Type),
+		attr("ID", word.ID),
+		attr("VariantID", word.VariantID),
+	)
+	attrs = append(attrs, optional("MatchingID", word.MatchingID)...)
+	xw.start("Word", attrs...)
+
+	for _, base := range word.BaseLangs {
+		xw.baseLang(base)
+	}
+	for _, target := range word.TargetLang {
+		xw.targetLang(target)
+	}
+
+	xw.newline(0)
+	xw.end("Word")
+}
+
+// baseLang writes a <BaseLang> element, children in the order of the parser
+// model
+func (xw *xmlWriter) baseLang(base parser.BaseLang) {
+	const depth = 2
+
+	xw.newline(1)
+	xw.start("BaseLang")
+
+	if base.Meaning.Content != "" || base.Meaning.MatchingID != "" {
+		xw.element(depth, "Meaning", base.Meaning.Content, optional("MatchingID", base.Meaning.MatchingID)...)
+	}
+	for _, ref := range base.References {
+		xw.element(depth, "Reference", "", optional("TYPE", ref.Type, "VALUE", ref.Value, "MatchingID", ref.MatchingID)...)
+	}
+	for _, comment := range base.Comments {
+		xw.element(depth, "Comment", comment.Content, optional("MatchingID", comment.MatchingID)...)
+	}
+	for _, expl := range base.Explanations {
+		xw.element(depth, "Explanation", expl.Content, optional("MatchingID", expl.MatchingID)...)
+	}
+	for _, alt := range base.Alternates {
+		xw.element(depth, "Alternate", alt.Content)
+	}
+	for _, ant := range base.Antonyms {
+		xw.element(depth, "Antonym", "", optional("Value", ant.Value)...)
+	}
+	for _, usage := range base.Usages {
+		xw.element(depth, "Usage", usage.Content, optional("MatchingID", usage.MatchingID)...)
+	}
+	if base.Phonetic.Content != "" || base.Phonetic.File != "" {
+		xw.element(depth, "Phonetic", base.Phonetic.Content, optional("File", base.Phonetic.File)...)
+	}
+	for _, ill := range base.Illustrations {
+		xw.element(depth, "Illustration", "", optional("TYPE", ill.Type, "VALUE", ill.Value, "Norlexin", ill.Norlexin)...)
+	}
+	for _, infl := range base.Inflections {
+		xw.newline(depth)
+		xw.start("Inflection")
+		xw.text(infl.Content)
+		for _, variant := range infl.Variants {
+			xw.start("Variant", optional("Description", variant.Description)...)
+			xw.text(variant.Content)
+			xw.end("Variant")
+		}
+		xw.end("Inflection")
+	}
+	if base.Graminfo != "" {
+		xw.element(depth, "Graminfo", base.Graminfo)
+	}
+	xw.examples(depth, base.Examples)
+	xw.idioms(depth, base.Idioms)
+	xw.compounds(depth, base.Compounds)
+	xw.derivations(depth, base.Derivations)
+	for _, index := range base.Indexes {
+		xw.element(depth, "Index", "", optional("Value", index.Value, "type", index.Type)...)
+	}
+
+	xw.newline(1)
+	xw.end("BaseLang")
+}
+
+// targetLang writes a <TargetLang> element
+func (xw *xmlWriter) targetLang(target parser.TargetLang) {
+	const depth = 2
+
+	xw.newline(1)
+	xw.start("TargetLang", optional("Comment", target.Comment)...)
+
+	for _, translation := range target.Translations {
+		xw.element(depth, "Translation", translation)
+	}
+	for _, synonym := range target.Synonyms {
+		xw.element(depth, "Synonym", synonym)
+	}
+	for _, comment := range target.CommentElems {
+		xw.element(depth, "Comment", comment)
+	}
+	for _, explanation := range target.Explanations {
+		xw.element(depth, "Explanation", explanation)
+	}
+	for _, ant := range target.Antonyms {
+		xw.element(depth, "Antonym", "", optional("Value", ant.Value)...)
+	}
+	xw.examples(depth, target.Examples)
+	xw.idioms(depth, target.Idioms)
+	xw.compounds(depth, target.Compounds)
+	xw.derivations(depth, target.Derivations)
+
+	xw.newline(1)
+	xw.end("TargetLang")
+}
+
+func (xw *xmlWriter) examples(depth int, examples []parser.Example) {
+	for _, example := range examples {
+		xw.element(depth, "Example", example.Content, optional("ID", example.ID, "MatchingID", example.MatchingID)...)
+	}
+}
+
+func (xw *xmlWriter) idioms(depth int, idioms []parser.Idiom) {
+	for _, idiom := range idioms {
+		xw.element(depth, "Idiom", idiom.Content, optional("ID", idiom.ID, "MatchingID", idiom.MatchingID)...)
+	}
+}
+
+func (xw *xmlWriter) compounds(depth int, compounds []parser.Compound) {
+	for _, compound := range compounds {
+		xw.newline(depth)
+		xw.start("Compound", optional("ID", compound.ID, "Description", compound.Description, "MatchingID", compound.MatchingID)...)
+		xw.text(compound.Content)
+		if compound.Inflection != "" {
+			xw.start("Inflection")
+			xw.text(compound.Inflection)
+			xw.end("Inflection")
+		}
+		xw.end("Compound")
+	}
+}
+
+func (xw *xmlWriter) derivations(depth int, derivations []parser.Derivation) {
+	for _, derivation := range derivations {
+		xw.newline(depth)
+		xw.start("Derivation", optional("ID", derivation.ID, "Description", derivation.Description)...)
+		xw.text(derivation.Content)
+		if derivation.Inflection != "" {
+			xw.start("Inflection")
+			xw.text(derivation.Inflection)
+			xw.end("Inflection")
+		}
+		xw.end("Derivation")
+	}
+}
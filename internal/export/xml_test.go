@@ -0,0 +1,43 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"lexin-sqlite/internal/parser"
+	"lexin-sqlite/pkg/lexin"
+)
+
+func TestXMLRoundTrip(t *testing.T) {
+	db, dict := importFixture(t)
+
+	var out bytes.Buffer
+	if err := XML(context.Background(), &out, db, dict, lexin.EntryFilter{}); err != nil {
+		t.Fatalf("XML failed: %v", err)
+	}
+
+	exported, err := parser.ParseXML(bytes.NewReader(out.Bytes()))
+	if err != nil {
+		t.Fatalf("failed to parse the export: %v\n%s", err, out.String())
+	}
+	original := parseFixture(t)
+
+	if exported.Header() != original.Header() {
+		t.Errorf("header = %+v, want %+v", exported.Header(), original.Header())
+	}
+	if len(exported.Words) != len(original.Words) {
+		t.Fatalf("exported %d words, want %d", len(exported.Words), len(original.Words))
+	}
+	for i := range original.Words {
+		if !reflect.DeepEqual(exported.Words[i], original.Words[i]) {
+			t.Errorf("word %d differs:\ngot  %+v\nwant %+v", i, exported.Words[i], original.Words[i])
+		}
+	}
+
+	if strings.Contains(out.String(), `Variant=""`) {
+		t.Error(`export writes Variant="" on words without a variant`)
+	}
+}
@@ -0,0 +1,208 @@
+package export
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"lexin-sqlite/internal/render"
+	"lexin-sqlite/pkg/lexin"
+)
+
+// yomitanBankSize is the number of terms in each term_bank_N.json
+const yomitanBankSize = 10000
+
+// YomitanOptions configures the Yomitan dictionary export
+type YomitanOptions struct {
+	// Title is the dictionary title, "Lexin {base}-{target}" when empty.
+	// {base}, {target} and {version} are replaced as in deck names.
+	Title string
+}
+
+// yomitanIndex is the index.json of a Yomitan dictionary
+type yomitanIndex struct {
+	Title          string `json:"title"`
+	Revision       string `json:"revision"`
+	Format         int    `json:"format"`
+	Sequenced      bool   `json:"sequenced"`
+	Author         string `json:"author"`
+	Description    string `json:"description"`
+	SourceLanguage string `json:"sourceLanguage"`
+	TargetLanguage string `json:"targetLanguage"`
+}
+
+// yomitanNode is an element of a structured-content glossary
+type yomitanNode struct {
+	Tag     string            `json:"tag"`
+	Content any               `json:"content,omitempty"`
+	Data    map[string]string `json:"data,omitempty"`
+	Style   map[string]string `json:"style,omitempty"`
+}
+
+// Yomitan writes dict as a Yomitan dictionary zip: index.json, the terms
+// in term_bank_N.json and the part-of-speech tags in tag_bank_1.json.
+// Each headword gets a structured-content glossary with its translations,
+// meanings, examples and idioms, and each inflected form a deinflection
+// entry leading to the headword.
+func Yomitan(ctx context.Context, w io.Writer, db *lexin.DB, dict *lexin.Dictionary, filter lexin.EntryFilter, opts YomitanOptions) error {
+	if dict == nil {
+		return errNoDictionary
+	}
+	if opts.Title == "" {
+		opts.Title = "Lexin {base}-{target}"
+	}
+
+	zw := zip.NewWriter(w)
+
+	// The dictionary is not sequenced: every entry has a sequence of its
+	// own, so merging by sequence would only fold inflected forms into
+	// their headword
+	if err := writeZipJSON(zw, "index.json", yomitanIndex{
+		Title:          ankiDeckName(opts.Title, *dict),
+		Revision:       dict.Name() + "-" + dict.Version,
+		Format:         3,
+		Sequenced:      false,
+		Author:         "Lexin",
+		Description:    fmt.Sprintf("Lexin %s dictionary, version %s", dict.Name(), dict.Version),
+		SourceLanguage: languageTag(dict.BaseLang),
+		TargetLanguage: languageTag(dict.TargetLang),
+	}); err != nil {
+		return err
+	}
+
+	var bank [][]any
+	banks := 0
+	flush := func() error {
+		if len(bank) == 0 {
+			return nil
+		}
+		banks++
+		err := writeZipJSON(zw, fmt.Sprintf("term_bank_%d.json", banks), bank)
+		bank = bank[:0]
+		return err
+	}
+
+	tags := make(map[string]string)
+	err := db.EachEntry(ctx, dict, filter, func(entry lexin.Entry) error {
+		glossary := yomitanGlossary(entry)
+		if glossary == nil {
+			return nil
+		}
+
+		tag := yomitanTag(entry.Type)
+		if tag != "" {
+			tags[tag] = entry.Type
+		}
+		bank = append(bank, []any{entry.Value, "", tag, "", 0, []any{glossary}, entry.ID, ""})
+
+		// Lexin does not name the rules that derive a form, so the rule
+		// chain is empty and the form leads straight to the headword
+		for _, form := range inflectedForms(entry) {
+			deinflection := []any{entry.Value, []string{}}
+			bank = append(bank, []any{form, "", "non-lemma", "", -1, []any{deinflection}, 0, ""})
+		}
+
+		if len(bank) >= yomitanBankSize {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	tagBank := [][]any{{"non-lemma", "", 0, "Inflected form", 0}}
+	names := make([]string, 0, len(tags))
+	for name := range tags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		tagBank = append(tagBank, []any{name, "partOfSpeech", 0, tags[name], 0})
+	}
+	if err := writeZipJSON(zw, "tag_bank_1.json", tagBank); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+// yomitanGlossary builds the structured-content glossary of an entry, or
+// nil when there is nothing to show
+func yomitanGlossary(entry lexin.Entry) any {
+	var senses []any
+	for i, base := range entry.BaseLangs {
+		var content []any
+		if target, ok := render.Target(entry, base, i); ok {
+			if translations := render.TargetTranslations(target); len(translations) > 0 {
+				content = append(content, yomitanNode{Tag: "div", Data: yomitanData("translation"), Content: strings.Join(translations, "; ")})
+			}
+		}
+		if base.Meaning.Content != "" {
+			content = append(content, yomitanNode{
+				Tag:     "div",
+				Data:    yomitanData("meaning"),
+				Style:   map[string]string{"fontStyle": "italic"},
+				Content: base.Meaning.Content,
+			})
+		}
+		if list := yomitanList("examples", render.Examples(entry, base)); list != nil {
+			content = append(content, list)
+		}
+		if list := yomitanList("idioms", render.Idioms(entry, base)); list != nil {
+			content = append(content, list)
+		}
+		if len(content) > 0 {
+			senses = append(senses, yomitanNode{Tag: "div", Data: yomitanData("sense"), Content: content})
+		}
+	}
+
+	if len(senses) == 0 {
+		return nil
+	}
+	return map[string]any{"type": "structured-content", "content": senses}
+}
+
+// yomitanList renders examples or idioms and their translations as a list
+func yomitanList(class string, pairs []render.Pair) any {
+	if len(pairs) == 0 {
+		return nil
+	}
+
+	items := make([]any, 0, len(pairs))
+	for _, pair := range pairs {
+		content := []any{pair.Swedish}
+		if pair.Translation != "" {
+			content = append(content, " – ", yomitanNode{Tag: "span", Data: yomitanData("translation"), Content: pair.Translation})
+		}
+		items = append(items, yomitanNode{Tag: "li", Content: content})
+	}
+	return yomitanNode{Tag: "ul", Data: yomitanData(class), Content: items}
+}
+
+// yomitanData marks a node with its Lexin class for dictionary styles
+func yomitanData(class string) map[string]string {
+	return map[string]string{"lexin": class}
+}
+
+// yomitanTag turns a word type such as "subst." into a tag name, which
+// cannot hold spaces
+func yomitanTag(wordType string) string {
+	return strings.Join(strings.Fields(wordType), "_")
+}
+
+// writeZipJSON adds a JSON encoded archive member
+func writeZipJSON(zw *zip.Writer, name string, v any) error {
+	f, err := createZipEntry(zw, name)
+	if err != nil {
+		return err
+	}
+	return json.NewEncoder(f).Encode(v)
+}
@@ -0,0 +1,101 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"lexin-sqlite/pkg/lexin"
+)
+
+func TestYomitan(t *testing.T) {
+	db, dict := importFixture(t)
+
+	var out bytes.Buffer
+	if err := Yomitan(context.Background(), &out, db, dict, lexin.EntryFilter{}, YomitanOptions{}); err != nil {
+		t.Fatalf("Yomitan failed: %v", err)
+	}
+	members := readZip(t, out.Bytes())
+
+	var index yomitanIndex
+	if err := json.Unmarshal(members["index.json"], &index); err != nil {
+		t.Fatalf("index.json does not decode: %v", err)
+	}
+	want := yomitanIndex{
+		Title:          "Lexin swe-eng",
+		Revision:       "swe-eng-2.1",
+		Format:         3,
+		Sequenced:      false,
+		Author:         "Lexin",
+		Description:    "Lexin swe-eng dictionary, version 2.1",
+		SourceLanguage: "sv",
+		TargetLanguage: "en",
+	}
+	if index != want {
+		t.Errorf("index = %+v, want %+v", index, want)
+	}
+
+	var rows [][]json.RawMessage
+	if err := json.Unmarshal(members["term_bank_1.json"], &rows); err != nil {
+		t.Fatalf("term_bank_1.json does not decode: %v", err)
+	}
+	if _, ok := members["term_bank_2.json"]; ok {
+		t.Error("the fixture fills more than one term bank")
+	}
+
+	lemmas := make(map[string]int)
+	forms := make(map[string][]any)
+	for _, row := range rows {
+		if len(row) != 8 {
+			t.Fatalf("term row %s has %d fields, want 8", row, len(row))
+		}
+		var term, tags string
+		var glossary []any
+		for i, dest := range map[int]any{0: &term, 2: &tags, 5: &glossary} {
+			if err := json.Unmarshal(row[i], dest); err != nil {
+				t.Fatalf("term row %s field %d: %v", row, i, err)
+			}
+		}
+		if len(glossary) != 1 {
+			t.Fatalf("term %s has %d glossary items, want 1", term, len(glossary))
+		}
+
+		if tags == "non-lemma" {
+			forms[term] = glossary[0].([]any)
+			if string(row[6]) != "0" {
+				t.Errorf("form %s has sequence %s, want 0", term, row[6])
+			}
+			continue
+		}
+		lemmas[term]++
+		content, ok := glossary[0].(map[string]any)
+		if !ok || content["type"] != "structured-content" {
+			t.Errorf("term %s glossary = %v, want structured content", term, glossary[0])
+		}
+		if tags != "subst." {
+			t.Errorf("term %s has tags %q, want subst.", term, tags)
+		}
+	}
+
+	// The second bostad has no translation but still a meaning
+	if !reflect.DeepEqual(lemmas, map[string]int{"hus": 1, "bostad": 2}) {
+		t.Errorf("lemmas = %v", lemmas)
+	}
+	for _, form := range []string{"huset", "husen", "husena"} {
+		deinflection, ok := forms[form]
+		if !ok {
+			t.Errorf("no deinflection for %s", form)
+			continue
+		}
+		if !reflect.DeepEqual(deinflection, []any{"hus", []any{}}) {
+			t.Errorf("deinflection of %s = %v, want hus with no rules", form, deinflection)
+		}
+	}
+
+	if !strings.Contains(string(members["tag_bank_1.json"]), `["subst.","partOfSpeech",0,"subst.",0]`) {
+		t.Errorf("tag bank = %s, want the subst. part of speech", members["tag_bank_1.json"])
+	}
+}
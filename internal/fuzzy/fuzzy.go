@@ -0,0 +1,147 @@
+// Package fuzzy implements the typo tolerant matching used for "did you
+// mean" suggestions: a diacritic folding trigram index and an edit distance
+// that treats the Swedish å/ä/ö confusions as cheap.
+package fuzzy
+
+import (
+	"strings"
+	"unicode"
+)
+
+// DiacriticCost is the cost of substituting two letters that only differ by
+// a diacritic, such as a/å/ä or o/ö
+const DiacriticCost = 0.25
+
+// folds maps letters with diacritics to their base letter
+var folds = map[rune]rune{
+	'å': 'a', 'ä': 'a', 'à': 'a', 'á': 'a', 'â': 'a',
+	'ö': 'o', 'ø': 'o', 'ó': 'o', 'ò': 'o', 'ô': 'o',
+	'é': 'e', 'è': 'e', 'ê': 'e', 'ë': 'e',
+	'ü': 'u', 'ú': 'u', 'ù': 'u', 'û': 'u',
+	'í': 'i', 'ì': 'i', 'î': 'i', 'ï': 'i',
+	'æ': 'a', 'ç': 'c', 'ñ': 'n',
+}
+
+// FoldRune lower cases r and strips its diacritic
+func FoldRune(r rune) rune {
+	r = unicode.ToLower(r)
+	if folded, ok := folds[r]; ok {
+		return folded
+	}
+	return r
+}
+
+// Fold lower cases s and strips diacritics, so that "Väg", "väg" and "vag"
+// fold to the same string
+func Fold(s string) string {
+	return strings.Map(FoldRune, strings.TrimSpace(s))
+}
+
+// Trigrams returns the distinct trigrams of the folded form of s. The string
+// is padded so that short words and word edges get trigrams too.
+func Trigrams(s string) []string {
+	runes := []rune("$$" + Fold(s) + "$")
+	if len(runes) < 4 {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var trigrams []string
+	for i := 0; i+3 <= len(runes); i++ {
+		trigram := string(runes[i : i+3])
+		if seen[trigram] {
+			continue
+		}
+		seen[trigram] = true
+		trigrams = append(trigrams, trigram)
+	}
+	return trigrams
+}
+
+// DeleteIndexLength is the longest folded headword kept in the deletion
+// index. Words this short have so few trigrams that a typo can leave only
+// the edge trigram shared, so queries shorter than this also look up their
+// Deletes, which find every headword within one edit.
+const DeleteIndexLength = 5
+
+// Deletes returns the distinct folded form of s and the strings made by
+// deleting one letter from it. Two words within one insertion, deletion,
+// substitution or transposition of each other share at least one of them.
+func Deletes(s string) []string {
+	runes := []rune(Fold(s))
+	if len(runes) == 0 {
+		return nil
+	}
+
+	seen := map[string]bool{string(runes): true}
+	deletes := []string{string(runes)}
+	for i := range runes {
+		deleted := string(runes[:i]) + string(runes[i+1:])
+		if deleted == "" || seen[deleted] {
+			continue
+		}
+		seen[deleted] = true
+		deletes = append(deletes, deleted)
+	}
+	return deletes
+}
+
+// Distance returns the weighted optimal string alignment distance between a
+// and b, ignoring case. Insertions, deletions, substitutions and adjacent
+// transpositions cost 1, except substitutions between letters that fold to
+// the same letter, which cost DiacriticCost.
+func Distance(a, b string) float64 {
+	ra := []rune(strings.ToLower(a))
+	rb := []rune(strings.ToLower(b))
+
+	// Three rows are enough for transpositions
+	prev2 := make([]float64, len(rb)+1)
+	prev := make([]float64, len(rb)+1)
+	curr := make([]float64, len(rb)+1)
+	for j := range prev {
+		prev[j] = float64(j)
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = float64(i)
+		for j := 1; j <= len(rb); j++ {
+			cost := substitutionCost(ra[i-1], rb[j-1])
+			best := prev[j-1] + cost
+			if d := prev[j] + 1; d < best {
+				best = d
+			}
+			if d := curr[j-1] + 1; d < best {
+				best = d
+			}
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				if d := prev2[j-2] + 1; d < best {
+					best = d
+				}
+			}
+			curr[j] = best
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+
+	return prev[len(rb)]
+}
+
+// substitutionCost returns the cost of replacing a with b
+func substitutionCost(a, b rune) float64 {
+	if a == b {
+		return 0
+	}
+	if FoldRune(a) == FoldRune(b) {
+		return DiacriticCost
+	}
+	return 1
+}
+
+// MaxDistance returns the default largest distance accepted for a word of
+// the given length: short words tolerate one edit, longer words two
+func MaxDistance(s string) float64 {
+	if len([]rune(s)) <= 4 {
+		return 1
+	}
+	return 2
+}
@@ -0,0 +1,95 @@
+package fuzzy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDistance(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want float64
+	}{
+		{"hus", "hus", 0},
+		{"Hus", "hus", 0},
+		{"vag", "väg", DiacriticCost},
+		{"våg", "väg", DiacriticCost},
+		{"ol", "öl", DiacriticCost},
+		{"Åsa", "asa", DiacriticCost},
+		{"vag", "våg", DiacriticCost},
+		{"hsu", "hus", 1},
+		{"hus", "huset", 2},
+		{"bil", "bal", 1},
+		{"vag", "vägg", 1 + DiacriticCost},
+		{"", "hus", 3},
+		{"katt", "", 4},
+	} {
+		if got := Distance(tc.a, tc.b); got != tc.want {
+			t.Errorf("Distance(%q, %q) = %v, want %v", tc.a, tc.b, got, tc.want)
+		}
+		if got := Distance(tc.b, tc.a); got != tc.want {
+			t.Errorf("Distance(%q, %q) = %v, want %v", tc.b, tc.a, got, tc.want)
+		}
+	}
+}
+
+func TestMaxDistance(t *testing.T) {
+	for _, tc := range []struct {
+		s    string
+		want float64
+	}{
+		{"hus", 1},
+		{"väg", 1},
+		{"häst", 1},
+		{"husen", 2},
+	} {
+		if got := MaxDistance(tc.s); got != tc.want {
+			t.Errorf("MaxDistance(%q) = %v, want %v", tc.s, got, tc.want)
+		}
+	}
+}
+
+func TestTrigrams(t *testing.T) {
+	for _, tc := range []struct {
+		s    string
+		want []string
+	}{
+		{"Väg", []string{"$$v", "$va", "vag", "ag$"}},
+		{"a", []string{"$$a", "$a$"}},
+		{"", nil},
+	} {
+		if got := Trigrams(tc.s); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Trigrams(%q) = %q, want %q", tc.s, got, tc.want)
+		}
+	}
+}
+
+func TestDeletes(t *testing.T) {
+	for _, tc := range []struct {
+		s    string
+		want []string
+	}{
+		{"Hus", []string{"hus", "us", "hs", "hu"}},
+		{"öl", []string{"ol", "l", "o"}},
+		{"ill", []string{"ill", "ll", "il"}},
+		{"a", []string{"a"}},
+		{"", nil},
+	} {
+		if got := Deletes(tc.s); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Deletes(%q) = %q, want %q", tc.s, got, tc.want)
+		}
+	}
+
+	// Words one edit apart share a deletion
+	for _, pair := range [][2]string{{"hsu", "hus"}, {"hus", "huss"}, {"bil", "bal"}, {"väg", "vag"}} {
+		shared := false
+		for _, a := range Deletes(pair[0]) {
+			for _, b := range Deletes(pair[1]) {
+				shared = shared || a == b
+			}
+		}
+		if !shared {
+			t.Errorf("%q and %q share no deletion", pair[0], pair[1])
+		}
+	}
+}
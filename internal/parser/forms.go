@@ -0,0 +1,114 @@
+package parser
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Sources of surface forms
+const (
+	FormInflection = "inflection"
+	FormVariant    = "variant"
+	FormCompound   = "compound"
+	FormDerivation = "derivation"
+)
+
+// Form is a surface form that leads back to a headword
+type Form struct {
+	Value       string
+	Source      string
+	Description string
+}
+
+// WordForms returns every surface form of a word found in its inflections,
+// inflection variants, compounds and derivations. Forms are lower case and
+// each form is listed once, with the first source it was found in.
+func WordForms(word Word) []Form {
+	var forms []Form
+	seen := make(map[string]bool)
+	add := func(values []string, source, description string) {
+		for _, value := range values {
+			if seen[value] {
+				continue
+			}
+			seen[value] = true
+			forms = append(forms, Form{Value: value, Source: source, Description: description})
+		}
+	}
+
+	for _, baseLang := range word.BaseLangs {
+		for _, infl := range baseLang.Inflections {
+			add(InflectionForms(infl.Content), FormInflection, "")
+			for _, variant := range infl.Variants {
+				add(InflectionForms(variant.Content), FormVariant, variant.Description)
+			}
+		}
+		for _, compound := range baseLang.Compounds {
+			add(AffixedForms(compound.Content, compound.Inflection), FormCompound, compound.Description)
+		}
+		for _, derivation := range baseLang.Derivations {
+			add(AffixedForms(derivation.Content, derivation.Inflection), FormDerivation, derivation.Description)
+		}
+	}
+
+	return forms
+}
+
+// InflectionForms splits the text of an Inflection element into forms.
+// Lexin lists one or more full forms separated by spaces or commas.
+func InflectionForms(content string) []string {
+	var forms []string
+	for _, field := range splitForms(content) {
+		if form := cleanForm(field); form != "" {
+			forms = append(forms, form)
+		}
+	}
+	return forms
+}
+
+// AffixedForms returns the forms of a compound or derivation. The stem is
+// written with ~ at the joints ("hus~bil") and its inflection is usually a
+// list of endings ("-en -ar") that are appended to the joined stem. Full
+// forms in the inflection are returned as they are.
+func AffixedForms(stem, inflection string) []string {
+	base := cleanForm(strings.ReplaceAll(stem, "~", ""))
+	if base == "" {
+		return nil
+	}
+
+	forms := []string{base}
+	for _, field := range splitForms(inflection) {
+		field = strings.TrimSpace(field)
+		if strings.HasPrefix(field, "-") || strings.HasPrefix(field, "~") {
+			if ending := cleanForm(field[1:]); ending != "" {
+				forms = append(forms, base+ending)
+			}
+			continue
+		}
+		if form := cleanForm(strings.ReplaceAll(field, "~", "")); form != "" {
+			forms = append(forms, form)
+		}
+	}
+	return forms
+}
+
+// NormalizeForm lower cases a form the same way stored forms are
+func NormalizeForm(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+// splitForms splits a list of forms on white space, commas and semicolons
+func splitForms(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || r == ',' || r == ';'
+	})
+}
+
+// cleanForm strips surrounding punctuation such as parentheses from a form
+// and lower cases it
+func cleanForm(s string) string {
+	s = strings.TrimFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return NormalizeForm(s)
+}
@@ -0,0 +1,143 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWordForms(t *testing.T) {
+	tests := []struct {
+		name string
+		word Word
+		want []Form
+	}{
+		{
+			name: "no forms",
+			word: Word{Value: "och", BaseLangs: []BaseLang{{Meaning: Meaning{Content: "bindeord"}}}},
+			want: nil,
+		},
+		{
+			name: "inflections",
+			word: Word{Value: "hus", BaseLangs: []BaseLang{{
+				Inflections: []Inflection{{Content: "huset, husen; (husens)"}},
+			}}},
+			want: []Form{
+				{Value: "huset", Source: FormInflection},
+				{Value: "husen", Source: FormInflection},
+				{Value: "husens", Source: FormInflection},
+			},
+		},
+		{
+			name: "variants",
+			word: Word{Value: "gå", BaseLangs: []BaseLang{{
+				Inflections: []Inflection{{
+					Content: "gick gått",
+					Variants: []Variant{
+						{Content: "går", Description: "presens"},
+						{Content: "gick", Description: "preteritum"},
+						{Content: "gångit gångne", Description: "ålderdomligt"},
+					},
+				}},
+			}}},
+			want: []Form{
+				{Value: "gick", Source: FormInflection},
+				{Value: "gått", Source: FormInflection},
+				{Value: "går", Source: FormVariant, Description: "presens"},
+				{Value: "gångit", Source: FormVariant, Description: "ålderdomligt"},
+				{Value: "gångne", Source: FormVariant, Description: "ålderdomligt"},
+			},
+		},
+		{
+			name: "compounds",
+			word: Word{Value: "hus", BaseLangs: []BaseLang{{
+				Compounds: []Compound{
+					{Content: "hus~bil", Description: "subst.", Inflection: "-en -ar"},
+					{Content: "hus~båt", Inflection: "~en, hus~båtar"},
+					{Content: "~"},
+				},
+			}}},
+			want: []Form{
+				{Value: "husbil", Source: FormCompound, Description: "subst."},
+				{Value: "husbilen", Source: FormCompound, Description: "subst."},
+				{Value: "husbilar", Source: FormCompound, Description: "subst."},
+				{Value: "husbåt", Source: FormCompound},
+				{Value: "husbåten", Source: FormCompound},
+				{Value: "husbåtar", Source: FormCompound},
+			},
+		},
+		{
+			name: "derivations",
+			word: Word{Value: "hus", BaseLangs: []BaseLang{{
+				Derivations: []Derivation{{Content: "hus~lig", Description: "adj.", Inflection: "-t -a"}},
+			}}},
+			want: []Form{
+				{Value: "huslig", Source: FormDerivation, Description: "adj."},
+				{Value: "husligt", Source: FormDerivation, Description: "adj."},
+				{Value: "husliga", Source: FormDerivation, Description: "adj."},
+			},
+		},
+		{
+			name: "first source wins across senses",
+			word: Word{Value: "hus", BaseLangs: []BaseLang{
+				{
+					Inflections: []Inflection{{Content: "huset", Variants: []Variant{{Content: "Huset", Description: "bestämd"}}}},
+					Compounds:   []Compound{{Content: "hus~bil"}},
+				},
+				{
+					Inflections: []Inflection{{Content: "HUSBIL huset"}},
+					Derivations: []Derivation{{Content: "hus~et"}},
+				},
+			}},
+			want: []Form{
+				{Value: "huset", Source: FormInflection},
+				{Value: "husbil", Source: FormCompound},
+			},
+		},
+		{
+			name: "case folding",
+			word: Word{Value: "Åre", BaseLangs: []BaseLang{{
+				Inflections: []Inflection{{Content: "ÅRES Öar", Variants: []Variant{{Content: "ÄNGEN"}}}},
+				Compounds:   []Compound{{Content: "Åre~STUGA", Inflection: "-N"}},
+			}}},
+			want: []Form{
+				{Value: "åres", Source: FormInflection},
+				{Value: "öar", Source: FormInflection},
+				{Value: "ängen", Source: FormVariant},
+				{Value: "årestuga", Source: FormCompound},
+				{Value: "årestugan", Source: FormCompound},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WordForms(tt.word); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WordForms() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeForm(t *testing.T) {
+	tests := []struct {
+		form string
+		want string
+	}{
+		{"hus", "hus"},
+		{"Huset", "huset"},
+		{"  huset\t", "huset"},
+		{"hus ", "hus"},
+		{"ÅÄÖ", "åäö"},
+		{"Ölet", "ölet"},
+		{"ÉTUDE", "étude"},
+		{"FØRSTE", "første"},
+		{"hus-bil", "hus-bil"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeForm(tt.form); got != tt.want {
+			t.Errorf("NormalizeForm(%q) = %q, want %q", tt.form, got, tt.want)
+		}
+	}
+}
@@ -154,10 +154,10 @@ type Index struct {
 // TargetLang represents the target language information
 type TargetLang struct {
 	Comment     string      `xml:"Comment,attr"`
-	Translation string      `xml:"Translation"`
-	Synonym     string      `xml:"Synonym"`
-	CommentElem string      `xml:"Comment"`
-	Explanation string      `xml:"Explanation"`
+	Translations []string   `xml:"Translation"`
+	Synonyms    []string    `xml:"Synonym"`
+	CommentElems []string   `xml:"Comment"`
+	Explanations []string   `xml:"Explanation"`
 	Antonyms    []Antonym   `xml:"Antonym"`
 	Examples    []Example   `xml:"Example"`
 	Idioms      []Idiom     `xml:"Idiom"`
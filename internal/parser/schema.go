@@ -0,0 +1,64 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+)
+
+// elementSchema describes an element the parser structs read: the
+// attributes and child elements they have fields for
+type elementSchema struct {
+	attrs    map[string]bool
+	children map[string]*elementSchema
+	// repeated is set when the field is a slice, so that the element may
+	// appear more than once in its parent
+	repeated bool
+	// text is set when the element's character data is read
+	text bool
+}
+
+// dictionarySchema is the schema of the root Dictionary element, built
+// from the struct tags of the parser model
+var dictionarySchema = schemaOf(reflect.TypeOf(Dictionary{}))
+
+// schemaOf builds the schema of an element decoded into a value of type t.
+// Elements decoded into strings have no attributes or children.
+func schemaOf(t reflect.Type) *elementSchema {
+	schema := &elementSchema{
+		attrs:    make(map[string]bool),
+		children: make(map[string]*elementSchema),
+	}
+	if t.Kind() != reflect.Struct {
+		schema.text = t.Kind() == reflect.String
+		return schema
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("xml")
+		if tag == "" || tag == "-" || field.Name == "XMLName" {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		switch {
+		case options == "attr":
+			schema.attrs[name] = true
+		case options == "chardata":
+			schema.text = true
+		case options != "" || name == "":
+			// innerxml and the like hold no named elements
+		default:
+			fieldType := field.Type
+			repeated := fieldType.Kind() == reflect.Slice
+			if repeated {
+				fieldType = fieldType.Elem()
+			}
+			child := schemaOf(fieldType)
+			child.repeated = repeated
+			schema.children[name] = child
+		}
+	}
+
+	return schema
+}
@@ -0,0 +1,163 @@
+package parser
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Header holds the attributes of the root Dictionary element
+type Header struct {
+	BaseLang   string `json:"base_lang"`
+	TargetLang string `json:"target_lang"`
+	Version    string `json:"version"`
+}
+
+// Source yields the words of a dictionary one at a time
+type Source interface {
+	// Header returns the dictionary attributes
+	Header() Header
+	// Next returns the next word, or io.EOF when there are no more words
+	Next() (*Word, error)
+}
+
+// Reader streams words from a Lexin XML document without holding the
+// whole dictionary in memory
+type Reader struct {
+	decoder *xml.Decoder
+	closer  io.Closer
+	header  Header
+	done    bool
+}
+
+// NewReader reads up to the root Dictionary element and returns a Reader
+// positioned at its first child
+func NewReader(r io.Reader) (*Reader, error) {
+	decoder := xml.NewDecoder(r)
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil, fmt.Errorf("failed to decode XML: no Dictionary element found")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode XML: %w", err)
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if start.Name.Local != "Dictionary" {
+			return nil, fmt.Errorf("failed to decode XML: expected Dictionary element, got %s", start.Name.Local)
+		}
+
+		return &Reader{decoder: decoder, header: headerOf(start)}, nil
+	}
+}
+
+// OpenXMLFile opens a Lexin XML file for streaming. The caller must close
+// the returned Reader.
+func OpenXMLFile(filePath string) (*Reader, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open XML file: %w", err)
+	}
+
+	reader, err := NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	reader.closer = file
+
+	return reader, nil
+}
+
+// Header returns the attributes of the root element
+func (r *Reader) Header() Header {
+	return r.header
+}
+
+// Next decodes the next Word element. It returns io.EOF once the end of the
+// Dictionary element is reached.
+func (r *Reader) Next() (*Word, error) {
+	if r.done {
+		return nil, io.EOF
+	}
+
+	for {
+		token, err := r.decoder.Token()
+		if err == io.EOF {
+			return nil, fmt.Errorf("failed to decode XML: unexpected end of file")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode XML: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Local != "Word" {
+				// Unknown elements at this level are ignored, just like
+				// ParseXML does
+				if err := r.decoder.Skip(); err != nil {
+					return nil, fmt.Errorf("failed to decode XML: %w", err)
+				}
+				continue
+			}
+
+			var word Word
+			if err := r.decoder.DecodeElement(&word, &t); err != nil {
+				return nil, fmt.Errorf("failed to decode word: %w", err)
+			}
+			return &word, nil
+
+		case xml.EndElement:
+			// The only end element seen at this level closes Dictionary
+			r.done = true
+			return nil, io.EOF
+		}
+	}
+}
+
+// Close closes the underlying file if the Reader was created by OpenXMLFile
+func (r *Reader) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+	return r.closer.Close()
+}
+
+// Header returns the dictionary attributes
+func (d *Dictionary) Header() Header {
+	return Header{
+		BaseLang:   d.BaseLang,
+		TargetLang: d.TargetLang,
+		Version:    d.Version,
+	}
+}
+
+// Source returns a Source over the already parsed words
+func (d *Dictionary) Source() Source {
+	return &sliceSource{dict: d}
+}
+
+// sliceSource adapts a parsed Dictionary to the Source interface
+type sliceSource struct {
+	dict *Dictionary
+	next int
+}
+
+func (s *sliceSource) Header() Header {
+	return s.dict.Header()
+}
+
+func (s *sliceSource) Next() (*Word, error) {
+	if s.next >= len(s.dict.Words) {
+		return nil, io.EOF
+	}
+	word := &s.dict.Words[s.next]
+	s.next++
+	return word, nil
+}
@@ -0,0 +1,237 @@
+package parser
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Severities of diagnostics
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// ReferenceTypes are the TYPE values of Reference elements the
+// word_references table accepts
+var ReferenceTypes = []string{"animation", "compare", "phonetic", "see"}
+
+// IndexTypes are the type values of Index elements the indexes table
+// accepts, besides an empty one
+var IndexTypes = []string{"prefix", "suffix"}
+
+// Diagnostic is a problem found at a position of a Lexin XML file
+type Diagnostic struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// String formats the diagnostic as file:line:col: severity: message
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %s", d.File, d.Line, d.Column, d.Severity, d.Message)
+}
+
+// Validation is the result of validating a Lexin XML file
+type Validation struct {
+	File        string       `json:"file"`
+	Header      Header       `json:"header"`
+	Words       int          `json:"words"`
+	Errors      int          `json:"errors"`
+	Warnings    int          `json:"warnings"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+	// Unmapped counts the data the parser drops by path below the
+	// Dictionary element: unknown elements such as "Word/BaseLang/Foo",
+	// unknown attributes such as "Word/@Extra", text such as
+	// "Word/BaseLang/#text" and repeats of elements the parser reads once
+	// such as "Word/TargetLang/Translation[2]", of which only one reaches
+	// the database
+	Unmapped map[string]int `json:"unmapped"`
+}
+
+// validationFrame is an open element while validating
+type validationFrame struct {
+	name string
+	// path is the path of the element below the Dictionary element
+	path string
+	// schema is nil for elements the parser does not read
+	schema *elementSchema
+	// seen counts the child elements by name
+	seen map[string]int
+}
+
+// ValidateXMLFile validates the Lexin XML file at path
+func ValidateXMLFile(path string) (*Validation, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open XML file: %w", err)
+	}
+	defer file.Close()
+
+	return ValidateXML(file, path)
+}
+
+// ValidateXML reads a Lexin XML document token by token, alongside the
+// schema of the parser structs, and reports with their line and column the
+// problems that would make an import fail or lose data: malformed XML,
+// elements, attributes and text the parser structs ignore or overwrite,
+// words without the ID, VariantID, Type or Value attributes, and
+// Reference and Index types the database rejects. A syntax error ends the
+// validation; every other problem is collected. name is the file name used
+// in the diagnostics.
+func ValidateXML(r io.Reader, name string) (*Validation, error) {
+	v := &Validation{File: name, Diagnostics: []Diagnostic{}, Unmapped: make(map[string]int)}
+	decoder := xml.NewDecoder(r)
+
+	var stack []validationFrame
+	root := false
+	for {
+		line, column := decoder.InputPos()
+		token, err := decoder.Token()
+		if err == io.EOF {
+			if !root {
+				v.report(line, column, SeverityError, "no Dictionary element found")
+			}
+			return v, nil
+		}
+		if err != nil {
+			line, column = decoder.InputPos()
+			message := err.Error()
+			if syntax, ok := err.(*xml.SyntaxError); ok {
+				message = syntax.Msg
+			}
+			v.report(line, column, SeverityError, message)
+			return v, nil
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			frame := validationFrame{name: t.Name.Local, seen: make(map[string]int)}
+
+			if len(stack) == 0 {
+				root = true
+				if t.Name.Local != "Dictionary" {
+					v.report(line, column, SeverityError, fmt.Sprintf("expected Dictionary element, got <%s>", t.Name.Local))
+				} else {
+					frame.schema = dictionarySchema
+					v.Header = headerOf(t)
+				}
+			} else if parent := stack[len(stack)-1]; parent.schema != nil {
+				frame.path = joinPath(parent.path, t.Name.Local)
+				parent.seen[t.Name.Local]++
+				frame.schema = parent.schema.children[t.Name.Local]
+
+				switch {
+				case frame.schema == nil:
+					v.Unmapped[frame.path]++
+					v.report(line, column, SeverityWarning, fmt.Sprintf("unknown element <%s> in <%s> is ignored", t.Name.Local, parent.name))
+				case !frame.schema.repeated && parent.seen[t.Name.Local] > 1:
+					v.Unmapped[fmt.Sprintf("%s[%d]", frame.path, parent.seen[t.Name.Local])]++
+					v.report(line, column, SeverityWarning, fmt.Sprintf("<%s> repeated in <%s>, only one is kept", t.Name.Local, parent.name))
+					frame.schema = nil
+				}
+			}
+
+			if frame.schema != nil {
+				v.checkAttrs(line, column, t, frame)
+			}
+			if frame.schema != nil && t.Name.Local == "Word" && len(stack) == 1 {
+				v.Words++
+			}
+
+			stack = append(stack, frame)
+
+		case xml.CharData:
+			if len(stack) > 0 {
+				frame := stack[len(stack)-1]
+				if frame.schema != nil && !frame.schema.text && len(strings.TrimSpace(string(t))) > 0 {
+					v.Unmapped[joinPath(frame.path, "#text")]++
+					v.report(line, column, SeverityWarning, fmt.Sprintf("text in <%s> is ignored", frame.name))
+				}
+			}
+
+		case xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+}
+
+// checkAttrs reports the attributes of an element the parser ignores and
+// the required or constrained attributes that are missing or invalid
+func (v *Validation) checkAttrs(line, column int, start xml.StartElement, frame validationFrame) {
+	values := make(map[string]string, len(start.Attr))
+	for _, attr := range start.Attr {
+		if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
+			continue
+		}
+		values[attr.Name.Local] = attr.Value
+		if !frame.schema.attrs[attr.Name.Local] {
+			v.Unmapped[joinPath(frame.path, "@"+attr.Name.Local)]++
+			v.report(line, column, SeverityWarning, fmt.Sprintf("unknown attribute %s on <%s> is ignored", attr.Name.Local, start.Name.Local))
+		}
+	}
+
+	switch start.Name.Local {
+	case "Word":
+		for _, name := range []string{"Value", "ID", "VariantID", "Type"} {
+			if strings.TrimSpace(values[name]) == "" {
+				v.report(line, column, SeverityError, fmt.Sprintf("<Word> is missing the %s attribute", name))
+			}
+		}
+	case "Reference":
+		if value := values["TYPE"]; !slices.Contains(ReferenceTypes, value) {
+			v.report(line, column, SeverityError, fmt.Sprintf("<Reference> TYPE %q is not one of %s", value, strings.Join(ReferenceTypes, ", ")))
+		}
+	case "Index":
+		if value := values["type"]; value != "" && !slices.Contains(IndexTypes, value) {
+			v.report(line, column, SeverityError, fmt.Sprintf("<Index> type %q is not one of %s", value, strings.Join(IndexTypes, ", ")))
+		}
+	}
+}
+
+// report adds a diagnostic
+func (v *Validation) report(line, column int, severity, message string) {
+	v.Diagnostics = append(v.Diagnostics, Diagnostic{
+		File:     v.File,
+		Line:     line,
+		Column:   column,
+		Severity: severity,
+		Message:  message,
+	})
+	if severity == SeverityError {
+		v.Errors++
+	} else {
+		v.Warnings++
+	}
+}
+
+// joinPath appends a step to a path below the Dictionary element
+func joinPath(path, step string) string {
+	if path == "" {
+		return step
+	}
+	return path + "/" + step
+}
+
+// headerOf reads the dictionary attributes from the root element
+func headerOf(start xml.StartElement) Header {
+	var header Header
+	for _, attr := range start.Attr {
+		switch attr.Name.Local {
+		case "BaseLang":
+			header.BaseLang = attr.Value
+		case "TargetLang":
+			header.TargetLang = attr.Value
+		case "Version":
+			header.Version = attr.Value
+		}
+	}
+	return header
+}
@@ -0,0 +1,163 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// validDocument is a well formed document the parser reads completely
+const validDocument = `<?xml version="1.0" encoding="UTF-8"?>
+<Dictionary BaseLang="swe" TargetLang="eng" Version="1">
+<Word Value="hus" Type="subst." ID="1" VariantID="1">
+  <BaseLang>
+    <Meaning>byggnad</Meaning>
+    <Reference TYPE="see" VALUE="bostad"/>
+    <Index Value="hus" type="prefix"/>
+  </BaseLang>
+  <TargetLang Comment="countable">
+    <Translation>house</Translation>
+  </TargetLang>
+</Word>
+</Dictionary>
+`
+
+func TestValidateXML(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		want     []string
+	}{
+		{
+			name:     "valid",
+			document: validDocument,
+			want:     nil,
+		},
+		{
+			name: "missing word attributes",
+			document: `<Dictionary>
+  <Word Value="hus" ID="1"></Word>
+</Dictionary>`,
+			want: []string{
+				"test.xml:2:3: error: <Word> is missing the VariantID attribute",
+				"test.xml:2:3: error: <Word> is missing the Type attribute",
+			},
+		},
+		{
+			name: "unknown element and attribute",
+			document: `<Dictionary>
+<Word Value="hus" Type="subst." ID="1" VariantID="1" Extra="x">
+  <BaseLang><Foo/></BaseLang>
+</Word>
+</Dictionary>`,
+			want: []string{
+				"test.xml:2:1: warning: unknown attribute Extra on <Word> is ignored",
+				"test.xml:3:13: warning: unknown element <Foo> in <BaseLang> is ignored",
+			},
+		},
+		{
+			name: "repeated element and stray text",
+			document: `<Dictionary>
+<Word Value="hus" Type="subst." ID="1" VariantID="1">stray
+  <BaseLang><Meaning>byggnad</Meaning><Meaning>hem</Meaning></BaseLang>
+</Word>
+</Dictionary>`,
+			want: []string{
+				"test.xml:2:54: warning: text in <Word> is ignored",
+				"test.xml:3:39: warning: <Meaning> repeated in <BaseLang>, only one is kept",
+			},
+		},
+		{
+			name: "invalid reference and index types",
+			document: `<Dictionary>
+<Word Value="hus" Type="subst." ID="1" VariantID="1">
+  <BaseLang>
+    <Reference TYPE="link" VALUE="bostad"/>
+	<Index Value="hus" type="infix"/>
+  </BaseLang>
+</Word>
+</Dictionary>`,
+			want: []string{
+				`test.xml:4:5: error: <Reference> TYPE "link" is not one of animation, compare, phonetic, see`,
+				`test.xml:5:2: error: <Index> type "infix" is not one of prefix, suffix`,
+			},
+		},
+		{
+			name:     "wrong root element",
+			document: "<Lexicon/>",
+			want: []string{
+				"test.xml:1:1: error: expected Dictionary element, got <Lexicon>",
+			},
+		},
+		{
+			name:     "empty document",
+			document: "",
+			want: []string{
+				"test.xml:1:1: error: no Dictionary element found",
+			},
+		},
+		{
+			name: "syntax error ends the validation",
+			document: `<Dictionary>
+<Word Value="hus" ID="1">
+  </Meaning>
+<Word Value="bil"/>
+</Dictionary>`,
+			want: []string{
+				"test.xml:2:1: error: <Word> is missing the VariantID attribute",
+				"test.xml:2:1: error: <Word> is missing the Type attribute",
+				"test.xml:3:13: error: element <Word> closed by </Meaning>",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := ValidateXML(strings.NewReader(tt.document), "test.xml")
+			if err != nil {
+				t.Fatalf("ValidateXML failed: %v", err)
+			}
+
+			var got []string
+			errors, warnings := 0, 0
+			for _, d := range v.Diagnostics {
+				got = append(got, d.String())
+				if d.Severity == SeverityError {
+					errors++
+				} else {
+					warnings++
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diagnostics:\ngot  %q\nwant %q", got, tt.want)
+			}
+			if v.Errors != errors || v.Warnings != warnings {
+				t.Errorf("counted %d errors and %d warnings, want %d and %d", v.Errors, v.Warnings, errors, warnings)
+			}
+		})
+	}
+}
+
+func TestValidateXMLSummary(t *testing.T) {
+	document := strings.Replace(validDocument, `<Meaning>byggnad</Meaning>`,
+		`<Meaning>byggnad</Meaning><Meaning>hem</Meaning><Foo/>`, 1)
+
+	v, err := ValidateXML(strings.NewReader(document), "test.xml")
+	if err != nil {
+		t.Fatalf("ValidateXML failed: %v", err)
+	}
+
+	if want := (Header{BaseLang: "swe", TargetLang: "eng", Version: "1"}); v.Header != want {
+		t.Errorf("header = %+v, want %+v", v.Header, want)
+	}
+	if v.Words != 1 {
+		t.Errorf("words = %d, want 1", v.Words)
+	}
+	want := map[string]int{
+		"Word/BaseLang/Meaning[2]": 1,
+		"Word/BaseLang/Foo":        1,
+	}
+	if !reflect.DeepEqual(v.Unmapped, want) {
+		t.Errorf("unmapped = %v, want %v", v.Unmapped, want)
+	}
+}
@@ -0,0 +1,124 @@
+package render
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"lexin-sqlite/pkg/lexin"
+)
+
+// HTML renders the body of an entry as an HTML fragment. The headword
+// itself is left out, since dictionary readers show it already. The markup
+// is well-formed XHTML so that the fragment can also be embedded in XML
+// documents.
+func HTML(entry lexin.Entry) string {
+	var b strings.Builder
+
+	for i, base := range entry.BaseLangs {
+		b.WriteString(`<div class="sense">`)
+		writeHTMLBase(&b, entry, base, i)
+		b.WriteString(`</div>`)
+	}
+
+	return b.String()
+}
+
+// writeHTMLBase renders one base language and the target language data that
+// belongs to it
+func writeHTMLBase(b *strings.Builder, entry lexin.Entry, base lexin.BaseLang, index int) {
+	esc := html.EscapeString
+
+	var grammar []string
+	if entry.Type != "" {
+		grammar = append(grammar, `<i>`+esc(entry.Type)+`</i>`)
+	}
+	if base.Phonetic != nil && base.Phonetic.Content != "" {
+		grammar = append(grammar, `[`+esc(base.Phonetic.Content)+`]`)
+	}
+	if base.Graminfo != "" {
+		grammar = append(grammar, esc(base.Graminfo))
+	}
+	if inflections := Inflections(base); inflections != "" {
+		grammar = append(grammar, `<b>`+esc(inflections)+`</b>`)
+	}
+	if len(grammar) > 0 {
+		fmt.Fprintf(b, `<div class="grammar">%s</div>`, strings.Join(grammar, " "))
+	}
+
+	for _, usage := range base.Usages {
+		fmt.Fprintf(b, `<div class="usage">(%s)</div>`, esc(usage.Content))
+	}
+	if base.Meaning.Content != "" {
+		fmt.Fprintf(b, `<div class="meaning">%s</div>`, esc(base.Meaning.Content))
+	}
+	for _, explanation := range base.Explanations {
+		fmt.Fprintf(b, `<div class="explanation">%s</div>`, esc(explanation.Content))
+	}
+	for _, comment := range base.Comments {
+		fmt.Fprintf(b, `<div class="comment">%s</div>`, esc(comment.Content))
+	}
+
+	if target, ok := Target(entry, base, index); ok {
+		translation := esc(strings.Join(TargetTranslations(target), "; "))
+		if target.Comment != "" {
+			translation = `<i>` + esc(target.Comment) + `</i> ` + translation
+		}
+		if strings.TrimSpace(translation) != "" {
+			fmt.Fprintf(b, `<div class="translation">%s</div>`, translation)
+		}
+		for _, explanation := range nonEmpty(target.Explanations...) {
+			fmt.Fprintf(b, `<div class="explanation">%s</div>`, esc(explanation))
+		}
+		for _, comment := range nonEmpty(target.CommentElems...) {
+			fmt.Fprintf(b, `<div class="comment">%s</div>`, esc(comment))
+		}
+	}
+
+	for _, alternate := range base.Alternates {
+		fmt.Fprintf(b, `<div class="alternate">Also: %s</div>`, esc(alternate.Content))
+	}
+	for _, reference := range base.References {
+		if isMediaReference(reference) {
+			continue
+		}
+		fmt.Fprintf(b, `<div class="reference">See also: %s</div>`, esc(reference.Value))
+	}
+	if len(base.Antonyms) > 0 {
+		values := make([]string, 0, len(base.Antonyms))
+		for _, antonym := range base.Antonyms {
+			values = append(values, esc(antonym.Value))
+		}
+		fmt.Fprintf(b, `<div class="antonym">Opposite: %s</div>`, strings.Join(values, ", "))
+	}
+
+	writeHTMLPairs(b, "examples", Examples(entry, base))
+	writeHTMLPairs(b, "idioms", Idioms(entry, base))
+	writeHTMLPairs(b, "compounds", Compounds(entry, base))
+	writeHTMLPairs(b, "derivations", Derivations(entry, base, index))
+}
+
+// writeHTMLPairs renders a list of phrases and their translations
+func writeHTMLPairs(b *strings.Builder, class string, pairs []Pair) {
+	if len(pairs) == 0 {
+		return
+	}
+
+	esc := html.EscapeString
+	fmt.Fprintf(b, `<ul class="%s">`, class)
+	for _, pair := range pairs {
+		b.WriteString(`<li>`)
+		b.WriteString(esc(pair.Swedish))
+		if pair.Description != "" {
+			fmt.Fprintf(b, ` <i>%s</i>`, esc(pair.Description))
+		}
+		if pair.Inflection != "" {
+			fmt.Fprintf(b, ` %s`, esc(pair.Inflection))
+		}
+		if pair.Translation != "" {
+			fmt.Fprintf(b, ` – <span class="translation">%s</span>`, esc(pair.Translation))
+		}
+		b.WriteString(`</li>`)
+	}
+	b.WriteString(`</ul>`)
+}
@@ -0,0 +1,340 @@
+// Package render turns hydrated entries into the definition bodies used by
+// the servers and exporters
+package render
+
+import (
+	"fmt"
+	"strings"
+
+	"lexin-sqlite/pkg/lexin"
+)
+
+// Pair is a Swedish phrase and its translation, which is empty when the
+// target language has none
+type Pair struct {
+	Swedish     string
+	Description string
+	Inflection  string
+	Translation string
+}
+
+// Translations returns the translations and synonyms of an entry in order,
+// without duplicates
+func Translations(entry lexin.Entry) []string {
+	seen := make(map[string]bool)
+	var translations []string
+	add := func(s string) {
+		s = strings.TrimSpace(s)
+		if s == "" || seen[s] {
+			return
+		}
+		seen[s] = true
+		translations = append(translations, s)
+	}
+	for _, target := range entry.TargetLangs {
+		for _, translation := range target.Translations {
+			add(translation)
+		}
+	}
+	for _, target := range entry.TargetLangs {
+		for _, synonym := range target.Synonyms {
+			add(synonym)
+		}
+	}
+	return translations
+}
+
+// TargetTranslations returns the translations and then the synonyms of one
+// target language, leaving out blank ones
+func TargetTranslations(target lexin.TargetLang) []string {
+	var values []string
+	values = append(values, nonEmpty(target.Translations...)...)
+	values = append(values, nonEmpty(target.Synonyms...)...)
+	return values
+}
+
+// Target returns the target language that translates the base language at
+// index: the TargetLang the linking pass matched with it, or in a database
+// that was not linked since it was imported, the one at the same position
+func Target(entry lexin.Entry, base lexin.BaseLang, index int) (lexin.TargetLang, bool) {
+	if base.MatchedTargetLangID != 0 {
+		for _, target := range entry.TargetLangs {
+			if target.ID == base.MatchedTargetLangID {
+				return target, true
+			}
+		}
+	}
+	if index < len(entry.TargetLangs) {
+		return entry.TargetLangs[index], true
+	}
+	return lexin.TargetLang{}, false
+}
+
+// translated holds the target language examples, idioms or compounds of an
+// entry, to be looked up from the base language element matched with them
+type translated struct {
+	byRowID      map[int64]string
+	byMatchingID map[string]string
+}
+
+// newTranslated returns an empty translated
+func newTranslated() translated {
+	return translated{byRowID: make(map[int64]string), byMatchingID: make(map[string]string)}
+}
+
+// add adds a target language element
+func (t translated) add(rowID int64, matchingID, content string) {
+	t.byRowID[rowID] = content
+	if matchingID != "" {
+		t.byMatchingID[matchingID] = content
+	}
+}
+
+// of returns the translation of a base language element: the target
+// element it is linked to, or in a database that was not linked since it
+// was imported, the one whose MatchingID is its ID
+func (t translated) of(matchedID int64, id string) string {
+	if matchedID != 0 {
+		return t.byRowID[matchedID]
+	}
+	return t.byMatchingID[id]
+}
+
+// Examples pairs the examples of a base language with the target language
+// examples linked to them
+func Examples(entry lexin.Entry, base lexin.BaseLang) []Pair {
+	translations := newTranslated()
+	for _, target := range entry.TargetLangs {
+		for _, example := range target.Examples {
+			translations.add(example.RowID, example.MatchingID, example.Content)
+		}
+	}
+
+	pairs := make([]Pair, 0, len(base.Examples))
+	for _, example := range base.Examples {
+		pairs = append(pairs, Pair{Swedish: example.Content, Translation: translations.of(example.MatchedID, example.ID)})
+	}
+	return pairs
+}
+
+// Idioms pairs the idioms of a base language with the target language
+// idioms linked to them
+func Idioms(entry lexin.Entry, base lexin.BaseLang) []Pair {
+	translations := newTranslated()
+	for _, target := range entry.TargetLangs {
+		for _, idiom := range target.Idioms {
+			translations.add(idiom.RowID, idiom.MatchingID, idiom.Content)
+		}
+	}
+
+	pairs := make([]Pair, 0, len(base.Idioms))
+	for _, idiom := range base.Idioms {
+		pairs = append(pairs, Pair{Swedish: idiom.Content, Translation: translations.of(idiom.MatchedID, idiom.ID)})
+	}
+	return pairs
+}
+
+// Compounds pairs the compounds of a base language with the target
+// language compounds linked to them
+func Compounds(entry lexin.Entry, base lexin.BaseLang) []Pair {
+	translations := newTranslated()
+	for _, target := range entry.TargetLangs {
+		for _, compound := range target.Compounds {
+			translations.add(compound.RowID, compound.MatchingID, compound.Content)
+		}
+	}
+
+	pairs := make([]Pair, 0, len(base.Compounds))
+	for _, compound := range base.Compounds {
+		pairs = append(pairs, Pair{
+			Swedish:     compound.Content,
+			Description: compound.Description,
+			Inflection:  compound.Inflection,
+			Translation: translations.of(compound.MatchedID, compound.ID),
+		})
+	}
+	return pairs
+}
+
+// Derivations pairs the derivations of a base language with their
+// translations. Target derivations carry no MatchingID, so they are paired
+// by position within the target language of the base language.
+func Derivations(entry lexin.Entry, base lexin.BaseLang, index int) []Pair {
+	var translated []lexin.Derivation
+	if target, ok := Target(entry, base, index); ok {
+		translated = target.Derivations
+	}
+
+	pairs := make([]Pair, 0, len(base.Derivations))
+	for i, derivation := range base.Derivations {
+		pair := Pair{
+			Swedish:     derivation.Content,
+			Description: derivation.Description,
+			Inflection:  derivation.Inflection,
+		}
+		if i < len(translated) {
+			pair.Translation = translated[i].Content
+		}
+		pairs = append(pairs, pair)
+	}
+	return pairs
+}
+
+// isMediaReference reports whether a reference names an animation or
+// sound file rather than a headword. Text and HTML leave these out.
+func isMediaReference(reference lexin.Reference) bool {
+	switch reference.Type {
+	case "animation", "phonetic":
+		return true
+	}
+	return false
+}
+
+// Inflections returns the inflected forms of a base language as one line,
+// with variants in parentheses
+func Inflections(base lexin.BaseLang) string {
+	forms := make([]string, 0, len(base.Inflections))
+	for _, inflection := range base.Inflections {
+		form := inflection.Content
+		for _, variant := range inflection.Variants {
+			if variant.Description != "" {
+				form += fmt.Sprintf(" (%s: %s)", variant.Description, variant.Content)
+			} else {
+				form += fmt.Sprintf(" (%s)", variant.Content)
+			}
+		}
+		if form != "" {
+			forms = append(forms, form)
+		}
+	}
+	return strings.Join(forms, ", ")
+}
+
+// Text renders an entry as plain text, one fact per line, for clients that
+// cannot display markup
+func Text(entry lexin.Entry) string {
+	var b strings.Builder
+
+	b.WriteString(entry.Value)
+	for _, base := range entry.BaseLangs {
+		if base.Phonetic != nil && base.Phonetic.Content != "" {
+			fmt.Fprintf(&b, " [%s]", base.Phonetic.Content)
+			break
+		}
+	}
+	if entry.Type != "" {
+		fmt.Fprintf(&b, " %s", entry.Type)
+	}
+	b.WriteString("\n")
+
+	for i, base := range entry.BaseLangs {
+		writeTextBase(&b, entry, base, i)
+	}
+
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+// writeTextBase renders one base language and the target language data that
+// belongs to it
+func writeTextBase(b *strings.Builder, entry lexin.Entry, base lexin.BaseLang, index int) {
+	line := func(format string, args ...interface{}) {
+		b.WriteString("  ")
+		fmt.Fprintf(b, format, args...)
+		b.WriteString("\n")
+	}
+
+	grammar := base.Graminfo
+	if inflections := Inflections(base); inflections != "" {
+		if grammar != "" {
+			grammar += "; "
+		}
+		grammar += inflections
+	}
+	if grammar != "" {
+		line("%s", grammar)
+	}
+	for _, usage := range base.Usages {
+		line("(%s)", usage.Content)
+	}
+	if base.Meaning.Content != "" {
+		line("%s", base.Meaning.Content)
+	}
+	for _, explanation := range base.Explanations {
+		line("%s", explanation.Content)
+	}
+	for _, comment := range base.Comments {
+		line("%s", comment.Content)
+	}
+
+	if target, ok := Target(entry, base, index); ok {
+		translation := strings.Join(TargetTranslations(target), "; ")
+		if target.Comment != "" {
+			translation = strings.TrimSpace(fmt.Sprintf("(%s) %s", target.Comment, translation))
+		}
+		if translation != "" {
+			line("= %s", translation)
+		}
+		for _, explanation := range nonEmpty(target.Explanations...) {
+			line("%s", explanation)
+		}
+		for _, comment := range nonEmpty(target.CommentElems...) {
+			line("%s", comment)
+		}
+	}
+
+	for _, alternate := range base.Alternates {
+		line("Also: %s", alternate.Content)
+	}
+	for _, reference := range base.References {
+		if isMediaReference(reference) {
+			continue
+		}
+		line("See also: %s", reference.Value)
+	}
+	if len(base.Antonyms) > 0 {
+		values := make([]string, 0, len(base.Antonyms))
+		for _, antonym := range base.Antonyms {
+			values = append(values, antonym.Value)
+		}
+		line("Opposite: %s", strings.Join(values, ", "))
+	}
+
+	writeTextPairs(b, "Examples", Examples(entry, base))
+	writeTextPairs(b, "Idioms", Idioms(entry, base))
+	writeTextPairs(b, "Compounds", Compounds(entry, base))
+	writeTextPairs(b, "Derivations", Derivations(entry, base, index))
+	b.WriteString("\n")
+}
+
+// writeTextPairs renders a titled list of phrases and their translations
+func writeTextPairs(b *strings.Builder, title string, pairs []Pair) {
+	if len(pairs) == 0 {
+		return
+	}
+
+	fmt.Fprintf(b, "\n  %s:\n", title)
+	for _, pair := range pairs {
+		text := pair.Swedish
+		if pair.Description != "" {
+			text += " (" + pair.Description + ")"
+		}
+		if pair.Inflection != "" {
+			text += " " + pair.Inflection
+		}
+		if pair.Translation != "" {
+			text += " - " + pair.Translation
+		}
+		fmt.Fprintf(b, "    %s\n", text)
+	}
+}
+
+// nonEmpty returns the arguments that are not blank
+func nonEmpty(values ...string) []string {
+	var result []string
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
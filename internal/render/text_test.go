@@ -0,0 +1,156 @@
+package render
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"lexin-sqlite/pkg/lexin"
+)
+
+// linkedEntry has two base languages whose examples share the ID 10. The
+// links pair each with the TargetLang at its position, which MatchingID
+// alone cannot.
+func linkedEntry() lexin.Entry {
+	return lexin.Entry{
+		Value: "hus",
+		BaseLangs: []lexin.BaseLang{
+			{
+				ID:                  1,
+				MatchedTargetLangID: 11,
+				Examples:            []lexin.Example{{RowID: 101, ID: "10", Content: "ett stort hus", MatchedID: 201}},
+				Idioms:              []lexin.Idiom{{RowID: 102, ID: "20", Content: "hålla hus", MatchedID: 202}},
+				Compounds:           []lexin.Compound{{RowID: 103, ID: "30", Content: "hus~bil", MatchedID: 203}},
+				Derivations:         []lexin.Derivation{{Content: "hus~lig"}},
+			},
+			{
+				ID:                  2,
+				MatchedTargetLangID: 12,
+				Examples:            []lexin.Example{{RowID: 104, ID: "10", Content: "hela huset", MatchedID: 204}},
+			},
+		},
+		TargetLangs: []lexin.TargetLang{
+			{
+				ID:           11,
+				Translations: []string{"house"},
+				Examples:     []lexin.Example{{RowID: 201, MatchingID: "10", Content: "a big house", MatchedID: 101}},
+				Idioms:       []lexin.Idiom{{RowID: 202, MatchingID: "20", Content: "keep house", MatchedID: 102}},
+				Compounds:    []lexin.Compound{{RowID: 203, MatchingID: "30", Content: "camper", MatchedID: 103}},
+				Derivations:  []lexin.Derivation{{Content: "domestic"}},
+			},
+			{
+				ID:           12,
+				Translations: []string{"household"},
+				Examples:     []lexin.Example{{RowID: 204, MatchingID: "10", Content: "the whole household", MatchedID: 104}},
+			},
+		},
+	}
+}
+
+// unlinked returns entry as read from a database that was not linked
+func unlinked(entry lexin.Entry) lexin.Entry {
+	var out lexin.Entry
+	out.Value = entry.Value
+	for _, base := range entry.BaseLangs {
+		base.MatchedTargetLangID = 0
+		base.Examples = append([]lexin.Example(nil), base.Examples...)
+		for i := range base.Examples {
+			base.Examples[i].MatchedID = 0
+		}
+		base.Idioms = append([]lexin.Idiom(nil), base.Idioms...)
+		for i := range base.Idioms {
+			base.Idioms[i].MatchedID = 0
+		}
+		base.Compounds = append([]lexin.Compound(nil), base.Compounds...)
+		for i := range base.Compounds {
+			base.Compounds[i].MatchedID = 0
+		}
+		out.BaseLangs = append(out.BaseLangs, base)
+	}
+	out.TargetLangs = entry.TargetLangs
+	return out
+}
+
+func TestPairsFollowLinks(t *testing.T) {
+	entry := linkedEntry()
+	first, second := entry.BaseLangs[0], entry.BaseLangs[1]
+
+	tests := []struct {
+		name string
+		got  []Pair
+		want []Pair
+	}{
+		{"first examples", Examples(entry, first), []Pair{{Swedish: "ett stort hus", Translation: "a big house"}}},
+		{"second examples", Examples(entry, second), []Pair{{Swedish: "hela huset", Translation: "the whole household"}}},
+		{"idioms", Idioms(entry, first), []Pair{{Swedish: "hålla hus", Translation: "keep house"}}},
+		{"compounds", Compounds(entry, first), []Pair{{Swedish: "hus~bil", Translation: "camper"}}},
+		{"derivations", Derivations(entry, first, 0), []Pair{{Swedish: "hus~lig", Translation: "domestic"}}},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("%s = %+v, want %+v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestPairsWithoutLinks(t *testing.T) {
+	entry := unlinked(linkedEntry())
+
+	// Without links the MatchingID is compared, and the last target
+	// example with a MatchingID wins
+	got := Examples(entry, entry.BaseLangs[0])
+	want := []Pair{{Swedish: "ett stort hus", Translation: "the whole household"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("examples = %+v, want %+v", got, want)
+	}
+	if got := Compounds(entry, entry.BaseLangs[0]); got[0].Translation != "camper" {
+		t.Errorf("compound translation = %q, want camper", got[0].Translation)
+	}
+}
+
+func TestTarget(t *testing.T) {
+	entry := linkedEntry()
+
+	// The link wins over the position
+	swapped := entry
+	swapped.BaseLangs = []lexin.BaseLang{entry.BaseLangs[1], entry.BaseLangs[0]}
+	if target, ok := Target(swapped, swapped.BaseLangs[0], 0); !ok || target.ID != 12 {
+		t.Errorf("Target = %d, %v, want 12", target.ID, ok)
+	}
+
+	// Unlinked base languages use the position
+	plain := unlinked(entry)
+	if target, ok := Target(plain, plain.BaseLangs[1], 1); !ok || target.ID != 12 {
+		t.Errorf("Target = %d, %v, want 12", target.ID, ok)
+	}
+	if _, ok := Target(plain, lexin.BaseLang{}, 2); ok {
+		t.Error("Target found a target language past the end")
+	}
+}
+
+func TestReferencesLeaveOutMediaFiles(t *testing.T) {
+	entry := lexin.Entry{
+		Value: "hus",
+		BaseLangs: []lexin.BaseLang{{
+			References: []lexin.Reference{
+				{Type: "see", Value: "bostad"},
+				{Type: "compare", Value: "stuga"},
+				{Type: "animation", Value: "hus.swf"},
+				{Type: "phonetic", Value: "hus.mp3"},
+			},
+		}},
+	}
+
+	for name, out := range map[string]string{"Text": Text(entry), "HTML": HTML(entry)} {
+		for _, want := range []string{"See also: bostad", "See also: stuga"} {
+			if !strings.Contains(out, want) {
+				t.Errorf("%s lacks %q:\n%s", name, want, out)
+			}
+		}
+		for _, file := range []string{"hus.swf", "hus.mp3"} {
+			if strings.Contains(out, file) {
+				t.Errorf("%s shows the media file %s:\n%s", name, file, out)
+			}
+		}
+	}
+}
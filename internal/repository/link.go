@@ -0,0 +1,362 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+
+	"lexin-sqlite/internal/parser"
+)
+
+// Kinds of links resolved by LinkDictionary
+const (
+	LinkReference = "reference"
+	LinkAntonym   = "antonym"
+	LinkMeaning   = "meaning"
+	LinkExample   = "example"
+	LinkIdiom     = "idiom"
+	LinkCompound  = "compound"
+)
+
+// LinkReport describes the outcome of the linking pass over a dictionary
+type LinkReport struct {
+	BaseLang   string           `json:"base_lang"`
+	TargetLang string           `json:"target_lang"`
+	Resolved   int              `json:"resolved"`
+	Unresolved []UnresolvedLink `json:"unresolved"`
+}
+
+// UnresolvedLink is a reference, antonym or MatchingID that leads nowhere,
+// or a meaning of a translated word that has no TargetLang at its position
+type UnresolvedLink struct {
+	Kind      string `json:"kind"`
+	ID        string `json:"id"`
+	VariantID string `json:"variant_id"`
+	Word      string `json:"word"`
+	// Value is the headword or MatchingID that was not found, or the
+	// untranslated meaning
+	Value string `json:"value"`
+}
+
+// WriteSummary writes a human readable summary of the report
+func (l *LinkReport) WriteSummary(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "Dictionary %s to %s: %d links resolved, %d unresolved\n", l.BaseLang, l.TargetLang, l.Resolved, len(l.Unresolved))
+	if err != nil {
+		return err
+	}
+
+	for _, link := range l.Unresolved {
+		_, err := fmt.Fprintf(w, "  %s %q in %s [%s/%s]\n", link.Kind, link.Value, link.Word, link.ID, link.VariantID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// headwordOf finds the word of dictionary ?1 a reference or antonym value,
+// c.value, names: a headword, or else a headword or a word with an
+// inflected form equal to the value lower cased with parser.NormalizeForm,
+// read from the link_forms table
+const headwordOf = `COALESCE(
+	(SELECT w.id FROM words w
+		WHERE w.dictionary_id = ?1 AND w.value = c.value
+		ORDER BY w.id LIMIT 1),
+	(SELECT w.id FROM words w
+		WHERE w.dictionary_id = ?1
+			AND w.value = (SELECT l.form FROM temp.link_forms l WHERE l.value = c.value)
+		ORDER BY w.id LIMIT 1),
+	(SELECT w.id FROM word_forms f JOIN words w ON w.id = f.word_id
+		WHERE w.dictionary_id = ?1
+			AND f.form = (SELECT l.form FROM temp.link_forms l WHERE l.value = c.value)
+		ORDER BY w.id LIMIT 1)
+)`
+
+// matchedOf finds the base language element whose ID the MatchingID of a
+// target language element, c, names within the same word. An element of
+// the BaseLang at the same position as the TargetLang is preferred.
+func matchedOf(table string) string {
+	return fmt.Sprintf(`(
+		SELECT b.id FROM %s b
+		JOIN base_langs bl ON bl.id = b.base_lang_id
+		JOIN target_langs tl ON tl.word_id = bl.word_id
+		WHERE tl.id = c.target_lang_id AND b.original_id = c.matching_id
+		ORDER BY bl.position <> tl.position, bl.position, b.position, b.id
+		LIMIT 1
+	)`, table)
+}
+
+// targetRows selects the elements of a target language that carry a
+// MatchingID, aliased c, with their word w
+func targetRows(table string) string {
+	return fmt.Sprintf(`%s c
+		JOIN target_langs t ON t.id = c.target_lang_id
+		JOIN words w ON w.id = t.word_id
+		WHERE w.dictionary_id = ?1 AND c.matching_id <> ''`, table)
+}
+
+// backLink points the base language elements of dictionary ?1 at the target
+// language element matched with them
+func backLink(table, column string) string {
+	return fmt.Sprintf(`
+		UPDATE %[1]s AS c SET %[2]s = (
+			SELECT t.id FROM %[1]s t
+			WHERE t.%[2]s = c.id AND t.target_lang_id IS NOT NULL
+			ORDER BY t.id LIMIT 1
+		)
+		WHERE c.base_lang_id IN (
+			SELECT b.id FROM base_langs b JOIN words w ON w.id = b.word_id
+			WHERE w.dictionary_id = ?1
+		)`, table, column)
+}
+
+// links are the foreign keys set by the linking pass
+var links = []struct {
+	kind   string
+	table  string
+	column string
+	// rows selects the rows of dictionary ?1 that carry a link, aliased c,
+	// with their word w
+	rows string
+	// target computes the id the link of row c points at
+	target string
+	// unresolved selects the rows reported when left without a link, rows
+	// when empty
+	unresolved string
+	// positional links pair rows by position rather than resolve text
+	// naming another row, so they are not counted as resolved
+	positional bool
+	// value is the column of c shown for unresolved links
+	value string
+	// after runs once the link is set, to fill the opposite direction
+	after string
+}{
+	{
+		kind:   LinkReference,
+		table:  "word_references",
+		column: "target_word_id",
+		// animation and phonetic references name files, not words
+		rows: `word_references c
+			JOIN base_langs b ON b.id = c.base_lang_id
+			JOIN words w ON w.id = b.word_id
+			WHERE w.dictionary_id = ?1 AND c.type IN ('see', 'compare')`,
+		target: headwordOf,
+		value:  "value",
+	},
+	{
+		kind:   LinkAntonym,
+		table:  "antonyms",
+		column: "target_word_id",
+		// Antonyms of a TargetLang are in the target language, which has
+		// no headwords in this dictionary
+		rows: `antonyms c
+			JOIN base_langs b ON b.id = c.base_lang_id
+			JOIN words w ON w.id = b.word_id
+			WHERE w.dictionary_id = ?1`,
+		target: headwordOf,
+		value:  "value",
+	},
+	{
+		kind:   LinkMeaning,
+		table:  "base_langs",
+		column: "matched_target_lang_id",
+		// TargetLang elements carry no IDs, so a meaning is matched with
+		// the TargetLang at its position, as the exports pair them. Words
+		// without any TargetLang are untranslated rather than mismatched,
+		// so only the meanings of a word with fewer TargetLang than
+		// BaseLang elements are reported.
+		rows: `base_langs c
+			JOIN words w ON w.id = c.word_id
+			WHERE w.dictionary_id = ?1`,
+		unresolved: `base_langs c
+			JOIN words w ON w.id = c.word_id
+			WHERE w.dictionary_id = ?1
+				AND EXISTS (SELECT 1 FROM target_langs t WHERE t.word_id = c.word_id)`,
+		positional: true,
+		target: `(
+			SELECT t.id FROM target_langs t
+			WHERE t.word_id = c.word_id AND t.position = c.position
+			ORDER BY t.id LIMIT 1
+		)`,
+		value: "meaning",
+	},
+	{
+		kind:   LinkExample,
+		table:  "examples",
+		column: "matched_example_id",
+		rows:   targetRows("examples"),
+		target: matchedOf("examples"),
+		value:  "matching_id",
+		after:  backLink("examples", "matched_example_id"),
+	},
+	{
+		kind:   LinkIdiom,
+		table:  "idioms",
+		column: "matched_idiom_id",
+		rows:   targetRows("idioms"),
+		target: matchedOf("idioms"),
+		value:  "matching_id",
+		after:  backLink("idioms", "matched_idiom_id"),
+	},
+	{
+		kind:   LinkCompound,
+		table:  "compounds",
+		column: "matched_compound_id",
+		rows:   targetRows("compounds"),
+		target: matchedOf("compounds"),
+		value:  "matching_id",
+		after:  backLink("compounds", "matched_compound_id"),
+	},
+}
+
+// LinkDictionary resolves the text that points at other rows into
+// foreign keys: the words named by references and antonyms, the
+// TargetLang of each meaning, and the base language examples, idioms and
+// compounds whose ID the MatchingID of a target language one names, in
+// both directions. Links are resolved again from scratch on every run, so
+// it runs after each import. It returns the links that could not be
+// resolved.
+func (r *Repository) LinkDictionary(ctx context.Context, dictionaryID int64) (*LinkReport, error) {
+	report := &LinkReport{Unresolved: []UnresolvedLink{}}
+
+	err := r.db.RunInTransaction(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRow(`SELECT base_lang, target_lang FROM dictionaries WHERE id = ?`, dictionaryID).
+			Scan(&report.BaseLang, &report.TargetLang)
+		if err != nil {
+			return fmt.Errorf("failed to read dictionary: %w", err)
+		}
+
+		// SQLite's lower() only folds ASCII, so values are lower cased
+		// here the way word_forms is filled
+		if err := createLinkForms(tx, dictionaryID); err != nil {
+			return fmt.Errorf("failed to normalise link values: %w", err)
+		}
+		defer tx.Exec(`DROP TABLE IF EXISTS temp.link_forms`)
+
+		for _, link := range links {
+			_, err := tx.Exec(fmt.Sprintf(`
+				UPDATE %s AS c SET %s = %s
+				WHERE c.id IN (SELECT c.id FROM %s)
+			`, link.table, link.column, link.target, link.rows), dictionaryID)
+			if err != nil {
+				return fmt.Errorf("failed to link %ss: %w", link.kind, err)
+			}
+
+			if link.after != "" {
+				if _, err := tx.Exec(link.after, dictionaryID); err != nil {
+					return fmt.Errorf("failed to link %ss: %w", link.kind, err)
+				}
+			}
+
+			if !link.positional {
+				var resolved int
+				err = tx.QueryRow(`SELECT COUNT(*) FROM `+link.rows+` AND c.`+link.column+` IS NOT NULL`, dictionaryID).
+					Scan(&resolved)
+				if err != nil {
+					return fmt.Errorf("failed to count %s links: %w", link.kind, err)
+				}
+				report.Resolved += resolved
+			}
+
+			unresolved := link.unresolved
+			if unresolved == "" {
+				unresolved = link.rows
+			}
+			if err := loadUnresolved(tx, dictionaryID, link.kind, unresolved, link.column, link.value, report); err != nil {
+				return fmt.Errorf("failed to read unresolved %s links: %w", link.kind, err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// createLinkForms fills the temporary link_forms table with the reference
+// and antonym values of dictionary dictionaryID and their normalised forms
+func createLinkForms(tx *sql.Tx, dictionaryID int64) error {
+	_, err := tx.Exec(`
+		CREATE TEMP TABLE IF NOT EXISTS link_forms (
+			value TEXT PRIMARY KEY,
+			form TEXT NOT NULL
+		) WITHOUT ROWID;
+		DELETE FROM temp.link_forms;
+	`)
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(`
+		SELECT c.value FROM word_references c
+		JOIN base_langs b ON b.id = c.base_lang_id
+		JOIN words w ON w.id = b.word_id
+		WHERE w.dictionary_id = ?1
+		UNION
+		SELECT c.value FROM antonyms c
+		JOIN base_langs b ON b.id = c.base_lang_id
+		JOIN words w ON w.id = b.word_id
+		WHERE w.dictionary_id = ?1
+	`, dictionaryID)
+	if err != nil {
+		return err
+	}
+	var values []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			rows.Close()
+			return err
+		}
+		values = append(values, value)
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(`INSERT INTO temp.link_forms (value, form) VALUES (?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, value := range values {
+		if _, err := stmt.Exec(value, parser.NormalizeForm(value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadUnresolved adds the rows left without a link to the report
+func loadUnresolved(tx *sql.Tx, dictionaryID int64, kind, rows, column, value string, report *LinkReport) error {
+	result, err := tx.Query(`
+		SELECT w.original_id, w.variant_id, w.value, c.`+value+`
+		FROM `+rows+` AND c.`+column+` IS NULL
+		ORDER BY w.id, c.id
+	`, dictionaryID)
+	if err != nil {
+		return err
+	}
+	defer result.Close()
+
+	for result.Next() {
+		link := UnresolvedLink{Kind: kind}
+		var linkValue sql.NullString
+		if err := result.Scan(&link.ID, &link.VariantID, &link.Word, &linkValue); err != nil {
+			return err
+		}
+		link.Value = linkValue.String
+		report.Unresolved = append(report.Unresolved, link)
+	}
+
+	return result.Err()
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"lexin-sqlite/internal/parser"
+)
+
+func TestLinkReportsOnlyMismatchedMeanings(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	repo := New(db)
+
+	dict, err := parser.ParseXML(strings.NewReader(`<Dictionary BaseLang="swe" TargetLang="eng" Version="1">
+<Word Value="hus" Type="subst." ID="1" VariantID="1">
+  <BaseLang><Meaning>byggnad</Meaning></BaseLang>
+  <BaseLang><Meaning>hushåll</Meaning></BaseLang>
+  <TargetLang><Translation>house</Translation></TargetLang>
+</Word>
+<Word Value="bil" Type="subst." ID="2" VariantID="1">
+  <BaseLang><Meaning>fordon</Meaning></BaseLang>
+  <TargetLang><Translation>car</Translation></TargetLang>
+</Word>
+<Word Value="stuga" Type="subst." ID="3" VariantID="1">
+  <BaseLang><Meaning>litet hus</Meaning></BaseLang>
+</Word>
+</Dictionary>`))
+	if err != nil {
+		t.Fatalf("failed to parse XML: %v", err)
+	}
+	if _, err := repo.UpsertDictionary(ctx, dict); err != nil {
+		t.Fatalf("failed to import: %v", err)
+	}
+
+	report, err := repo.LinkDictionary(ctx, 1)
+	if err != nil {
+		t.Fatalf("LinkDictionary failed: %v", err)
+	}
+
+	// The second meaning of hus has no TargetLang; stuga is untranslated
+	// as a whole and not reported
+	want := []UnresolvedLink{{Kind: LinkMeaning, ID: "1", VariantID: "1", Word: "hus", Value: "hushåll"}}
+	if !reflect.DeepEqual(report.Unresolved, want) {
+		t.Errorf("unresolved = %+v, want %+v", report.Unresolved, want)
+	}
+	if report.Resolved != 0 {
+		t.Errorf("resolved = %d, want 0 for positional meaning links", report.Resolved)
+	}
+
+	var matched int
+	err = db.GetDB().QueryRow(`SELECT COUNT(*) FROM base_langs WHERE matched_target_lang_id IS NOT NULL`).Scan(&matched)
+	if err != nil {
+		t.Fatalf("failed to count meaning links: %v", err)
+	}
+	if matched != 2 {
+		t.Errorf("%d meanings matched with a TargetLang, want 2", matched)
+	}
+}
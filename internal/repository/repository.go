@@ -4,9 +4,11 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"io"
 	"log"
 
 	"lexin-sqlite/internal/database"
+	"lexin-sqlite/internal/fuzzy"
 	"lexin-sqlite/internal/parser"
 )
 
@@ -22,45 +24,103 @@ func New(db *database.DB) *Repository {
 
 // StoreDictionary stores a dictionary in the database
 func (r *Repository) StoreDictionary(ctx context.Context, dict *parser.Dictionary) error {
-	return r.db.RunInTransaction(ctx, func(tx *sql.Tx) error {
-		// Check if dictionary already exists
-		dictID, _, _, _, err := r.db.GetDictionaryByLanguages(ctx, dict.BaseLang, dict.TargetLang)
-		if err != nil && err != sql.ErrNoRows {
-			return fmt.Errorf("failed to check if dictionary exists: %w", err)
+	_, err := r.StoreSource(ctx, dict.Source())
+	return err
+}
+
+// StoreSource stores words read one at a time from src, so memory use does
+// not depend on the size of the dictionary. It returns the number of words
+// stored.
+func (r *Repository) StoreSource(ctx context.Context, src parser.Source) (int, error) {
+	header := src.Header()
+	count := 0
+
+	err := r.db.RunInTransaction(ctx, func(tx *sql.Tx) error {
+		dictID, created, err := ensureDictionary(tx, header)
+		if err != nil {
+			return err
+		}
+		if !created {
+			log.Printf("Dictionary %s to %s already exists, adding entries", header.BaseLang, header.TargetLang)
 		}
 
-		if err == sql.ErrNoRows {
-			// Create dictionary
-			var err error
-			dictID, err = r.db.CreateDictionary(ctx, dict.BaseLang, dict.TargetLang, dict.Version)
+		// Process each word
+		for {
+			word, err := src.Next()
+			if err == io.EOF {
+				break
+			}
 			if err != nil {
-				return fmt.Errorf("failed to create dictionary: %w", err)
+				return err
 			}
-		} else {
-			log.Printf("Dictionary %s to %s already exists, adding/updating entries", dict.BaseLang, dict.TargetLang)
-		}
 
-		// Process each word
-		for i, word := range dict.Words {
-			if i > 0 && i%1000 == 0 {
-				log.Printf("Processed %d words...", i)
+			if count > 0 && count%1000 == 0 {
+				log.Printf("Processed %d words...", count)
 			}
 
-			if err := storeWord(tx, dictID, word); err != nil {
+			if err := storeWord(tx, dictID, *word); err != nil {
 				return fmt.Errorf("failed to store word %s: %w", word.Value, err)
 			}
+			count++
 		}
 
 		return nil
 	})
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// ensureDictionary returns the id of the dictionary described by header,
+// creating it if needed. The boolean reports whether it was created. Both
+// statements run on tx, so a failed import leaves no empty dictionary
+// behind.
+func ensureDictionary(tx *sql.Tx, header parser.Header) (int64, bool, error) {
+	// Check if dictionary already exists
+	var dictID int64
+	err := tx.QueryRow(`
+		SELECT id
+		FROM dictionaries
+		WHERE base_lang = ? AND target_lang = ?
+		LIMIT 1
+	`, header.BaseLang, header.TargetLang).Scan(&dictID)
+	if err == nil {
+		return dictID, false, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, false, fmt.Errorf("failed to check if dictionary exists: %w", err)
+	}
+
+	// Create dictionary
+	result, err := tx.Exec(`
+		INSERT INTO dictionaries (base_lang, target_lang, version)
+		VALUES (?, ?, ?)
+	`, header.BaseLang, header.TargetLang, header.Version)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to create dictionary: %w", err)
+	}
+
+	dictID, err = result.LastInsertId()
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to create dictionary: %w", err)
+	}
+
+	return dictID, true, nil
 }
 
 // storeWord stores a word and its related data
 func storeWord(tx *sql.Tx, dictionaryID int64, word parser.Word) error {
+	checksum, err := wordChecksum(word)
+	if err != nil {
+		return err
+	}
+
 	// Insert word
 	wordStmt, err := tx.Prepare(`
-		INSERT INTO words (dictionary_id, value, variant, type, original_id, variant_id, matching_id)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO words (dictionary_id, value, variant, type, original_id, variant_id, matching_id, checksum)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 	`)
 	if err != nil {
 		return err
@@ -75,6 +135,7 @@ func storeWord(tx *sql.Tx, dictionaryID int64, word parser.Word) error {
 		word.ID,
 		word.VariantID,
 		nullString(word.MatchingID),
+		checksum,
 	)
 	if err != nil {
 		return err
@@ -85,29 +146,133 @@ func storeWord(tx *sql.Tx, dictionaryID int64, word parser.Word) error {
 		return err
 	}
 
+	return storeWordChildren(tx, wordID, word)
+}
+
+// storeWordChildren stores the base and target language entries of a word
+func storeWordChildren(tx *sql.Tx, wordID int64, word parser.Word) error {
 	// Process base language entries
-	for _, baseLang := range word.BaseLangs {
-		if err := storeBaseLang(tx, wordID, baseLang); err != nil {
+	for position, baseLang := range word.BaseLangs {
+		if err := storeBaseLang(tx, wordID, position, baseLang); err != nil {
 			return fmt.Errorf("failed to store base language: %w", err)
 		}
 	}
 
 	// Process target language entries
-	for _, targetLang := range word.TargetLang {
-		if err := storeTargetLang(tx, wordID, targetLang); err != nil {
+	for position, targetLang := range word.TargetLang {
+		if err := storeTargetLang(tx, wordID, position, targetLang); err != nil {
 			return fmt.Errorf("failed to store target language: %w", err)
 		}
 	}
 
+	if err := storeWordForms(tx, wordID, word); err != nil {
+		return fmt.Errorf("failed to store inflected forms: %w", err)
+	}
+
+	if err := storeWordTrigrams(tx, wordID, word.Value); err != nil {
+		return fmt.Errorf("failed to store trigrams: %w", err)
+	}
+
+	if err := storeWordDeletes(tx, wordID, word.Value); err != nil {
+		return fmt.Errorf("failed to store deletions: %w", err)
+	}
+
 	return nil
 }
 
-// storeBaseLang stores a BaseLang entry and its related data
-func storeBaseLang(tx *sql.Tx, wordID int64, baseLang parser.BaseLang) error {
+// storeWordTrigrams indexes the headword for typo tolerant suggestions
+func storeWordTrigrams(tx *sql.Tx, wordID int64, value string) error {
+	trigrams := fuzzy.Trigrams(value)
+	if len(trigrams) == 0 {
+		return nil
+	}
+
+	trigramStmt, err := tx.Prepare(`
+		INSERT OR IGNORE INTO word_trigrams (trigram, word_id)
+		VALUES (?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+	defer trigramStmt.Close()
+
+	for _, trigram := range trigrams {
+		if _, err := trigramStmt.Exec(trigram, wordID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// storeWordDeletes indexes a short headword by its one letter deletions,
+// for suggestions the trigrams cannot find
+func storeWordDeletes(tx *sql.Tx, wordID int64, value string) error {
+	if len([]rune(fuzzy.Fold(value))) > fuzzy.DeleteIndexLength {
+		return nil
+	}
+	deletes := fuzzy.Deletes(value)
+	if len(deletes) == 0 {
+		return nil
+	}
+
+	deleteStmt, err := tx.Prepare(`
+		INSERT OR IGNORE INTO word_deletes (variant, word_id)
+		VALUES (?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+	defer deleteStmt.Close()
+
+	for _, variant := range deletes {
+		if _, err := deleteStmt.Exec(variant, wordID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// storeWordForms indexes the inflected, compound and derived forms of a word
+// so that lookups can resolve them to the headword
+func storeWordForms(tx *sql.Tx, wordID int64, word parser.Word) error {
+	forms := parser.WordForms(word)
+	if len(forms) == 0 {
+		return nil
+	}
+
+	formStmt, err := tx.Prepare(`
+		INSERT OR IGNORE INTO word_forms (word_id, form, source, description)
+		VALUES (?, ?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+	defer formStmt.Close()
+
+	for _, form := range forms {
+		_, err = formStmt.Exec(
+			wordID,
+			form.Value,
+			form.Source,
+			nullString(form.Description),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// storeBaseLang stores a BaseLang entry at position in its word and its
+// related data
+func storeBaseLang(tx *sql.Tx, wordID int64, position int, baseLang parser.BaseLang) error {
 	// Insert base language
 	baseLangStmt, err := tx.Prepare(`
-		INSERT INTO base_langs (word_id, meaning, matching_id)
-		VALUES (?, ?, ?)
+		INSERT INTO base_langs (word_id, meaning, matching_id, position)
+		VALUES (?, ?, ?, ?)
 	`)
 	if err != nil {
 		return err
@@ -118,6 +283,7 @@ func storeBaseLang(tx *sql.Tx, wordID int64, baseLang parser.BaseLang) error {
 		wordID,
 		nullString(baseLang.Meaning.Content),
 		nullString(baseLang.Meaning.MatchingID),
+		position,
 	)
 	if err != nil {
 		return err
@@ -131,20 +297,21 @@ func storeBaseLang(tx *sql.Tx, wordID int64, baseLang parser.BaseLang) error {
 	// Store word_references
 	if len(baseLang.References) > 0 {
 		refStmt, err := tx.Prepare(`
-			INSERT INTO word_references (base_lang_id, type, value, matching_id)
-			VALUES (?, ?, ?, ?)
+			INSERT INTO word_references (base_lang_id, type, value, matching_id, position)
+			VALUES (?, ?, ?, ?, ?)
 		`)
 		if err != nil {
 			return err
 		}
 		defer refStmt.Close()
 
-		for _, ref := range baseLang.References {
+		for position, ref := range baseLang.References {
 			_, err = refStmt.Exec(
 				baseLangID,
 				ref.Type,
 				ref.Value,
 				nullString(ref.MatchingID),
+				position,
 			)
 			if err != nil {
 				return err
@@ -155,19 +322,20 @@ func storeBaseLang(tx *sql.Tx, wordID int64, baseLang parser.BaseLang) error {
 	// Store comments
 	if len(baseLang.Comments) > 0 {
 		commentStmt, err := tx.Prepare(`
-			INSERT INTO comments (base_lang_id, content, matching_id)
-			VALUES (?, ?, ?)
+			INSERT INTO comments (base_lang_id, content, matching_id, position)
+			VALUES (?, ?, ?, ?)
 		`)
 		if err != nil {
 			return err
 		}
 		defer commentStmt.Close()
 
-		for _, comment := range baseLang.Comments {
+		for position, comment := range baseLang.Comments {
 			_, err = commentStmt.Exec(
 				baseLangID,
 				comment.Content,
 				nullString(comment.MatchingID),
+				position,
 			)
 			if err != nil {
 				return err
@@ -178,19 +346,20 @@ func storeBaseLang(tx *sql.Tx, wordID int64, baseLang parser.BaseLang) error {
 	// Store explanations
 	if len(baseLang.Explanations) > 0 {
 		explStmt, err := tx.Prepare(`
-			INSERT INTO explanations (base_lang_id, content, matching_id)
-			VALUES (?, ?, ?)
+			INSERT INTO explanations (base_lang_id, content, matching_id, position)
+			VALUES (?, ?, ?, ?)
 		`)
 		if err != nil {
 			return err
 		}
 		defer explStmt.Close()
 
-		for _, expl := range baseLang.Explanations {
+		for position, expl := range baseLang.Explanations {
 			_, err = explStmt.Exec(
 				baseLangID,
 				expl.Content,
 				nullString(expl.MatchingID),
+				position,
 			)
 			if err != nil {
 				return err
@@ -201,18 +370,19 @@ func storeBaseLang(tx *sql.Tx, wordID int64, baseLang parser.BaseLang) error {
 	// Store alternates
 	if len(baseLang.Alternates) > 0 {
 		altStmt, err := tx.Prepare(`
-			INSERT INTO alternates (base_lang_id, content)
-			VALUES (?, ?)
+			INSERT INTO alternates (base_lang_id, content, position)
+			VALUES (?, ?, ?)
 		`)
 		if err != nil {
 			return err
 		}
 		defer altStmt.Close()
 
-		for _, alt := range baseLang.Alternates {
+		for position, alt := range baseLang.Alternates {
 			_, err = altStmt.Exec(
 				baseLangID,
 				alt.Content,
+				position,
 			)
 			if err != nil {
 				return err
@@ -223,18 +393,19 @@ func storeBaseLang(tx *sql.Tx, wordID int64, baseLang parser.BaseLang) error {
 	// Store antonyms
 	if len(baseLang.Antonyms) > 0 {
 		antStmt, err := tx.Prepare(`
-			INSERT INTO antonyms (base_lang_id, value)
-			VALUES (?, ?)
+			INSERT INTO antonyms (base_lang_id, value, position)
+			VALUES (?, ?, ?)
 		`)
 		if err != nil {
 			return err
 		}
 		defer antStmt.Close()
 
-		for _, ant := range baseLang.Antonyms {
+		for position, ant := range baseLang.Antonyms {
 			_, err = antStmt.Exec(
 				baseLangID,
 				ant.Value,
+				position,
 			)
 			if err != nil {
 				return err
@@ -245,19 +416,20 @@ func storeBaseLang(tx *sql.Tx, wordID int64, baseLang parser.BaseLang) error {
 	// Store usages
 	if len(baseLang.Usages) > 0 {
 		usageStmt, err := tx.Prepare(`
-			INSERT INTO usages (base_lang_id, content, matching_id)
-			VALUES (?, ?, ?)
+			INSERT INTO usages (base_lang_id, content, matching_id, position)
+			VALUES (?, ?, ?, ?)
 		`)
 		if err != nil {
 			return err
 		}
 		defer usageStmt.Close()
 
-		for _, usage := range baseLang.Usages {
+		for position, usage := range baseLang.Usages {
 			_, err = usageStmt.Exec(
 				baseLangID,
 				usage.Content,
 				nullString(usage.MatchingID),
+				position,
 			)
 			if err != nil {
 				return err
@@ -283,20 +455,21 @@ func storeBaseLang(tx *sql.Tx, wordID int64, baseLang parser.BaseLang) error {
 	// Store illustrations
 	if len(baseLang.Illustrations) > 0 {
 		illStmt, err := tx.Prepare(`
-			INSERT INTO illustrations (base_lang_id, type, value, norlexin)
-			VALUES (?, ?, ?, ?)
+			INSERT INTO illustrations (base_lang_id, type, value, norlexin, position)
+			VALUES (?, ?, ?, ?, ?)
 		`)
 		if err != nil {
 			return err
 		}
 		defer illStmt.Close()
 
-		for _, ill := range baseLang.Illustrations {
+		for position, ill := range baseLang.Illustrations {
 			_, err = illStmt.Exec(
 				baseLangID,
 				ill.Type,
 				ill.Value,
 				nullString(ill.Norlexin),
+				position,
 			)
 			if err != nil {
 				return err
@@ -307,8 +480,8 @@ func storeBaseLang(tx *sql.Tx, wordID int64, baseLang parser.BaseLang) error {
 	// Store inflections
 	if len(baseLang.Inflections) > 0 {
 		inflStmt, err := tx.Prepare(`
-			INSERT INTO inflections (base_lang_id, content)
-			VALUES (?, ?)
+			INSERT INTO inflections (base_lang_id, content, position)
+			VALUES (?, ?, ?)
 		`)
 		if err != nil {
 			return err
@@ -316,18 +489,19 @@ func storeBaseLang(tx *sql.Tx, wordID int64, baseLang parser.BaseLang) error {
 		defer inflStmt.Close()
 
 		variantStmt, err := tx.Prepare(`
-			INSERT INTO inflection_variants (inflection_id, content, description)
-			VALUES (?, ?, ?)
+			INSERT INTO inflection_variants (inflection_id, content, description, position)
+			VALUES (?, ?, ?, ?)
 		`)
 		if err != nil {
 			return err
 		}
 		defer variantStmt.Close()
 
-		for _, infl := range baseLang.Inflections {
+		for position, infl := range baseLang.Inflections {
 			inflResult, err := inflStmt.Exec(
 				baseLangID,
 				nullString(infl.Content),
+				position,
 			)
 			if err != nil {
 				return err
@@ -339,11 +513,12 @@ func storeBaseLang(tx *sql.Tx, wordID int64, baseLang parser.BaseLang) error {
 			}
 
 			// Store variants
-			for _, variant := range infl.Variants {
+			for variantPosition, variant := range infl.Variants {
 				_, err = variantStmt.Exec(
 					inflID,
 					variant.Content,
 					nullString(variant.Description),
+					variantPosition,
 				)
 				if err != nil {
 					return err
@@ -369,20 +544,21 @@ func storeBaseLang(tx *sql.Tx, wordID int64, baseLang parser.BaseLang) error {
 	// Store examples
 	if len(baseLang.Examples) > 0 {
 		exampleStmt, err := tx.Prepare(`
-			INSERT INTO examples (base_lang_id, content, original_id, matching_id)
-			VALUES (?, ?, ?, ?)
+			INSERT INTO examples (base_lang_id, content, original_id, matching_id, position)
+			VALUES (?, ?, ?, ?, ?)
 		`)
 		if err != nil {
 			return err
 		}
 		defer exampleStmt.Close()
 
-		for _, example := range baseLang.Examples {
+		for position, example := range baseLang.Examples {
 			_, err = exampleStmt.Exec(
 				baseLangID,
 				example.Content,
 				example.ID,
 				nullString(example.MatchingID),
+				position,
 			)
 			if err != nil {
 				return err
@@ -393,20 +569,21 @@ func storeBaseLang(tx *sql.Tx, wordID int64, baseLang parser.BaseLang) error {
 	// Store idioms
 	if len(baseLang.Idioms) > 0 {
 		idiomStmt, err := tx.Prepare(`
-			INSERT INTO idioms (base_lang_id, content, original_id, matching_id)
-			VALUES (?, ?, ?, ?)
+			INSERT INTO idioms (base_lang_id, content, original_id, matching_id, position)
+			VALUES (?, ?, ?, ?, ?)
 		`)
 		if err != nil {
 			return err
 		}
 		defer idiomStmt.Close()
 
-		for _, idiom := range baseLang.Idioms {
+		for position, idiom := range baseLang.Idioms {
 			_, err = idiomStmt.Exec(
 				baseLangID,
 				idiom.Content,
 				idiom.ID,
 				nullString(idiom.MatchingID),
+				position,
 			)
 			if err != nil {
 				return err
@@ -417,8 +594,8 @@ func storeBaseLang(tx *sql.Tx, wordID int64, baseLang parser.BaseLang) error {
 	// Store compounds
 	if len(baseLang.Compounds) > 0 {
 		compoundStmt, err := tx.Prepare(`
-			INSERT INTO compounds (base_lang_id, content, original_id, description, matching_id)
-			VALUES (?, ?, ?, ?, ?)
+			INSERT INTO compounds (base_lang_id, content, original_id, description, matching_id, position)
+			VALUES (?, ?, ?, ?, ?, ?)
 		`)
 		if err != nil {
 			return err
@@ -434,13 +611,14 @@ func storeBaseLang(tx *sql.Tx, wordID int64, baseLang parser.BaseLang) error {
 		}
 		defer inflStmt.Close()
 
-		for _, compound := range baseLang.Compounds {
+		for position, compound := range baseLang.Compounds {
 			compoundResult, err := compoundStmt.Exec(
 				baseLangID,
 				nullString(compound.Content),
 				compound.ID,
 				nullString(compound.Description),
 				nullString(compound.MatchingID),
+				position,
 			)
 			if err != nil {
 				return err
@@ -466,8 +644,8 @@ func storeBaseLang(tx *sql.Tx, wordID int64, baseLang parser.BaseLang) error {
 	// Store derivations
 	if len(baseLang.Derivations) > 0 {
 		derivationStmt, err := tx.Prepare(`
-			INSERT INTO derivations (base_lang_id, content, original_id, description)
-			VALUES (?, ?, ?, ?)
+			INSERT INTO derivations (base_lang_id, content, original_id, description, position)
+			VALUES (?, ?, ?, ?, ?)
 		`)
 		if err != nil {
 			return err
@@ -483,12 +661,13 @@ func storeBaseLang(tx *sql.Tx, wordID int64, baseLang parser.BaseLang) error {
 		}
 		defer inflStmt.Close()
 
-		for _, derivation := range baseLang.Derivations {
+		for position, derivation := range baseLang.Derivations {
 			derivationResult, err := derivationStmt.Exec(
 				baseLangID,
 				nullString(derivation.Content),
 				derivation.ID,
 				nullString(derivation.Description),
+				position,
 			)
 			if err != nil {
 				return err
@@ -514,19 +693,20 @@ func storeBaseLang(tx *sql.Tx, wordID int64, baseLang parser.BaseLang) error {
 	// Store indexes
 	if len(baseLang.Indexes) > 0 {
 		indexStmt, err := tx.Prepare(`
-			INSERT INTO indexes (base_lang_id, value, type)
-			VALUES (?, ?, ?)
+			INSERT INTO indexes (base_lang_id, value, type, position)
+			VALUES (?, ?, ?, ?)
 		`)
 		if err != nil {
 			return err
 		}
 		defer indexStmt.Close()
 
-		for _, index := range baseLang.Indexes {
+		for position, index := range baseLang.Indexes {
 			_, err = indexStmt.Exec(
 				baseLangID,
 				index.Value,
 				nullString(index.Type),
+				position,
 			)
 			if err != nil {
 				return err
@@ -537,12 +717,13 @@ func storeBaseLang(tx *sql.Tx, wordID int64, baseLang parser.BaseLang) error {
 	return nil
 }
 
-// storeTargetLang stores a TargetLang entry and its related data
-func storeTargetLang(tx *sql.Tx, wordID int64, targetLang parser.TargetLang) error {
+// storeTargetLang stores a TargetLang entry at position in its word and its
+// related data
+func storeTargetLang(tx *sql.Tx, wordID int64, position int, targetLang parser.TargetLang) error {
 	// Insert target language
 	targetLangStmt, err := tx.Prepare(`
-		INSERT INTO target_langs (word_id, comment)
-		VALUES (?, ?)
+		INSERT INTO target_langs (word_id, comment, position)
+		VALUES (?, ?, ?)
 	`)
 	if err != nil {
 		return err
@@ -552,6 +733,7 @@ func storeTargetLang(tx *sql.Tx, wordID int64, targetLang parser.TargetLang) err
 	targetLangResult, err := targetLangStmt.Exec(
 		wordID,
 		nullString(targetLang.Comment),
+		position,
 	)
 	if err != nil {
 		return err
@@ -562,77 +744,134 @@ func storeTargetLang(tx *sql.Tx, wordID int64, targetLang parser.TargetLang) err
 		return err
 	}
 
-	// Store translation
-	if targetLang.Translation != "" {
-		_, err = tx.Exec(`
-			INSERT INTO translations (target_lang_id, content)
-			VALUES (?, ?)
-		`,
-			targetLangID,
-			targetLang.Translation,
-		)
+	// Store translations, numbering the non-empty ones
+	if len(targetLang.Translations) > 0 {
+		translationStmt, err := tx.Prepare(`
+			INSERT INTO translations (target_lang_id, content, position)
+			VALUES (?, ?, ?)
+		`)
 		if err != nil {
 			return err
 		}
+		defer translationStmt.Close()
+
+		position := 0
+		for _, value := range targetLang.Translations {
+			if value == "" {
+				continue
+			}
+			_, err = translationStmt.Exec(
+				targetLangID,
+				value,
+				position,
+			)
+			if err != nil {
+				return err
+			}
+			position++
+		}
 	}
 
-	// Store synonym
-	if targetLang.Synonym != "" {
-		_, err = tx.Exec(`
-			INSERT INTO synonyms (target_lang_id, content)
-			VALUES (?, ?)
-		`,
-			targetLangID,
-			targetLang.Synonym,
-		)
+	// Store synonyms, numbering the non-empty ones
+	if len(targetLang.Synonyms) > 0 {
+		synonymStmt, err := tx.Prepare(`
+			INSERT INTO synonyms (target_lang_id, content, position)
+			VALUES (?, ?, ?)
+		`)
 		if err != nil {
 			return err
 		}
+		defer synonymStmt.Close()
+
+		position := 0
+		for _, value := range targetLang.Synonyms {
+			if value == "" {
+				continue
+			}
+			_, err = synonymStmt.Exec(
+				targetLangID,
+				value,
+				position,
+			)
+			if err != nil {
+				return err
+			}
+			position++
+		}
 	}
 
-	// Store comment
-	if targetLang.CommentElem != "" {
-		_, err = tx.Exec(`
-			INSERT INTO target_comments (target_lang_id, content)
-			VALUES (?, ?)
-		`,
-			targetLangID,
-			targetLang.CommentElem,
-		)
+	// Store comments, numbering the non-empty ones
+	if len(targetLang.CommentElems) > 0 {
+		commentStmt, err := tx.Prepare(`
+			INSERT INTO target_comments (target_lang_id, content, position)
+			VALUES (?, ?, ?)
+		`)
 		if err != nil {
 			return err
 		}
+		defer commentStmt.Close()
+
+		position := 0
+		for _, value := range targetLang.CommentElems {
+			if value == "" {
+				continue
+			}
+			_, err = commentStmt.Exec(
+				targetLangID,
+				value,
+				position,
+			)
+			if err != nil {
+				return err
+			}
+			position++
+		}
 	}
 
-	// Store explanation
-	if targetLang.Explanation != "" {
-		_, err = tx.Exec(`
-			INSERT INTO target_explanations (target_lang_id, content)
-			VALUES (?, ?)
-		`,
-			targetLangID,
-			targetLang.Explanation,
-		)
+	// Store explanations, numbering the non-empty ones
+	if len(targetLang.Explanations) > 0 {
+		explanationStmt, err := tx.Prepare(`
+			INSERT INTO target_explanations (target_lang_id, content, position)
+			VALUES (?, ?, ?)
+		`)
 		if err != nil {
 			return err
 		}
+		defer explanationStmt.Close()
+
+		position := 0
+		for _, value := range targetLang.Explanations {
+			if value == "" {
+				continue
+			}
+			_, err = explanationStmt.Exec(
+				targetLangID,
+				value,
+				position,
+			)
+			if err != nil {
+				return err
+			}
+			position++
+		}
 	}
 
 	// Store antonyms
 	if len(targetLang.Antonyms) > 0 {
 		antStmt, err := tx.Prepare(`
-			INSERT INTO antonyms (target_lang_id, value)
-			VALUES (?, ?)
+			INSERT INTO antonyms (target_lang_id, value, position)
+			VALUES (?, ?, ?)
 		`)
 		if err != nil {
 			return err
 		}
 		defer antStmt.Close()
 
-		for _, ant := range targetLang.Antonyms {
+		for position, ant := range targetLang.Antonyms {
 			_, err = antStmt.Exec(
 				targetLangID,
 				ant.Value,
+				position,
 			)
 			if err != nil {
 				return err
@@ -643,20 +882,21 @@ func storeTargetLang(tx *sql.Tx, wordID int64, targetLang parser.TargetLang) err
 	// Store examples
 	if len(targetLang.Examples) > 0 {
 		exampleStmt, err := tx.Prepare(`
-			INSERT INTO examples (target_lang_id, content, original_id, matching_id)
-			VALUES (?, ?, ?, ?)
+			INSERT INTO examples (target_lang_id, content, original_id, matching_id, position)
+			VALUES (?, ?, ?, ?, ?)
 		`)
 		if err != nil {
 			return err
 		}
 		defer exampleStmt.Close()
 
-		for _, example := range targetLang.Examples {
+		for position, example := range targetLang.Examples {
 			_, err = exampleStmt.Exec(
 				targetLangID,
 				example.Content,
 				example.ID,
 				nullString(example.MatchingID),
+				position,
 			)
 			if err != nil {
 				return err
@@ -667,20 +907,21 @@ func storeTargetLang(tx *sql.Tx, wordID int64, targetLang parser.TargetLang) err
 	// Store idioms
 	if len(targetLang.Idioms) > 0 {
 		idiomStmt, err := tx.Prepare(`
-			INSERT INTO idioms (target_lang_id, content, original_id, matching_id)
-			VALUES (?, ?, ?, ?)
+			INSERT INTO idioms (target_lang_id, content, original_id, matching_id, position)
+			VALUES (?, ?, ?, ?, ?)
 		`)
 		if err != nil {
 			return err
 		}
 		defer idiomStmt.Close()
 
-		for _, idiom := range targetLang.Idioms {
+		for position, idiom := range targetLang.Idioms {
 			_, err = idiomStmt.Exec(
 				targetLangID,
 				idiom.Content,
 				idiom.ID,
 				nullString(idiom.MatchingID),
+				position,
 			)
 			if err != nil {
 				return err
@@ -691,8 +932,8 @@ func storeTargetLang(tx *sql.Tx, wordID int64, targetLang parser.TargetLang) err
 	// Store compounds
 	if len(targetLang.Compounds) > 0 {
 		compoundStmt, err := tx.Prepare(`
-			INSERT INTO compounds (target_lang_id, content, original_id, description, matching_id)
-			VALUES (?, ?, ?, ?, ?)
+			INSERT INTO compounds (target_lang_id, content, original_id, description, matching_id, position)
+			VALUES (?, ?, ?, ?, ?, ?)
 		`)
 		if err != nil {
 			return err
@@ -708,13 +949,14 @@ func storeTargetLang(tx *sql.Tx, wordID int64, targetLang parser.TargetLang) err
 		}
 		defer inflStmt.Close()
 
-		for _, compound := range targetLang.Compounds {
+		for position, compound := range targetLang.Compounds {
 			compoundResult, err := compoundStmt.Exec(
 				targetLangID,
 				nullString(compound.Content),
 				compound.ID,
 				nullString(compound.Description),
 				nullString(compound.MatchingID),
+				position,
 			)
 			if err != nil {
 				return err
@@ -740,8 +982,8 @@ func storeTargetLang(tx *sql.Tx, wordID int64, targetLang parser.TargetLang) err
 	// Store derivations
 	if len(targetLang.Derivations) > 0 {
 		derivationStmt, err := tx.Prepare(`
-			INSERT INTO derivations (target_lang_id, content, original_id, description)
-			VALUES (?, ?, ?, ?)
+			INSERT INTO derivations (target_lang_id, content, original_id, description, position)
+			VALUES (?, ?, ?, ?, ?)
 		`)
 		if err != nil {
 			return err
@@ -757,12 +999,13 @@ func storeTargetLang(tx *sql.Tx, wordID int64, targetLang parser.TargetLang) err
 		}
 		defer inflStmt.Close()
 
-		for _, derivation := range targetLang.Derivations {
+		for position, derivation := range targetLang.Derivations {
 			derivationResult, err := derivationStmt.Exec(
 				targetLangID,
 				nullString(derivation.Content),
 				derivation.ID,
 				nullString(derivation.Description),
+				position,
 			)
 			if err != nil {
 				return err
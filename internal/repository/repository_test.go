@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"lexin-sqlite/internal/parser"
+)
+
+func TestStoreTargetLangNumbersStoredTexts(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+
+	dict, err := parser.ParseXML(strings.NewReader(`<Dictionary BaseLang="swe" TargetLang="eng" Version="1">
+<Word Value="hus" Type="subst." ID="1" VariantID="1">
+  <TargetLang>
+    <Translation></Translation>
+    <Translation>house</Translation>
+    <Translation/>
+    <Translation>building</Translation>
+    <Synonym>home</Synonym>
+    <Synonym></Synonym>
+    <Synonym>dwelling</Synonym>
+    <Comment/>
+    <Comment>informal</Comment>
+    <Explanation>a place to live</Explanation>
+  </TargetLang>
+</Word>
+</Dictionary>`))
+	if err != nil {
+		t.Fatalf("failed to parse XML: %v", err)
+	}
+	if err := New(db).StoreDictionary(ctx, dict); err != nil {
+		t.Fatalf("failed to store: %v", err)
+	}
+
+	for _, tt := range []struct {
+		table string
+		want  []string
+	}{
+		{"translations", []string{"0 house", "1 building"}},
+		{"synonyms", []string{"0 home", "1 dwelling"}},
+		{"target_comments", []string{"0 informal"}},
+		{"target_explanations", []string{"0 a place to live"}},
+	} {
+		rows, err := db.GetDB().Query(`SELECT position || ' ' || content FROM ` + tt.table + ` ORDER BY position`)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", tt.table, err)
+		}
+		var got []string
+		for rows.Next() {
+			var row string
+			if err := rows.Scan(&row); err != nil {
+				t.Fatalf("failed to read %s: %v", tt.table, err)
+			}
+			got = append(got, row)
+		}
+		rows.Close()
+
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s = %q, want %q", tt.table, got, tt.want)
+		}
+	}
+}
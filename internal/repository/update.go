@@ -0,0 +1,230 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+
+	"lexin-sqlite/internal/parser"
+)
+
+// ChangeReport describes the differences applied by an update
+type ChangeReport struct {
+	BaseLang    string       `json:"base_lang"`
+	TargetLang  string       `json:"target_lang"`
+	FromVersion string       `json:"from_version"`
+	ToVersion   string       `json:"to_version"`
+	Added       []WordChange `json:"added"`
+	Changed     []WordChange `json:"changed"`
+	Removed     []WordChange `json:"removed"`
+	Unchanged   int          `json:"unchanged"`
+	// Repeated lists the words skipped because the source already held a
+	// word with the same ID and VariantID
+	Repeated []WordChange `json:"repeated"`
+}
+
+// WordChange identifies a word that was added, changed or removed
+type WordChange struct {
+	ID        string `json:"id"`
+	VariantID string `json:"variant_id"`
+	Value     string `json:"value"`
+	Type      string `json:"type"`
+}
+
+// WriteSummary writes a human readable summary of the report
+func (c *ChangeReport) WriteSummary(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "Dictionary %s to %s: version %s -> %s\n", c.BaseLang, c.TargetLang, c.FromVersion, c.ToVersion)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "  %d added, %d changed, %d removed, %d unchanged\n", len(c.Added), len(c.Changed), len(c.Removed), c.Unchanged)
+	if err != nil {
+		return err
+	}
+	if len(c.Repeated) > 0 {
+		if _, err := fmt.Fprintf(w, "  %d repeated in the source and skipped\n", len(c.Repeated)); err != nil {
+			return err
+		}
+	}
+
+	sections := []struct {
+		sign  string
+		words []WordChange
+	}{
+		{"+", c.Added},
+		{"~", c.Changed},
+		{"-", c.Removed},
+		{"!", c.Repeated},
+	}
+	for _, section := range sections {
+		for _, word := range section.words {
+			_, err := fmt.Fprintf(w, "  %s %s (%s) [%s/%s]\n", section.sign, word.Value, word.Type, word.ID, word.VariantID)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// UpdateDictionary brings the stored dictionary in line with dict
+func (r *Repository) UpdateDictionary(ctx context.Context, dict *parser.Dictionary) (*ChangeReport, error) {
+	return r.UpdateSource(ctx, dict.Source())
+}
+
+// UpdateSource compares the words read from src with the stored version of
+// the same dictionary. New words are added, changed words are replaced,
+// words missing from src are deleted, and the dictionary version is set to
+// the one in src. Unchanged words are not touched, and a word whose ID and
+// VariantID were already read from src is skipped and reported as
+// repeated.
+func (r *Repository) UpdateSource(ctx context.Context, src parser.Source) (*ChangeReport, error) {
+	header := src.Header()
+	report := &ChangeReport{
+		BaseLang:   header.BaseLang,
+		TargetLang: header.TargetLang,
+		ToVersion:  header.Version,
+		Added:      []WordChange{},
+		Changed:    []WordChange{},
+		Removed:    []WordChange{},
+		Repeated:   []WordChange{},
+	}
+
+	err := r.db.RunInTransaction(ctx, func(tx *sql.Tx) error {
+		dictID, created, err := ensureDictionary(tx, header)
+		if err != nil {
+			return err
+		}
+
+		if !created {
+			err := tx.QueryRow(`SELECT version FROM dictionaries WHERE id = ?`, dictID).Scan(&report.FromVersion)
+			if err != nil {
+				return fmt.Errorf("failed to read dictionary version: %w", err)
+			}
+		}
+
+		stored, err := loadWordKeys(tx, dictID)
+		if err != nil {
+			return err
+		}
+
+		seen := make(map[wordKey]bool)
+		processed := 0
+		for {
+			word, err := src.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+
+			if processed > 0 && processed%1000 == 0 {
+				log.Printf("Processed %d words...", processed)
+			}
+			processed++
+
+			change := WordChange{ID: word.ID, VariantID: word.VariantID, Value: word.Value, Type: word.Type}
+			if repeatedWord(seen, *word) {
+				report.Repeated = append(report.Repeated, change)
+				continue
+			}
+
+			outcome, err := upsertWord(tx, dictID, *word)
+			if err != nil {
+				return fmt.Errorf("failed to update word %s: %w", word.Value, err)
+			}
+
+			switch outcome {
+			case wordInserted:
+				report.Added = append(report.Added, change)
+			case wordReplaced:
+				report.Changed = append(report.Changed, change)
+			default:
+				report.Unchanged++
+			}
+		}
+
+		// Delete words that are no longer in the source
+		for key, word := range stored {
+			if seen[key] {
+				continue
+			}
+			if _, err := tx.Exec(`DELETE FROM words WHERE id = ?`, word.id); err != nil {
+				return fmt.Errorf("failed to delete word %s: %w", word.value, err)
+			}
+			report.Removed = append(report.Removed, WordChange{
+				ID:        key.originalID,
+				VariantID: key.variantID,
+				Value:     word.value,
+				Type:      word.wordType,
+			})
+		}
+		sort.Slice(report.Removed, func(i, j int) bool {
+			return report.Removed[i].Value < report.Removed[j].Value
+		})
+
+		_, err = tx.Exec(`UPDATE dictionaries SET version = ? WHERE id = ?`, header.Version, dictID)
+		if err != nil {
+			return fmt.Errorf("failed to update dictionary version: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// wordKey identifies a word within a dictionary
+type wordKey struct {
+	originalID string
+	variantID  string
+}
+
+// storedWord is the part of a stored word needed to report its removal
+type storedWord struct {
+	id       int64
+	value    string
+	wordType string
+}
+
+// loadWordKeys loads the keys of every word stored for a dictionary
+func loadWordKeys(tx *sql.Tx, dictionaryID int64) (map[wordKey]storedWord, error) {
+	rows, err := tx.Query(`
+		SELECT id, original_id, variant_id, value, type
+		FROM words
+		WHERE dictionary_id = ?
+		ORDER BY id
+	`, dictionaryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load stored words: %w", err)
+	}
+	defer rows.Close()
+
+	words := make(map[wordKey]storedWord)
+	for rows.Next() {
+		var key wordKey
+		var word storedWord
+		if err := rows.Scan(&word.id, &key.originalID, &key.variantID, &word.value, &word.wordType); err != nil {
+			return nil, fmt.Errorf("failed to load stored words: %w", err)
+		}
+		// Keep the oldest row for keys stored more than once, the same
+		// row findWord keeps
+		if _, ok := words[key]; !ok {
+			words[key] = word
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load stored words: %w", err)
+	}
+
+	return words, nil
+}
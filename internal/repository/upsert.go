@@ -0,0 +1,239 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+
+	"lexin-sqlite/internal/parser"
+)
+
+// UpsertStats summarises the outcome of an upsert import
+type UpsertStats struct {
+	Inserted  int
+	Replaced  int
+	Unchanged int
+	// Repeated counts the words skipped because the source already held
+	// a word with the same ID and VariantID
+	Repeated int
+}
+
+// Total returns the number of words read from the source
+func (s *UpsertStats) Total() int {
+	return s.Inserted + s.Replaced + s.Unchanged + s.Repeated
+}
+
+// UpsertDictionary stores a dictionary, replacing words that already exist
+func (r *Repository) UpsertDictionary(ctx context.Context, dict *parser.Dictionary) (*UpsertStats, error) {
+	return r.UpsertSource(ctx, dict.Source())
+}
+
+// UpsertSource stores words read from src keyed on their Lexin ID and
+// VariantID. A word that is already stored with the same content is left
+// alone, a word whose content changed has its subtree replaced in place, and
+// a new word is inserted. Importing the same file twice leaves the database
+// unchanged. A word whose ID and VariantID were already read from src is
+// skipped with a warning, so that the first occurrence is kept.
+func (r *Repository) UpsertSource(ctx context.Context, src parser.Source) (*UpsertStats, error) {
+	header := src.Header()
+	stats := &UpsertStats{}
+
+	err := r.db.RunInTransaction(ctx, func(tx *sql.Tx) error {
+		dictID, _, err := ensureDictionary(tx, header)
+		if err != nil {
+			return err
+		}
+
+		seen := make(map[wordKey]bool)
+		for {
+			word, err := src.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+
+			if n := stats.Total(); n > 0 && n%1000 == 0 {
+				log.Printf("Processed %d words...", n)
+			}
+
+			if repeatedWord(seen, *word) {
+				stats.Repeated++
+				continue
+			}
+
+			outcome, err := upsertWord(tx, dictID, *word)
+			if err != nil {
+				return fmt.Errorf("failed to upsert word %s: %w", word.Value, err)
+			}
+
+			switch outcome {
+			case wordInserted:
+				stats.Inserted++
+			case wordReplaced:
+				stats.Replaced++
+			case wordUnchanged:
+				stats.Unchanged++
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// repeatedWord reports whether a word with the same ID and VariantID as
+// word was seen earlier in the same source, warning about it, and records
+// word as seen
+func repeatedWord(seen map[wordKey]bool, word parser.Word) bool {
+	key := wordKey{word.ID, word.VariantID}
+	if seen[key] {
+		log.Printf("Warning: word %s [%s/%s] is repeated in the source, keeping the first one", word.Value, word.ID, word.VariantID)
+		return true
+	}
+	seen[key] = true
+	return false
+}
+
+// upsertOutcome tells what upsertWord did with a word
+type upsertOutcome int
+
+const (
+	wordInserted upsertOutcome = iota
+	wordReplaced
+	wordUnchanged
+)
+
+// upsertWord stores a word keyed on (dictionary_id, original_id, variant_id)
+func upsertWord(tx *sql.Tx, dictionaryID int64, word parser.Word) (upsertOutcome, error) {
+	wordID, storedChecksum, found, err := findWord(tx, dictionaryID, word.ID, word.VariantID)
+	if err != nil {
+		return 0, err
+	}
+
+	if !found {
+		return wordInserted, storeWord(tx, dictionaryID, word)
+	}
+
+	checksum, err := wordChecksum(word)
+	if err != nil {
+		return 0, err
+	}
+
+	if storedChecksum == checksum {
+		return wordUnchanged, nil
+	}
+
+	return wordReplaced, replaceWord(tx, wordID, checksum, word)
+}
+
+// findWord looks up a stored word by its Lexin key. Databases written before
+// upserts existed may hold the same key several times; the extra copies are
+// removed so that only the oldest row remains.
+func findWord(tx *sql.Tx, dictionaryID int64, originalID, variantID string) (int64, string, bool, error) {
+	rows, err := tx.Query(`
+		SELECT id, checksum
+		FROM words
+		WHERE dictionary_id = ? AND original_id = ? AND variant_id = ?
+		ORDER BY id
+	`, dictionaryID, originalID, variantID)
+	if err != nil {
+		return 0, "", false, err
+	}
+
+	var (
+		wordID   int64
+		checksum sql.NullString
+		extraIDs []int64
+		found    bool
+	)
+	for rows.Next() {
+		var id int64
+		var sum sql.NullString
+		if err := rows.Scan(&id, &sum); err != nil {
+			rows.Close()
+			return 0, "", false, err
+		}
+		if !found {
+			wordID, checksum, found = id, sum, true
+			continue
+		}
+		extraIDs = append(extraIDs, id)
+	}
+	if err := rows.Close(); err != nil {
+		return 0, "", false, err
+	}
+	if err := rows.Err(); err != nil {
+		return 0, "", false, err
+	}
+
+	for _, id := range extraIDs {
+		if _, err := tx.Exec(`DELETE FROM words WHERE id = ?`, id); err != nil {
+			return 0, "", false, err
+		}
+	}
+
+	return wordID, checksum.String, found, nil
+}
+
+// replaceWord updates a stored word and rebuilds its subtree, keeping the
+// word's row id stable
+func replaceWord(tx *sql.Tx, wordID int64, checksum string, word parser.Word) error {
+	_, err := tx.Exec(`
+		UPDATE words
+		SET value = ?, variant = ?, type = ?, matching_id = ?, checksum = ?
+		WHERE id = ?
+	`,
+		word.Value,
+		nullString(word.Variant),
+		word.Type,
+		nullString(word.MatchingID),
+		checksum,
+		wordID,
+	)
+	if err != nil {
+		return err
+	}
+
+	// Child rows of base_langs and target_langs go with them through
+	// ON DELETE CASCADE
+	if _, err := tx.Exec(`DELETE FROM base_langs WHERE word_id = ?`, wordID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM target_langs WHERE word_id = ?`, wordID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM word_forms WHERE word_id = ?`, wordID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM word_trigrams WHERE word_id = ?`, wordID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM word_deletes WHERE word_id = ?`, wordID); err != nil {
+		return err
+	}
+
+	return storeWordChildren(tx, wordID, word)
+}
+
+// wordChecksum returns a digest of everything parsed for a word, used to
+// detect whether a stored word changed
+func wordChecksum(word parser.Word) (string, error) {
+	data, err := json.Marshal(word)
+	if err != nil {
+		return "", fmt.Errorf("failed to compute checksum: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
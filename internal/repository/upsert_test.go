@@ -0,0 +1,267 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"lexin-sqlite/internal/database"
+	"lexin-sqlite/internal/parser"
+)
+
+const testXML = `<?xml version="1.0" encoding="UTF-8"?>
+<Dictionary BaseLang="swe" TargetLang="eng" Version="%s">
+<Word Value="hus" Variant="" Type="subst." ID="100" VariantID="1">
+  <BaseLang>
+    <Meaning MatchingID="m1">byggnad för boende</Meaning>
+    <Reference TYPE="see" VALUE="bostad"/>
+    <Inflection>huset</Inflection>
+    <Example ID="501">bo i ett stort hus</Example>
+    <Index Value="hus" type="%s"/>
+  </BaseLang>
+  <TargetLang>
+    <Translation>house</Translation>
+    <Example MatchingID="501">live in a big house</Example>
+  </TargetLang>
+</Word>
+<Word Value="bostad" Variant="" Type="subst." ID="300" VariantID="1">
+  <BaseLang>
+    <Meaning>ställe där man bor</Meaning>
+  </BaseLang>
+  <TargetLang>
+    <Translation>dwelling</Translation>
+  </TargetLang>
+</Word>
+</Dictionary>
+`
+
+// openTestDB creates a migrated database in a temporary directory
+func openTestDB(t *testing.T) *database.DB {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), "lexin.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// parseTestXML parses testXML with the given version and Index type
+func parseTestXML(t *testing.T, version, indexType string) *parser.Dictionary {
+	t.Helper()
+	dict, err := parser.ParseXML(strings.NewReader(fmt.Sprintf(testXML, version, indexType)))
+	if err != nil {
+		t.Fatalf("failed to parse XML: %v", err)
+	}
+	return dict
+}
+
+// dumpTables reads every row of every table, keyed on the table name
+func dumpTables(t *testing.T, db *database.DB) map[string][]string {
+	t.Helper()
+	ctx := context.Background()
+
+	rows, err := db.GetDB().QueryContext(ctx, `SELECT name FROM sqlite_master WHERE type = 'table' ORDER BY name`)
+	if err != nil {
+		t.Fatalf("failed to list tables: %v", err)
+	}
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatalf("failed to list tables: %v", err)
+		}
+		tables = append(tables, name)
+	}
+	rows.Close()
+
+	dump := make(map[string][]string)
+	for _, table := range tables {
+		rows, err := db.GetDB().QueryContext(ctx, `SELECT * FROM "`+table+`"`)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", table, err)
+		}
+		columns, err := rows.Columns()
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", table, err)
+		}
+		for rows.Next() {
+			values := make([]any, len(columns))
+			pointers := make([]any, len(columns))
+			for i := range values {
+				pointers[i] = &values[i]
+			}
+			if err := rows.Scan(pointers...); err != nil {
+				t.Fatalf("failed to read %s: %v", table, err)
+			}
+			dump[table] = append(dump[table], fmt.Sprint(values...))
+		}
+		rows.Close()
+	}
+	return dump
+}
+
+// countDictionaries returns the number of stored dictionaries
+func countDictionaries(t *testing.T, db *database.DB) int {
+	t.Helper()
+	var count int
+	if err := db.GetDB().QueryRow(`SELECT COUNT(*) FROM dictionaries`).Scan(&count); err != nil {
+		t.Fatalf("failed to count dictionaries: %v", err)
+	}
+	return count
+}
+
+func TestUpsertTwiceLeavesDatabaseUnchanged(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	repo := New(db)
+
+	stats, err := repo.UpsertDictionary(ctx, parseTestXML(t, "1.0", "prefix"))
+	if err != nil {
+		t.Fatalf("first import failed: %v", err)
+	}
+	if stats.Inserted != 2 {
+		t.Fatalf("first import inserted %d words, want 2", stats.Inserted)
+	}
+	before := dumpTables(t, db)
+
+	stats, err = repo.UpsertDictionary(ctx, parseTestXML(t, "1.0", "prefix"))
+	if err != nil {
+		t.Fatalf("second import failed: %v", err)
+	}
+	if *stats != (UpsertStats{Unchanged: 2}) {
+		t.Errorf("second import = %+v, want 2 unchanged words", *stats)
+	}
+
+	after := dumpTables(t, db)
+	if !reflect.DeepEqual(before, after) {
+		for table := range before {
+			if !reflect.DeepEqual(before[table], after[table]) {
+				t.Errorf("table %s changed:\nbefore %v\nafter  %v", table, before[table], after[table])
+			}
+		}
+	}
+}
+
+func TestFailedImportLeavesNoDictionary(t *testing.T) {
+	ctx := context.Background()
+
+	for _, mode := range []struct {
+		name  string
+		store func(*Repository, *parser.Dictionary) error
+	}{
+		{"append", func(r *Repository, d *parser.Dictionary) error { return r.StoreDictionary(ctx, d) }},
+		{"upsert", func(r *Repository, d *parser.Dictionary) error { _, err := r.UpsertDictionary(ctx, d); return err }},
+		{"update", func(r *Repository, d *parser.Dictionary) error { _, err := r.UpdateDictionary(ctx, d); return err }},
+	} {
+		t.Run(mode.name, func(t *testing.T) {
+			db := openTestDB(t)
+
+			// The indexes table rejects the Index type, which fails
+			// the import after the dictionary is looked up
+			if err := mode.store(New(db), parseTestXML(t, "1.0", "infix")); err == nil {
+				t.Fatal("import with an invalid Index type succeeded")
+			}
+			if n := countDictionaries(t, db); n != 0 {
+				t.Errorf("failed import left %d dictionaries", n)
+			}
+		})
+	}
+}
+
+func TestFailedUpdateKeepsVersion(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	repo := New(db)
+
+	if _, err := repo.UpdateDictionary(ctx, parseTestXML(t, "1.0", "prefix")); err != nil {
+		t.Fatalf("first update failed: %v", err)
+	}
+	if _, err := repo.UpdateDictionary(ctx, parseTestXML(t, "2.0", "infix")); err == nil {
+		t.Fatal("update with an invalid Index type succeeded")
+	}
+
+	var version string
+	if err := db.GetDB().QueryRow(`SELECT version FROM dictionaries`).Scan(&version); err != nil {
+		t.Fatalf("failed to read version: %v", err)
+	}
+	if version != "1.0" {
+		t.Errorf("version = %q after a failed update, want 1.0", version)
+	}
+}
+
+// repeatedXML holds the word 100/1 twice with different meanings
+const repeatedXML = `<Dictionary BaseLang="swe" TargetLang="eng" Version="1">
+<Word Value="hus" Type="subst." ID="100" VariantID="1">
+  <BaseLang><Meaning>byggnad</Meaning></BaseLang>
+</Word>
+<Word Value="bil" Type="subst." ID="200" VariantID="1"/>
+<Word Value="hus" Type="subst." ID="100" VariantID="1">
+  <BaseLang><Meaning>hushåll</Meaning></BaseLang>
+</Word>
+</Dictionary>`
+
+func TestRepeatedWordsKeepTheFirst(t *testing.T) {
+	ctx := context.Background()
+
+	meanings := func(t *testing.T, db *database.DB) []string {
+		t.Helper()
+		rows, err := db.GetDB().Query(`SELECT meaning FROM base_langs ORDER BY id`)
+		if err != nil {
+			t.Fatalf("failed to read meanings: %v", err)
+		}
+		defer rows.Close()
+		var values []string
+		for rows.Next() {
+			var value string
+			if err := rows.Scan(&value); err != nil {
+				t.Fatalf("failed to read meanings: %v", err)
+			}
+			values = append(values, value)
+		}
+		return values
+	}
+	parse := func(t *testing.T) *parser.Dictionary {
+		t.Helper()
+		dict, err := parser.ParseXML(strings.NewReader(repeatedXML))
+		if err != nil {
+			t.Fatalf("failed to parse XML: %v", err)
+		}
+		return dict
+	}
+
+	t.Run("upsert", func(t *testing.T) {
+		db := openTestDB(t)
+		stats, err := New(db).UpsertDictionary(ctx, parse(t))
+		if err != nil {
+			t.Fatalf("import failed: %v", err)
+		}
+		if *stats != (UpsertStats{Inserted: 2, Repeated: 1}) {
+			t.Errorf("stats = %+v, want 2 inserted and 1 repeated", *stats)
+		}
+		if got := meanings(t, db); !reflect.DeepEqual(got, []string{"byggnad"}) {
+			t.Errorf("meanings = %q, want the first word kept", got)
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		db := openTestDB(t)
+		report, err := New(db).UpdateDictionary(ctx, parse(t))
+		if err != nil {
+			t.Fatalf("update failed: %v", err)
+		}
+		if len(report.Added) != 2 || len(report.Changed) != 0 {
+			t.Errorf("added %d and changed %d words, want 2 and 0", len(report.Added), len(report.Changed))
+		}
+		want := []WordChange{{ID: "100", VariantID: "1", Value: "hus", Type: "subst."}}
+		if !reflect.DeepEqual(report.Repeated, want) {
+			t.Errorf("repeated = %+v, want %+v", report.Repeated, want)
+		}
+		if got := meanings(t, db); !reflect.DeepEqual(got, []string{"byggnad"}) {
+			t.Errorf("meanings = %q, want the first word kept", got)
+		}
+	})
+}
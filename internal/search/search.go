@@ -0,0 +1,182 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"lexin-sqlite/internal/database"
+)
+
+// Sources that can be searched
+const (
+	SourceMeaning     = "meaning"
+	SourceExample     = "example"
+	SourceIdiom       = "idiom"
+	SourceExplanation = "explanation"
+	SourceTranslation = "translation"
+)
+
+// Hit is a single full-text match together with the word it belongs to
+type Hit struct {
+	WordID       int64   `json:"word_id"`
+	DictionaryID int64   `json:"dictionary_id"`
+	Word         string  `json:"word"`
+	WordType     string  `json:"word_type"`
+	Source       string  `json:"source"`
+	Snippet      string  `json:"snippet"`
+	Rank         float64 `json:"rank"`
+}
+
+// Options narrows a search
+type Options struct {
+	// DictionaryID restricts hits to one dictionary when non-zero
+	DictionaryID int64
+	// Sources restricts hits to the given sources; all sources when empty
+	Sources []string
+	// Limit caps the number of hits, 20 when zero
+	Limit int
+	// Offset skips the first hits for pagination
+	Offset int
+	// HighlightStart and HighlightEnd surround matched terms in snippets,
+	// "[" and "]" when empty
+	HighlightStart string
+	HighlightEnd   string
+}
+
+// Searcher runs full-text queries over meanings, examples, idioms,
+// explanations and translations
+type Searcher struct {
+	db *database.DB
+}
+
+// New creates a new searcher
+func New(db *database.DB) *Searcher {
+	return &Searcher{db: db}
+}
+
+// source describes how to get from an FTS table back to the owning word
+type source struct {
+	name  string
+	fts   string
+	table string
+	// join links the content table, aliased c, to words w
+	join string
+}
+
+// ownerJoin joins a table that hangs off either base_langs or target_langs
+const ownerJoin = `
+	LEFT JOIN base_langs b ON b.id = c.base_lang_id
+	LEFT JOIN target_langs t ON t.id = c.target_lang_id
+	JOIN words w ON w.id = COALESCE(b.word_id, t.word_id)`
+
+var sources = []source{
+	{SourceMeaning, "base_langs_fts", "base_langs", `JOIN words w ON w.id = c.word_id`},
+	{SourceExample, "examples_fts", "examples", ownerJoin},
+	{SourceIdiom, "idioms_fts", "idioms", ownerJoin},
+	{SourceExplanation, "explanations_fts", "explanations", `
+	JOIN base_langs b ON b.id = c.base_lang_id
+	JOIN words w ON w.id = b.word_id`},
+	{SourceTranslation, "translations_fts", "translations", `
+	JOIN target_langs t ON t.id = c.target_lang_id
+	JOIN words w ON w.id = t.word_id`},
+}
+
+// Search returns hits for query ordered by relevance. Every whitespace
+// separated term must match; a term ending in * matches as a prefix.
+func (s *Searcher) Search(ctx context.Context, query string, opts Options) ([]Hit, error) {
+	match := ftsQuery(query)
+	if match == "" {
+		return []Hit{}, nil
+	}
+
+	if opts.Limit <= 0 {
+		opts.Limit = 20
+	}
+	if opts.HighlightStart == "" {
+		opts.HighlightStart = "["
+	}
+	if opts.HighlightEnd == "" {
+		opts.HighlightEnd = "]"
+	}
+
+	wanted := make(map[string]bool)
+	for _, name := range opts.Sources {
+		wanted[name] = true
+	}
+
+	var (
+		parts []string
+		args  []interface{}
+	)
+	for _, src := range sources {
+		if len(wanted) > 0 && !wanted[src.name] {
+			continue
+		}
+
+		part := fmt.Sprintf(`
+			SELECT w.id, w.dictionary_id, w.value, w.type, '%s',
+				snippet(%s, 0, ?, ?, '…', 12), bm25(%s)
+			FROM %s f
+			JOIN %s c ON c.id = f.rowid
+			%s
+			WHERE %s MATCH ?`,
+			src.name, src.fts, src.fts, src.fts, src.table, src.join, src.fts)
+		args = append(args, opts.HighlightStart, opts.HighlightEnd, match)
+
+		if opts.DictionaryID != 0 {
+			part += ` AND w.dictionary_id = ?`
+			args = append(args, opts.DictionaryID)
+		}
+
+		parts = append(parts, part)
+	}
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("no known search source in %v", opts.Sources)
+	}
+
+	statement := strings.Join(parts, "\nUNION ALL\n") + `
+		ORDER BY 7, 3
+		LIMIT ? OFFSET ?`
+	args = append(args, opts.Limit, opts.Offset)
+
+	rows, err := s.db.GetDB().QueryContext(ctx, statement, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search: %w", err)
+	}
+	defer rows.Close()
+
+	hits := []Hit{}
+	for rows.Next() {
+		var hit Hit
+		if err := rows.Scan(&hit.WordID, &hit.DictionaryID, &hit.Word, &hit.WordType, &hit.Source, &hit.Snippet, &hit.Rank); err != nil {
+			return nil, fmt.Errorf("failed to read search hit: %w", err)
+		}
+		hits = append(hits, hit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to search: %w", err)
+	}
+
+	return hits, nil
+}
+
+// ftsQuery turns free text into an FTS5 query where each term is quoted, so
+// that punctuation in user input cannot break the MATCH syntax
+func ftsQuery(query string) string {
+	var terms []string
+	for _, term := range strings.Fields(query) {
+		prefix := strings.HasSuffix(term, "*")
+		term = strings.Trim(term, `*"`)
+		if term == "" {
+			continue
+		}
+
+		quoted := `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+		if prefix {
+			quoted += "*"
+		}
+		terms = append(terms, quoted)
+	}
+	return strings.Join(terms, " ")
+}
@@ -0,0 +1,193 @@
+package search
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"lexin-sqlite/internal/database"
+	"lexin-sqlite/internal/parser"
+	"lexin-sqlite/internal/repository"
+)
+
+// testXML holds the term "zebra" once in each searchable source, under a
+// different word for each
+const testXML = `<Dictionary BaseLang="swe" TargetLang="eng" Version="1">
+<Word Value="randdjur" Type="subst." ID="1" VariantID="1">
+  <BaseLang><Meaning>zebra</Meaning></BaseLang>
+</Word>
+<Word Value="övergångsställe" Type="subst." ID="2" VariantID="1">
+  <BaseLang>
+    <Meaning>plats där man går över gatan</Meaning>
+    <Example ID="10">gå över vid ett övergångsställe som ibland kallas zebra på svenska gator</Example>
+  </BaseLang>
+</Word>
+<Word Value="rand" Type="subst." ID="3" VariantID="1">
+  <BaseLang><Idiom ID="20">randig som en zebra</Idiom></BaseLang>
+</Word>
+<Word Value="savann" Type="subst." ID="4" VariantID="1">
+  <BaseLang><Explanation>där lever lejon och zebra</Explanation></BaseLang>
+</Word>
+<Word Value="sebra" Type="subst." ID="5" VariantID="1">
+  <TargetLang><Translation>zebra</Translation></TargetLang>
+</Word>
+<Word Value="häst" Type="subst." ID="6" VariantID="1">
+  <BaseLang><Meaning>djur man rider på</Meaning></BaseLang>
+  <TargetLang><Translation>horse</Translation></TargetLang>
+</Word>
+</Dictionary>`
+
+// openTestDB imports testXML, and the same words as a second dictionary
+// when second is set, into a database in a temporary directory
+func openTestDB(t *testing.T, second bool) *database.DB {
+	t.Helper()
+	ctx := context.Background()
+
+	db, err := database.New(filepath.Join(t.TempDir(), "lexin.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	documents := []string{testXML}
+	if second {
+		documents = append(documents, strings.Replace(testXML, `TargetLang="eng"`, `TargetLang="deu"`, 1))
+	}
+	for _, document := range documents {
+		dict, err := parser.ParseXML(strings.NewReader(document))
+		if err != nil {
+			t.Fatalf("failed to parse XML: %v", err)
+		}
+		if _, err := repository.New(db).UpsertDictionary(ctx, dict); err != nil {
+			t.Fatalf("failed to import: %v", err)
+		}
+	}
+	return db
+}
+
+func TestSearchLabelsEachSource(t *testing.T) {
+	ctx := context.Background()
+	searcher := New(openTestDB(t, false))
+
+	hits, err := searcher.Search(ctx, "zebra", Options{})
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+
+	got := make(map[string]string)
+	for _, hit := range hits {
+		got[hit.Word] = hit.Source
+		if !strings.Contains(hit.Snippet, "[zebra]") {
+			t.Errorf("%s snippet %q does not highlight the term", hit.Word, hit.Snippet)
+		}
+		if hit.WordType != "subst." || hit.DictionaryID == 0 || hit.WordID == 0 {
+			t.Errorf("hit %+v lacks its word", hit)
+		}
+	}
+	want := map[string]string{
+		"randdjur":        SourceMeaning,
+		"övergångsställe": SourceExample,
+		"rand":            SourceIdiom,
+		"savann":          SourceExplanation,
+		"sebra":           SourceTranslation,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("hits = %v, want %v", got, want)
+	}
+}
+
+func TestSearchRanksByRelevance(t *testing.T) {
+	ctx := context.Background()
+	searcher := New(openTestDB(t, false))
+
+	hits, err := searcher.Search(ctx, "zebra", Options{})
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if len(hits) == 0 {
+		t.Fatal("search found nothing")
+	}
+
+	// bm25 is lower for better matches, across every source of the union
+	ranked := sort.SliceIsSorted(hits, func(i, j int) bool { return hits[i].Rank < hits[j].Rank })
+	if !ranked {
+		t.Errorf("hits are not ordered by rank: %+v", hits)
+	}
+	for _, hit := range hits {
+		if hit.Rank >= 0 {
+			t.Errorf("%s has rank %v, want a negative bm25 score", hit.Word, hit.Rank)
+		}
+	}
+
+	// A one word meaning is a better match than a long example
+	position := make(map[string]int)
+	for i, hit := range hits {
+		position[hit.Word] = i
+	}
+	if position["randdjur"] > position["övergångsställe"] {
+		t.Errorf("the meaning ranks below the long example: %+v", hits)
+	}
+
+	// Pages continue the same order
+	page, err := searcher.Search(ctx, "zebra", Options{Limit: 2, Offset: 2})
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if !reflect.DeepEqual(page, hits[2:4]) {
+		t.Errorf("second page = %+v, want %+v", page, hits[2:4])
+	}
+}
+
+func TestSearchOptions(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t, true)
+	searcher := New(db)
+
+	var engID int64
+	if err := db.GetDB().QueryRow(`SELECT id FROM dictionaries WHERE target_lang = 'eng'`).Scan(&engID); err != nil {
+		t.Fatalf("failed to find dictionary: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		opts  Options
+		want  []string
+	}{
+		{"every dictionary", "zebra", Options{Sources: []string{SourceTranslation}}, []string{"sebra", "sebra"}},
+		{"one dictionary", "zebra", Options{DictionaryID: engID, Sources: []string{SourceTranslation}}, []string{"sebra"}},
+		{"two sources", "zebra", Options{DictionaryID: engID, Sources: []string{SourceIdiom, SourceExplanation}}, []string{"rand", "savann"}},
+		{"every term", "zebra lejon", Options{DictionaryID: engID}, []string{"savann"}},
+		{"prefix", "hors*", Options{DictionaryID: engID}, []string{"häst"}},
+		{"punctuation", `"zebra" (lejon`, Options{DictionaryID: engID}, []string{"savann"}},
+		{"empty", ` * "" `, Options{}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits, err := searcher.Search(ctx, tt.query, tt.opts)
+			if err != nil {
+				t.Fatalf("search failed: %v", err)
+			}
+			got := []string{}
+			for _, hit := range hits {
+				got = append(got, hit.Word)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("words = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := searcher.Search(ctx, "zebra", Options{Sources: []string{"usage"}}); err == nil {
+		t.Error("search with an unknown source succeeded")
+	}
+
+	hits, err := searcher.Search(ctx, "zebra", Options{DictionaryID: engID, Sources: []string{SourceMeaning}, HighlightStart: "<b>", HighlightEnd: "</b>"})
+	if err != nil || len(hits) != 1 || hits[0].Snippet != "<b>zebra</b>" {
+		t.Errorf("highlighted hits = %+v, %v", hits, err)
+	}
+}
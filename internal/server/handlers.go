@@ -0,0 +1,241 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"lexin-sqlite/pkg/lexin"
+)
+
+// errNotFound answers requests for entries or dictionaries that do not exist
+var errNotFound = errors.New("not found")
+
+// handleDictionaries serves GET /api/dictionaries
+func (s *Server) handleDictionaries(w http.ResponseWriter, r *http.Request) {
+	dictionaries, err := s.db.Dictionaries(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	type dictionary struct {
+		Name string `json:"name"`
+		lexin.Dictionary
+	}
+	results := make([]dictionary, 0, len(dictionaries))
+	for _, dict := range dictionaries {
+		results = append(results, dictionary{Name: dict.Name(), Dictionary: dict})
+	}
+
+	writeJSON(w, r, results)
+}
+
+// handleLookup serves GET /api/lookup?q=<word>, the entries of a headword or
+// of the headword an inflected form belongs to
+func (s *Server) handleLookup(w http.ResponseWriter, r *http.Request) {
+	word, dict, limit, offset, ok := s.parseQuery(w, r)
+	if !ok {
+		return
+	}
+
+	entries, err := s.db.Lookup(r.Context(), dict, word)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// Headwords have a handful of entries at most, so they are paged in
+	// memory
+	hasMore := len(entries) > offset+limit
+	if offset >= len(entries) {
+		entries = []lexin.Entry{}
+	} else {
+		entries = entries[offset:]
+		if len(entries) > limit {
+			entries = entries[:limit]
+		}
+	}
+
+	writeJSON(w, r, page{Results: entries, Limit: limit, Offset: offset, HasMore: hasMore})
+}
+
+// handleAutocomplete serves GET /api/autocomplete?q=<prefix>
+func (s *Server) handleAutocomplete(w http.ResponseWriter, r *http.Request) {
+	prefix, dict, limit, offset, ok := s.parseQuery(w, r)
+	if !ok {
+		return
+	}
+
+	// One extra row tells whether there is a next page
+	values, err := s.db.Complete(r.Context(), dict, prefix, lexin.CompleteOptions{Limit: limit + 1, Offset: offset})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	hasMore := len(values) > limit
+	if hasMore {
+		values = values[:limit]
+	}
+
+	writeJSON(w, r, page{Results: values, Limit: limit, Offset: offset, HasMore: hasMore})
+}
+
+// handleReverse serves GET /api/reverse?q=<term>, Swedish entries found
+// through their translations and synonyms
+func (s *Server) handleReverse(w http.ResponseWriter, r *http.Request) {
+	term, dict, limit, offset, ok := s.parseQuery(w, r)
+	if !ok {
+		return
+	}
+
+	var kind lexin.MatchKind
+	if match := r.URL.Query().Get("match"); match != "" {
+		var err error
+		kind, err = lexin.ParseMatchKind(match)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	hits, err := s.db.ReverseLookup(r.Context(), dict, term, lexin.ReverseOptions{
+		Match:  kind,
+		Limit:  limit + 1,
+		Offset: offset,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	hasMore := len(hits) > limit
+	if hasMore {
+		hits = hits[:limit]
+	}
+
+	writeJSON(w, r, page{Results: hits, Limit: limit, Offset: offset, HasMore: hasMore})
+}
+
+// handleEntries serves GET /api/entries/{id}, every variant of the entry
+// with a Lexin ID
+func (s *Server) handleEntries(w http.ResponseWriter, r *http.Request) {
+	dict, err := s.dictionary(r)
+	if err != nil {
+		writeDictionaryError(w, err)
+		return
+	}
+
+	id := r.PathValue("id")
+	entries, err := s.db.EntriesByOriginalID(r.Context(), dict, id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if len(entries) == 0 {
+		writeError(w, http.StatusNotFound, fmt.Errorf("entry %s: %w", id, errNotFound))
+		return
+	}
+
+	writeJSON(w, r, entries)
+}
+
+// handleEntry serves GET /api/entries/{id}/{variant}, one variant of the
+// entry with a Lexin ID
+func (s *Server) handleEntry(w http.ResponseWriter, r *http.Request) {
+	dict, err := s.dictionary(r)
+	if err != nil {
+		writeDictionaryError(w, err)
+		return
+	}
+
+	id, variant := r.PathValue("id"), r.PathValue("variant")
+	entries, err := s.db.EntriesByOriginalID(r.Context(), dict, id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	for _, entry := range entries {
+		if entry.VariantID == variant {
+			writeJSON(w, r, entry)
+			return
+		}
+	}
+
+	writeError(w, http.StatusNotFound, fmt.Errorf("entry %s/%s: %w", id, variant, errNotFound))
+}
+
+// parseQuery reads the parameters shared by the search endpoints: q, dict,
+// limit and offset. It writes the error response and returns false when
+// they are invalid.
+func (s *Server) parseQuery(w http.ResponseWriter, r *http.Request) (q string, dict *lexin.Dictionary, limit, offset int, ok bool) {
+	query := r.URL.Query()
+
+	q = strings.TrimSpace(query.Get("q"))
+	if q == "" {
+		writeError(w, http.StatusBadRequest, errors.New("parameter q is required"))
+		return "", nil, 0, 0, false
+	}
+
+	dict, err := s.dictionary(r)
+	if err != nil {
+		writeDictionaryError(w, err)
+		return "", nil, 0, 0, false
+	}
+
+	limit = s.opts.DefaultLimit
+	if value := query.Get("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid limit: %s", value))
+			return "", nil, 0, 0, false
+		}
+	}
+	if limit > s.opts.MaxLimit {
+		limit = s.opts.MaxLimit
+	}
+
+	if value := query.Get("offset"); value != "" {
+		offset, err = strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid offset: %s", value))
+			return "", nil, 0, 0, false
+		}
+	}
+
+	return q, dict, limit, offset, true
+}
+
+// dictionary returns the dictionary named by the dict parameter, such as
+// "swe-eng", or nil for every dictionary when the parameter is absent
+func (s *Server) dictionary(r *http.Request) (*lexin.Dictionary, error) {
+	name := r.URL.Query().Get("dict")
+	if name == "" {
+		return nil, nil
+	}
+
+	dictionaries, err := s.db.Dictionaries(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	for i := range dictionaries {
+		if dictionaries[i].Name() == name {
+			return &dictionaries[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("dictionary %s: %w", name, errNotFound)
+}
+
+// writeDictionaryError answers a request whose dictionary could not be
+// resolved
+func writeDictionaryError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeError(w, http.StatusInternalServerError, err)
+}
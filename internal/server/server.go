@@ -0,0 +1,148 @@
+// Package server exposes a lexin-sqlite database as a read-only JSON API
+// over HTTP
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"lexin-sqlite/pkg/lexin"
+)
+
+// Options configures the server
+type Options struct {
+	// AllowOrigin is sent as Access-Control-Allow-Origin, "*" when empty
+	AllowOrigin string
+	// DefaultLimit is the page size when a request does not give one, 20
+	// when zero
+	DefaultLimit int
+	// MaxLimit caps the page size a request can ask for, 100 when zero
+	MaxLimit int
+}
+
+// Server answers API requests from a database
+type Server struct {
+	db   *lexin.DB
+	opts Options
+	mux  *http.ServeMux
+}
+
+// New creates a server reading from db, which should be opened with
+// lexin.OpenReadOnly
+func New(db *lexin.DB, opts Options) *Server {
+	if opts.AllowOrigin == "" {
+		opts.AllowOrigin = "*"
+	}
+	if opts.DefaultLimit <= 0 {
+		opts.DefaultLimit = 20
+	}
+	if opts.MaxLimit <= 0 {
+		opts.MaxLimit = 100
+	}
+
+	s := &Server{db: db, opts: opts, mux: http.NewServeMux()}
+	s.mux.HandleFunc("GET /api/dictionaries", s.handleDictionaries)
+	s.mux.HandleFunc("GET /api/lookup", s.handleLookup)
+	s.mux.HandleFunc("GET /api/autocomplete", s.handleAutocomplete)
+	s.mux.HandleFunc("GET /api/reverse", s.handleReverse)
+	s.mux.HandleFunc("GET /api/entries/{id}", s.handleEntries)
+	s.mux.HandleFunc("GET /api/entries/{id}/{variant}", s.handleEntry)
+
+	return s
+}
+
+// ServeHTTP adds the CORS headers and dispatches the request
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	header := w.Header()
+	header.Set("Access-Control-Allow-Origin", s.opts.AllowOrigin)
+	if s.opts.AllowOrigin != "*" {
+		header.Add("Vary", "Origin")
+	}
+	header.Set("Access-Control-Expose-Headers", "ETag")
+
+	// Preflight requests never reach the handlers
+	if r.Method == http.MethodOptions {
+		header.Set("Access-Control-Allow-Methods", "GET, HEAD, OPTIONS")
+		header.Set("Access-Control-Allow-Headers", "If-None-Match")
+		header.Set("Access-Control-Max-Age", "86400")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	s.mux.ServeHTTP(w, r)
+}
+
+// page is the envelope of paginated responses
+type page struct {
+	Results interface{} `json:"results"`
+	Limit   int         `json:"limit"`
+	Offset  int         `json:"offset"`
+	HasMore bool        `json:"has_more"`
+}
+
+// errorResponse is the body of failed requests
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// writeJSON writes v with an ETag derived from the body. The database is
+// read-only, so the same body always means the same data, and a request
+// whose If-None-Match holds the tag gets 304 Not Modified instead.
+func writeJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		w.Write(body)
+	}
+}
+
+// etagMatches reports whether an If-None-Match header lists etag. Weak
+// validators compare equal to strong ones, as RFC 9110 requires for
+// If-None-Match.
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// writeError writes an error response. Server errors are logged and their
+// details kept from the client.
+func writeError(w http.ResponseWriter, status int, err error) {
+	message := err.Error()
+	if status >= http.StatusInternalServerError {
+		log.Printf("Error: %v", err)
+		message = http.StatusText(status)
+	}
+
+	body, _ := json.Marshal(errorResponse{Error: message})
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(body)
+}
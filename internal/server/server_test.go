@@ -0,0 +1,233 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"lexin-sqlite/internal/database"
+	"lexin-sqlite/internal/parser"
+	"lexin-sqlite/internal/repository"
+	"lexin-sqlite/pkg/lexin"
+)
+
+const testXML = `<Dictionary BaseLang="swe" TargetLang="eng" Version="1">
+<Word Value="hus" Type="subst." ID="1" VariantID="1">
+  <TargetLang><Translation>house</Translation></TargetLang>
+</Word>
+<Word Value="husbil" Type="subst." ID="2" VariantID="1">
+  <TargetLang><Translation>camper</Translation></TargetLang>
+</Word>
+<Word Value="husdjur" Type="subst." ID="3" VariantID="1">
+  <TargetLang><Translation>pet</Translation></TargetLang>
+</Word>
+<Word Value="bil" Type="subst." ID="4" VariantID="1">
+  <TargetLang><Translation>car</Translation></TargetLang>
+</Word>
+</Dictionary>`
+
+// newTestServer imports testXML into a database in a temporary directory
+// and serves it with opts
+func newTestServer(t *testing.T, opts Options) *Server {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "lexin.db")
+
+	db, err := database.New(path)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	dict, err := parser.ParseXML(strings.NewReader(testXML))
+	if err != nil {
+		t.Fatalf("failed to parse XML: %v", err)
+	}
+	if _, err := repository.New(db).UpsertDictionary(context.Background(), dict); err != nil {
+		t.Fatalf("failed to import: %v", err)
+	}
+
+	ldb, err := lexin.OpenReadOnly(path)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { ldb.Close() })
+	return New(ldb, opts)
+}
+
+// get sends a GET request for target with the given headers
+func get(s *Server, target string, header map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	for name, value := range header {
+		r.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	return w
+}
+
+func TestETag(t *testing.T) {
+	s := newTestServer(t, Options{})
+
+	first := get(s, "/api/lookup?q=hus", nil)
+	if first.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", first.Code, first.Body)
+	}
+	etag := first.Header().Get("ETag")
+	if etag == "" || !strings.HasPrefix(etag, `"`) {
+		t.Fatalf("ETag = %q, want a quoted tag", etag)
+	}
+
+	tests := []struct {
+		ifNoneMatch string
+		want        int
+	}{
+		{etag, http.StatusNotModified},
+		{"W/" + etag, http.StatusNotModified},
+		{`"other", ` + etag, http.StatusNotModified},
+		{"*", http.StatusNotModified},
+		{`"other"`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := get(s, "/api/lookup?q=hus", map[string]string{"If-None-Match": tt.ifNoneMatch})
+		if w.Code != tt.want {
+			t.Errorf("If-None-Match %s: status = %d, want %d", tt.ifNoneMatch, w.Code, tt.want)
+		}
+		if got := w.Header().Get("ETag"); got != etag {
+			t.Errorf("If-None-Match %s: ETag = %q, want %q", tt.ifNoneMatch, got, etag)
+		}
+		if w.Code == http.StatusNotModified && w.Body.Len() != 0 {
+			t.Errorf("If-None-Match %s: 304 response has a body: %s", tt.ifNoneMatch, w.Body)
+		}
+	}
+
+	if other := get(s, "/api/lookup?q=bil", nil).Header().Get("ETag"); other == etag {
+		t.Errorf("different responses share the ETag %s", etag)
+	}
+}
+
+func TestCORS(t *testing.T) {
+	t.Run("any origin", func(t *testing.T) {
+		w := get(newTestServer(t, Options{}), "/api/dictionaries", nil)
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+			t.Errorf("Access-Control-Allow-Origin = %q, want *", got)
+		}
+		if got := w.Header().Get("Access-Control-Expose-Headers"); got != "ETag" {
+			t.Errorf("Access-Control-Expose-Headers = %q, want ETag", got)
+		}
+		if got := w.Header().Get("Vary"); got != "" {
+			t.Errorf("Vary = %q, want none", got)
+		}
+	})
+
+	t.Run("one origin", func(t *testing.T) {
+		w := get(newTestServer(t, Options{AllowOrigin: "https://example.com"}), "/api/dictionaries", nil)
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://example.com" {
+			t.Errorf("Access-Control-Allow-Origin = %q, want https://example.com", got)
+		}
+		if got := w.Header().Get("Vary"); got != "Origin" {
+			t.Errorf("Vary = %q, want Origin", got)
+		}
+	})
+
+	t.Run("preflight", func(t *testing.T) {
+		s := newTestServer(t, Options{})
+		r := httptest.NewRequest(http.MethodOptions, "/api/lookup?q=hus", nil)
+		r.Header.Set("Origin", "https://example.com")
+		r.Header.Set("Access-Control-Request-Method", "GET")
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+
+		if w.Code != http.StatusNoContent {
+			t.Errorf("status = %d, want 204", w.Code)
+		}
+		want := map[string]string{
+			"Access-Control-Allow-Origin":  "*",
+			"Access-Control-Allow-Methods": "GET, HEAD, OPTIONS",
+			"Access-Control-Allow-Headers": "If-None-Match",
+		}
+		for name, value := range want {
+			if got := w.Header().Get(name); got != value {
+				t.Errorf("%s = %q, want %q", name, got, value)
+			}
+		}
+	})
+}
+
+func TestPagination(t *testing.T) {
+	s := newTestServer(t, Options{DefaultLimit: 2, MaxLimit: 3})
+
+	tests := []struct {
+		query   string
+		results []string
+		limit   int
+		offset  int
+		hasMore bool
+	}{
+		{"q=hus", []string{"hus", "husbil"}, 2, 0, true},
+		{"q=hus&offset=2", []string{"husdjur"}, 2, 2, false},
+		{"q=hus&limit=1&offset=1", []string{"husbil"}, 1, 1, true},
+		{"q=hus&limit=50", []string{"hus", "husbil", "husdjur"}, 3, 0, false},
+		{"q=hus&offset=10", []string{}, 2, 10, false},
+	}
+	for _, tt := range tests {
+		w := get(s, "/api/autocomplete?"+tt.query, nil)
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: status = %d, want 200: %s", tt.query, w.Code, w.Body)
+			continue
+		}
+		var got struct {
+			Results []string `json:"results"`
+			Limit   int      `json:"limit"`
+			Offset  int      `json:"offset"`
+			HasMore bool     `json:"has_more"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatalf("%s: failed to decode %s: %v", tt.query, w.Body, err)
+		}
+		if !reflect.DeepEqual(got.Results, tt.results) || got.Limit != tt.limit || got.Offset != tt.offset || got.HasMore != tt.hasMore {
+			t.Errorf("%s: got %+v, want results %q, limit %d, offset %d, has_more %v",
+				tt.query, got, tt.results, tt.limit, tt.offset, tt.hasMore)
+		}
+	}
+
+	for _, query := range []string{"q=", "q=%20", "q=hus&limit=0", "q=hus&limit=-1", "q=hus&limit=x", "q=hus&offset=-1", "q=hus&offset=x"} {
+		w := get(s, "/api/autocomplete?"+query, nil)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, w.Code)
+		}
+		var body errorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.Error == "" {
+			t.Errorf("%s: body %s is not an error response", query, w.Body)
+		}
+	}
+}
+
+func TestUnknownDictionary(t *testing.T) {
+	s := newTestServer(t, Options{})
+
+	for _, target := range []string{
+		"/api/lookup?q=hus&dict=swe-xxx",
+		"/api/autocomplete?q=hus&dict=swe-xxx",
+		"/api/reverse?q=house&dict=swe-xxx",
+		"/api/entries/1?dict=swe-xxx",
+		"/api/entries/1/1?dict=swe-xxx",
+	} {
+		w := get(s, target, nil)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%s: status = %d, want 404", target, w.Code)
+			continue
+		}
+		var body errorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || !strings.Contains(body.Error, "swe-xxx") {
+			t.Errorf("%s: body = %s, want an error naming the dictionary", target, w.Body)
+		}
+	}
+
+	if w := get(s, "/api/lookup?q=hus&dict=swe-eng", nil); w.Code != http.StatusOK {
+		t.Errorf("known dictionary: status = %d, want 200", w.Code)
+	}
+}
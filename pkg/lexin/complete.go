@@ -0,0 +1,108 @@
+package lexin
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// CompleteOptions pages through completions
+type CompleteOptions struct {
+	// Limit caps the number of headwords returned, 10 when zero
+	Limit int
+	// Offset skips the first headwords for pagination
+	Offset int
+}
+
+// Complete returns the distinct headwords starting with prefix in
+// alphabetical order, for autocompletion. The match is case sensitive so
+// that it can be answered from the headword index.
+func (d *DB) Complete(ctx context.Context, dict *Dictionary, prefix string, opts CompleteOptions) ([]string, error) {
+	prefix = strings.TrimSpace(prefix)
+	if prefix == "" {
+		return []string{}, nil
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = 10
+	}
+
+	// Every string starting with prefix sorts between prefix and prefix
+	// followed by the largest code point
+	query := `
+		SELECT DISTINCT value
+		FROM words
+		WHERE value >= ? AND value < ?`
+	args := []interface{}{prefix, prefix + string(utf8.MaxRune)}
+	if dict != nil {
+		query += ` AND dictionary_id = ?`
+		args = append(args, dict.ID)
+	}
+	query += ` ORDER BY value LIMIT ? OFFSET ?`
+	args = append(args, opts.Limit, opts.Offset)
+
+	rows, err := d.db.GetDB().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to complete %q: %w", prefix, err)
+	}
+	defer rows.Close()
+
+	values := []string{}
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, fmt.Errorf("failed to complete %q: %w", prefix, err)
+		}
+		values = append(values, value)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to complete %q: %w", prefix, err)
+	}
+
+	return values, nil
+}
+
+// Contains returns the distinct headwords containing substring in
+// alphabetical order. Unlike Complete it cannot use an index and scans the
+// headwords of the dictionary.
+func (d *DB) Contains(ctx context.Context, dict *Dictionary, substring string, opts CompleteOptions) ([]string, error) {
+	substring = strings.TrimSpace(substring)
+	if substring == "" {
+		return []string{}, nil
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = 10
+	}
+
+	query := `
+		SELECT DISTINCT value
+		FROM words
+		WHERE value LIKE ? ESCAPE '\'`
+	args := []interface{}{"%" + escapeLike(substring) + "%"}
+	if dict != nil {
+		query += ` AND dictionary_id = ?`
+		args = append(args, dict.ID)
+	}
+	query += ` ORDER BY value LIMIT ? OFFSET ?`
+	args = append(args, opts.Limit, opts.Offset)
+
+	rows, err := d.db.GetDB().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to match %q: %w", substring, err)
+	}
+	defer rows.Close()
+
+	values := []string{}
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, fmt.Errorf("failed to match %q: %w", substring, err)
+		}
+		values = append(values, value)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to match %q: %w", substring, err)
+	}
+
+	return values, nil
+}
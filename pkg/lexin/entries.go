@@ -0,0 +1,62 @@
+package lexin
+
+import (
+	"context"
+	"fmt"
+	"unicode/utf8"
+)
+
+// EntryFilter narrows the entries visited by EachEntry
+type EntryFilter struct {
+	// Type keeps the entries with this word type, such as "subst."
+	Type string
+	// Prefix keeps the entries whose headword starts with it
+	Prefix string
+}
+
+// EachEntry calls fn for every entry of dict, or of every dictionary when
+// dict is nil, in import order. Entries are read and hydrated in batches, so
+// memory use does not grow with the size of the dictionary. Iteration stops
+// at the first error returned by fn.
+func (d *DB) EachEntry(ctx context.Context, dict *Dictionary, filter EntryFilter, fn func(Entry) error) error {
+	query := `
+		SELECT id, dictionary_id, value, variant, type, original_id, variant_id, matching_id
+		FROM words
+		WHERE id > ?`
+	var args []interface{}
+	if dict != nil {
+		query += ` AND dictionary_id = ?`
+		args = append(args, dict.ID)
+	}
+	if filter.Type != "" {
+		query += ` AND type = ?`
+		args = append(args, filter.Type)
+	}
+	if filter.Prefix != "" {
+		query += ` AND value >= ? AND value < ?`
+		args = append(args, filter.Prefix, filter.Prefix+string(utf8.MaxRune))
+	}
+	query += ` ORDER BY id LIMIT ?`
+	args = append(args, hydrateBatchSize)
+
+	// Keyset pagination on the word id: each batch starts after the last
+	// id of the previous one
+	var lastID int64
+	for {
+		batch, err := d.queryEntries(ctx, query, append([]interface{}{lastID}, args...)...)
+		if err != nil {
+			return fmt.Errorf("failed to read entries: %w", err)
+		}
+
+		for _, entry := range batch {
+			if err := fn(entry); err != nil {
+				return err
+			}
+		}
+
+		if len(batch) < hydrateBatchSize {
+			return nil
+		}
+		lastID = batch[len(batch)-1].ID
+	}
+}
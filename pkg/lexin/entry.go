@@ -0,0 +1,191 @@
+package lexin
+
+// Entry is a dictionary word with everything stored for it. It mirrors the
+// Word element of the Lexin XML format.
+type Entry struct {
+	ID           int64        `json:"id"`
+	DictionaryID int64        `json:"dictionary_id"`
+	Value        string       `json:"value"`
+	Variant      string       `json:"variant,omitempty"`
+	Type         string       `json:"type"`
+	OriginalID   string       `json:"original_id"`
+	VariantID    string       `json:"variant_id"`
+	MatchingID   string       `json:"matching_id,omitempty"`
+	BaseLangs    []BaseLang   `json:"base_langs"`
+	TargetLangs  []TargetLang `json:"target_langs"`
+	// Form is set when the entry was found through an inflected form
+	// rather than its headword
+	Form *FormMatch `json:"form,omitempty"`
+}
+
+// FormMatch tells which surface form of an entry matched a lookup
+type FormMatch struct {
+	Form string `json:"form"`
+	// Source is inflection, variant, compound or derivation
+	Source      string `json:"source"`
+	Description string `json:"description,omitempty"`
+}
+
+// BaseLang holds the Swedish description of an entry
+type BaseLang struct {
+	ID            int64          `json:"id"`
+	Meaning       Meaning        `json:"meaning"`
+	References    []Reference    `json:"references,omitempty"`
+	Comments      []Comment      `json:"comments,omitempty"`
+	Explanations  []Explanation  `json:"explanations,omitempty"`
+	Alternates    []Alternate    `json:"alternates,omitempty"`
+	Antonyms      []Antonym      `json:"antonyms,omitempty"`
+	Usages        []Usage        `json:"usages,omitempty"`
+	Phonetic      *Phonetic      `json:"phonetic,omitempty"`
+	Illustrations []Illustration `json:"illustrations,omitempty"`
+	Inflections   []Inflection   `json:"inflections,omitempty"`
+	Graminfo      string         `json:"graminfo,omitempty"`
+	Examples      []Example      `json:"examples,omitempty"`
+	Idioms        []Idiom        `json:"idioms,omitempty"`
+	Compounds     []Compound     `json:"compounds,omitempty"`
+	Derivations   []Derivation   `json:"derivations,omitempty"`
+	Indexes       []Index        `json:"indexes,omitempty"`
+	// MatchedTargetLangID is the ID of the TargetLang holding the
+	// translation of this base language, zero until the dictionary is
+	// linked
+	MatchedTargetLangID int64 `json:"matched_target_lang_id,omitempty"`
+}
+
+// TargetLang holds the translation of an entry
+type TargetLang struct {
+	ID           int64        `json:"id"`
+	Comment      string       `json:"comment,omitempty"`
+	Translations []string     `json:"translations,omitempty"`
+	Synonyms     []string     `json:"synonyms,omitempty"`
+	CommentElems []string     `json:"comment_elems,omitempty"`
+	Explanations []string     `json:"explanations,omitempty"`
+	Antonyms     []Antonym    `json:"antonyms,omitempty"`
+	Examples     []Example    `json:"examples,omitempty"`
+	Idioms       []Idiom      `json:"idioms,omitempty"`
+	Compounds    []Compound   `json:"compounds,omitempty"`
+	Derivations  []Derivation `json:"derivations,omitempty"`
+}
+
+// Meaning is the definition of a word
+type Meaning struct {
+	Content    string `json:"content,omitempty"`
+	MatchingID string `json:"matching_id,omitempty"`
+}
+
+// Reference points to another word
+type Reference struct {
+	Type       string `json:"type"`
+	Value      string `json:"value"`
+	MatchingID string `json:"matching_id,omitempty"`
+	// TargetWordID is the ID of the entry Value names, zero when it names
+	// none or the reference is to a file
+	TargetWordID int64 `json:"target_word_id,omitempty"`
+}
+
+// Comment is a comment on a word
+type Comment struct {
+	Content    string `json:"content"`
+	MatchingID string `json:"matching_id,omitempty"`
+}
+
+// Explanation is an explanation of a word
+type Explanation struct {
+	Content    string `json:"content"`
+	MatchingID string `json:"matching_id,omitempty"`
+}
+
+// Alternate is an alternate form of a word
+type Alternate struct {
+	Content string `json:"content"`
+}
+
+// Antonym is a word with the opposite meaning
+type Antonym struct {
+	Value string `json:"value"`
+	// TargetWordID is the ID of the entry Value names, zero when it names
+	// none
+	TargetWordID int64 `json:"target_word_id,omitempty"`
+}
+
+// Usage describes how a word is used
+type Usage struct {
+	Content    string `json:"content"`
+	MatchingID string `json:"matching_id,omitempty"`
+}
+
+// Phonetic holds the pronunciation of a word
+type Phonetic struct {
+	Content string `json:"content,omitempty"`
+	File    string `json:"file,omitempty"`
+}
+
+// Illustration refers to a picture or animation
+type Illustration struct {
+	Type     string `json:"type"`
+	Value    string `json:"value"`
+	Norlexin string `json:"norlexin,omitempty"`
+}
+
+// Inflection lists inflected forms of a word
+type Inflection struct {
+	Content  string    `json:"content,omitempty"`
+	Variants []Variant `json:"variants,omitempty"`
+}
+
+// Variant is an alternative inflected form
+type Variant struct {
+	Content     string `json:"content"`
+	Description string `json:"description,omitempty"`
+}
+
+// Example is an example sentence
+type Example struct {
+	Content    string `json:"content"`
+	ID         string `json:"id,omitempty"`
+	MatchingID string `json:"matching_id,omitempty"`
+	// RowID is the database ID of the example
+	RowID int64 `json:"row_id"`
+	// MatchedID is the RowID of the example on the other language
+	// side matched with this one, zero when there is none
+	MatchedID int64 `json:"matched_id,omitempty"`
+}
+
+// Idiom is an idiomatic expression
+type Idiom struct {
+	Content    string `json:"content"`
+	ID         string `json:"id,omitempty"`
+	MatchingID string `json:"matching_id,omitempty"`
+	// RowID is the database ID of the idiom
+	RowID int64 `json:"row_id"`
+	// MatchedID is the RowID of the idiom on the other language
+	// side matched with this one, zero when there is none
+	MatchedID int64 `json:"matched_id,omitempty"`
+}
+
+// Compound is a compound word built on the entry
+type Compound struct {
+	Content     string `json:"content,omitempty"`
+	ID          string `json:"id,omitempty"`
+	Description string `json:"description,omitempty"`
+	MatchingID  string `json:"matching_id,omitempty"`
+	Inflection  string `json:"inflection,omitempty"`
+	// RowID is the database ID of the compound
+	RowID int64 `json:"row_id"`
+	// MatchedID is the RowID of the compound on the other language side
+	// matched with this one, zero when there is none
+	MatchedID int64 `json:"matched_id,omitempty"`
+}
+
+// Derivation is a word derived from the entry
+type Derivation struct {
+	Content     string `json:"content,omitempty"`
+	ID          string `json:"id,omitempty"`
+	Description string `json:"description,omitempty"`
+	Inflection  string `json:"inflection,omitempty"`
+}
+
+// Index is an index entry of a word
+type Index struct {
+	Value string `json:"value"`
+	Type  string `json:"type,omitempty"`
+}
@@ -0,0 +1,698 @@
+package lexin
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// hydrateBatchSize is the number of words whose children are loaded with one
+// set of queries. Each batch costs a fixed number of queries no matter how
+// many words it holds.
+const hydrateBatchSize = 500
+
+// scanner is implemented by *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanWord scans the columns id, dictionary_id, value, variant, type,
+// original_id, variant_id and matching_id of the words table
+func scanWord(row scanner) (Entry, error) {
+	var entry Entry
+	var variant, matchingID sql.NullString
+
+	err := row.Scan(
+		&entry.ID,
+		&entry.DictionaryID,
+		&entry.Value,
+		&variant,
+		&entry.Type,
+		&entry.OriginalID,
+		&entry.VariantID,
+		&matchingID,
+	)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	entry.Variant = variant.String
+	entry.MatchingID = matchingID.String
+	entry.BaseLangs = []BaseLang{}
+	entry.TargetLangs = []TargetLang{}
+
+	return entry, nil
+}
+
+// hydrate loads the base and target language data of entries
+func (d *DB) hydrate(ctx context.Context, entries []Entry) error {
+	for start := 0; start < len(entries); start += hydrateBatchSize {
+		end := start + hydrateBatchSize
+		if end > len(entries) {
+			end = len(entries)
+		}
+		if err := d.hydrateBatch(ctx, entries[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// childRef locates an element that has children of its own, such as an
+// inflection or a compound, once its parent slice has stopped growing
+type childRef struct {
+	base    bool
+	ownerID int64
+	index   int
+}
+
+// hydrateBatch loads the children of a batch of entries, one query per table
+func (d *DB) hydrateBatch(ctx context.Context, entries []Entry) error {
+	byID := make(map[int64]*Entry, len(entries))
+	wordIDs := make([]int64, 0, len(entries))
+	for i := range entries {
+		byID[entries[i].ID] = &entries[i]
+		wordIDs = append(wordIDs, entries[i].ID)
+	}
+
+	// Base and target language rows
+	err := d.eachRow(ctx, `
+		SELECT id, word_id, meaning, matching_id, matched_target_lang_id
+		FROM base_langs
+		WHERE word_id IN (%s)
+		ORDER BY word_id, position, id
+	`, [][]int64{wordIDs}, func(rows *sql.Rows) error {
+		var wordID int64
+		var base BaseLang
+		var meaning, matchingID sql.NullString
+		var matched sql.NullInt64
+		if err := rows.Scan(&base.ID, &wordID, &meaning, &matchingID, &matched); err != nil {
+			return err
+		}
+		base.Meaning = Meaning{Content: meaning.String, MatchingID: matchingID.String}
+		base.MatchedTargetLangID = matched.Int64
+		byID[wordID].BaseLangs = append(byID[wordID].BaseLangs, base)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load base languages: %w", err)
+	}
+
+	err = d.eachRow(ctx, `
+		SELECT id, word_id, comment
+		FROM target_langs
+		WHERE word_id IN (%s)
+		ORDER BY word_id, position, id
+	`, [][]int64{wordIDs}, func(rows *sql.Rows) error {
+		var wordID int64
+		var target TargetLang
+		var comment sql.NullString
+		if err := rows.Scan(&target.ID, &wordID, &comment); err != nil {
+			return err
+		}
+		target.Comment = comment.String
+		byID[wordID].TargetLangs = append(byID[wordID].TargetLangs, target)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load target languages: %w", err)
+	}
+
+	// The parent slices are complete, so pointers into them stay valid
+	bases := make(map[int64]*BaseLang)
+	targets := make(map[int64]*TargetLang)
+	var baseIDs, targetIDs []int64
+	for i := range entries {
+		for j := range entries[i].BaseLangs {
+			base := &entries[i].BaseLangs[j]
+			bases[base.ID] = base
+			baseIDs = append(baseIDs, base.ID)
+		}
+		for j := range entries[i].TargetLangs {
+			target := &entries[i].TargetLangs[j]
+			targets[target.ID] = target
+			targetIDs = append(targetIDs, target.ID)
+		}
+	}
+
+	if len(baseIDs) > 0 {
+		if err := d.loadBaseChildren(ctx, bases, baseIDs); err != nil {
+			return err
+		}
+	}
+	if len(targetIDs) > 0 {
+		if err := d.loadTargetChildren(ctx, targets, targetIDs); err != nil {
+			return err
+		}
+	}
+
+	return d.loadSharedChildren(ctx, bases, targets, baseIDs, targetIDs)
+}
+
+// loadBaseChildren loads the tables that only hang off base_langs
+func (d *DB) loadBaseChildren(ctx context.Context, bases map[int64]*BaseLang, baseIDs []int64) error {
+	ids := [][]int64{baseIDs}
+
+	// word_references
+	err := d.eachRow(ctx, `
+		SELECT base_lang_id, type, value, matching_id, target_word_id
+		FROM word_references
+		WHERE base_lang_id IN (%s)
+		ORDER BY base_lang_id, position, id
+	`, ids, func(rows *sql.Rows) error {
+		var baseID int64
+		var ref Reference
+		var matchingID sql.NullString
+		var targetWordID sql.NullInt64
+		if err := rows.Scan(&baseID, &ref.Type, &ref.Value, &matchingID, &targetWordID); err != nil {
+			return err
+		}
+		ref.MatchingID = matchingID.String
+		ref.TargetWordID = targetWordID.Int64
+		bases[baseID].References = append(bases[baseID].References, ref)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load references: %w", err)
+	}
+
+	// comments
+	err = d.eachRow(ctx, `
+		SELECT base_lang_id, content, matching_id
+		FROM comments
+		WHERE base_lang_id IN (%s)
+		ORDER BY base_lang_id, position, id
+	`, ids, func(rows *sql.Rows) error {
+		var baseID int64
+		var comment Comment
+		var matchingID sql.NullString
+		if err := rows.Scan(&baseID, &comment.Content, &matchingID); err != nil {
+			return err
+		}
+		comment.MatchingID = matchingID.String
+		bases[baseID].Comments = append(bases[baseID].Comments, comment)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load comments: %w", err)
+	}
+
+	// explanations
+	err = d.eachRow(ctx, `
+		SELECT base_lang_id, content, matching_id
+		FROM explanations
+		WHERE base_lang_id IN (%s)
+		ORDER BY base_lang_id, position, id
+	`, ids, func(rows *sql.Rows) error {
+		var baseID int64
+		var expl Explanation
+		var matchingID sql.NullString
+		if err := rows.Scan(&baseID, &expl.Content, &matchingID); err != nil {
+			return err
+		}
+		expl.MatchingID = matchingID.String
+		bases[baseID].Explanations = append(bases[baseID].Explanations, expl)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load explanations: %w", err)
+	}
+
+	// alternates
+	err = d.eachRow(ctx, `
+		SELECT base_lang_id, content
+		FROM alternates
+		WHERE base_lang_id IN (%s)
+		ORDER BY base_lang_id, position, id
+	`, ids, func(rows *sql.Rows) error {
+		var baseID int64
+		var alt Alternate
+		if err := rows.Scan(&baseID, &alt.Content); err != nil {
+			return err
+		}
+		bases[baseID].Alternates = append(bases[baseID].Alternates, alt)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load alternates: %w", err)
+	}
+
+	// usages
+	err = d.eachRow(ctx, `
+		SELECT base_lang_id, content, matching_id
+		FROM usages
+		WHERE base_lang_id IN (%s)
+		ORDER BY base_lang_id, position, id
+	`, ids, func(rows *sql.Rows) error {
+		var baseID int64
+		var usage Usage
+		var matchingID sql.NullString
+		if err := rows.Scan(&baseID, &usage.Content, &matchingID); err != nil {
+			return err
+		}
+		usage.MatchingID = matchingID.String
+		bases[baseID].Usages = append(bases[baseID].Usages, usage)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load usages: %w", err)
+	}
+
+	// phonetics, one per base language
+	err = d.eachRow(ctx, `
+		SELECT base_lang_id, content, file
+		FROM phonetics
+		WHERE base_lang_id IN (%s)
+		ORDER BY base_lang_id, position, id
+	`, ids, func(rows *sql.Rows) error {
+		var baseID int64
+		var content, file sql.NullString
+		if err := rows.Scan(&baseID, &content, &file); err != nil {
+			return err
+		}
+		if bases[baseID].Phonetic == nil {
+			bases[baseID].Phonetic = &Phonetic{Content: content.String, File: file.String}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load phonetics: %w", err)
+	}
+
+	// illustrations
+	err = d.eachRow(ctx, `
+		SELECT base_lang_id, type, value, norlexin
+		FROM illustrations
+		WHERE base_lang_id IN (%s)
+		ORDER BY base_lang_id, position, id
+	`, ids, func(rows *sql.Rows) error {
+		var baseID int64
+		var ill Illustration
+		var norlexin sql.NullString
+		if err := rows.Scan(&baseID, &ill.Type, &ill.Value, &norlexin); err != nil {
+			return err
+		}
+		ill.Norlexin = norlexin.String
+		bases[baseID].Illustrations = append(bases[baseID].Illustrations, ill)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load illustrations: %w", err)
+	}
+
+	// inflections and their variants
+	inflections := make(map[int64]childRef)
+	err = d.eachRow(ctx, `
+		SELECT id, base_lang_id, content
+		FROM inflections
+		WHERE base_lang_id IN (%s)
+		ORDER BY base_lang_id, position, id
+	`, ids, func(rows *sql.Rows) error {
+		var id, baseID int64
+		var content sql.NullString
+		if err := rows.Scan(&id, &baseID, &content); err != nil {
+			return err
+		}
+		base := bases[baseID]
+		inflections[id] = childRef{base: true, ownerID: baseID, index: len(base.Inflections)}
+		base.Inflections = append(base.Inflections, Inflection{Content: content.String})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load inflections: %w", err)
+	}
+
+	if len(inflections) > 0 {
+		err = d.eachRow(ctx, `
+			SELECT inflection_id, content, description
+			FROM inflection_variants
+			WHERE inflection_id IN (%s)
+			ORDER BY inflection_id, position, id
+		`, [][]int64{refIDs(inflections)}, func(rows *sql.Rows) error {
+			var inflID int64
+			var variant Variant
+			var description sql.NullString
+			if err := rows.Scan(&inflID, &variant.Content, &description); err != nil {
+				return err
+			}
+			variant.Description = description.String
+			ref := inflections[inflID]
+			infl := &bases[ref.ownerID].Inflections[ref.index]
+			infl.Variants = append(infl.Variants, variant)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to load inflection variants: %w", err)
+		}
+	}
+
+	// graminfos, one per base language
+	err = d.eachRow(ctx, `
+		SELECT base_lang_id, content
+		FROM graminfos
+		WHERE base_lang_id IN (%s)
+		ORDER BY base_lang_id, position, id
+	`, ids, func(rows *sql.Rows) error {
+		var baseID int64
+		var content string
+		if err := rows.Scan(&baseID, &content); err != nil {
+			return err
+		}
+		if bases[baseID].Graminfo == "" {
+			bases[baseID].Graminfo = content
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load graminfos: %w", err)
+	}
+
+	// indexes
+	err = d.eachRow(ctx, `
+		SELECT base_lang_id, value, type
+		FROM indexes
+		WHERE base_lang_id IN (%s)
+		ORDER BY base_lang_id, position, id
+	`, ids, func(rows *sql.Rows) error {
+		var baseID int64
+		var index Index
+		var indexType sql.NullString
+		if err := rows.Scan(&baseID, &index.Value, &indexType); err != nil {
+			return err
+		}
+		index.Type = indexType.String
+		bases[baseID].Indexes = append(bases[baseID].Indexes, index)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load indexes: %w", err)
+	}
+
+	return nil
+}
+
+// loadTargetChildren loads the tables that only hang off target_langs
+func (d *DB) loadTargetChildren(ctx context.Context, targets map[int64]*TargetLang, targetIDs []int64) error {
+	// A TargetLang may hold several Translation, Synonym, Comment and
+	// Explanation elements, kept in document order
+	for _, child := range []struct {
+		table string
+		field func(*TargetLang) *[]string
+	}{
+		{"translations", func(t *TargetLang) *[]string { return &t.Translations }},
+		{"synonyms", func(t *TargetLang) *[]string { return &t.Synonyms }},
+		{"target_comments", func(t *TargetLang) *[]string { return &t.CommentElems }},
+		{"target_explanations", func(t *TargetLang) *[]string { return &t.Explanations }},
+	} {
+		query := `
+			SELECT target_lang_id, content
+			FROM ` + child.table + `
+			WHERE target_lang_id IN (%s)
+			ORDER BY target_lang_id, position, id
+		`
+		err := d.eachRow(ctx, query, [][]int64{targetIDs}, func(rows *sql.Rows) error {
+			var targetID int64
+			var content string
+			if err := rows.Scan(&targetID, &content); err != nil {
+				return err
+			}
+			field := child.field(targets[targetID])
+			*field = append(*field, content)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to load %s: %w", child.table, err)
+		}
+	}
+
+	return nil
+}
+
+// loadSharedChildren loads the tables that hang off either base_langs or
+// target_langs
+func (d *DB) loadSharedChildren(ctx context.Context, bases map[int64]*BaseLang, targets map[int64]*TargetLang, baseIDs, targetIDs []int64) error {
+	if len(baseIDs) == 0 && len(targetIDs) == 0 {
+		return nil
+	}
+
+	// The same id list is bound twice, once for each owner column. An
+	// empty list is replaced by an id that never exists.
+	ids := [][]int64{orNone(baseIDs), orNone(targetIDs)}
+	where := `WHERE base_lang_id IN (%s) OR target_lang_id IN (%s)`
+
+	// antonyms
+	err := d.eachRow(ctx, `
+		SELECT base_lang_id, target_lang_id, value, target_word_id
+		FROM antonyms
+		`+where+`
+		ORDER BY position, id
+	`, ids, func(rows *sql.Rows) error {
+		var baseID, targetID, targetWordID sql.NullInt64
+		var ant Antonym
+		if err := rows.Scan(&baseID, &targetID, &ant.Value, &targetWordID); err != nil {
+			return err
+		}
+		ant.TargetWordID = targetWordID.Int64
+		if baseID.Valid {
+			bases[baseID.Int64].Antonyms = append(bases[baseID.Int64].Antonyms, ant)
+		} else {
+			targets[targetID.Int64].Antonyms = append(targets[targetID.Int64].Antonyms, ant)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load antonyms: %w", err)
+	}
+
+	// examples
+	err = d.eachRow(ctx, `
+		SELECT id, base_lang_id, target_lang_id, content, original_id, matching_id, matched_example_id
+		FROM examples
+		`+where+`
+		ORDER BY position, id
+	`, ids, func(rows *sql.Rows) error {
+		var baseID, targetID, matched sql.NullInt64
+		var example Example
+		var matchingID sql.NullString
+		if err := rows.Scan(&example.RowID, &baseID, &targetID, &example.Content, &example.ID, &matchingID, &matched); err != nil {
+			return err
+		}
+		example.MatchingID = matchingID.String
+		example.MatchedID = matched.Int64
+		if baseID.Valid {
+			bases[baseID.Int64].Examples = append(bases[baseID.Int64].Examples, example)
+		} else {
+			targets[targetID.Int64].Examples = append(targets[targetID.Int64].Examples, example)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load examples: %w", err)
+	}
+
+	// idioms
+	err = d.eachRow(ctx, `
+		SELECT id, base_lang_id, target_lang_id, content, original_id, matching_id, matched_idiom_id
+		FROM idioms
+		`+where+`
+		ORDER BY position, id
+	`, ids, func(rows *sql.Rows) error {
+		var baseID, targetID, matched sql.NullInt64
+		var idiom Idiom
+		var matchingID sql.NullString
+		if err := rows.Scan(&idiom.RowID, &baseID, &targetID, &idiom.Content, &idiom.ID, &matchingID, &matched); err != nil {
+			return err
+		}
+		idiom.MatchingID = matchingID.String
+		idiom.MatchedID = matched.Int64
+		if baseID.Valid {
+			bases[baseID.Int64].Idioms = append(bases[baseID.Int64].Idioms, idiom)
+		} else {
+			targets[targetID.Int64].Idioms = append(targets[targetID.Int64].Idioms, idiom)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load idioms: %w", err)
+	}
+
+	// compounds and their inflections
+	compounds := make(map[int64]childRef)
+	err = d.eachRow(ctx, `
+		SELECT id, base_lang_id, target_lang_id, content, original_id, description, matching_id, matched_compound_id
+		FROM compounds
+		`+where+`
+		ORDER BY position, id
+	`, ids, func(rows *sql.Rows) error {
+		var id int64
+		var baseID, targetID, matched sql.NullInt64
+		var compound Compound
+		var content, description, matchingID sql.NullString
+		if err := rows.Scan(&id, &baseID, &targetID, &content, &compound.ID, &description, &matchingID, &matched); err != nil {
+			return err
+		}
+		compound.RowID = id
+		compound.MatchedID = matched.Int64
+		compound.Content = content.String
+		compound.Description = description.String
+		compound.MatchingID = matchingID.String
+		if baseID.Valid {
+			base := bases[baseID.Int64]
+			compounds[id] = childRef{base: true, ownerID: baseID.Int64, index: len(base.Compounds)}
+			base.Compounds = append(base.Compounds, compound)
+		} else {
+			target := targets[targetID.Int64]
+			compounds[id] = childRef{ownerID: targetID.Int64, index: len(target.Compounds)}
+			target.Compounds = append(target.Compounds, compound)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load compounds: %w", err)
+	}
+
+	if len(compounds) > 0 {
+		err = d.eachRow(ctx, `
+			SELECT compound_id, content
+			FROM compound_inflections
+			WHERE compound_id IN (%s)
+			ORDER BY compound_id, position, id
+		`, [][]int64{refIDs(compounds)}, func(rows *sql.Rows) error {
+			var compoundID int64
+			var content string
+			if err := rows.Scan(&compoundID, &content); err != nil {
+				return err
+			}
+			ref := compounds[compoundID]
+			var compound *Compound
+			if ref.base {
+				compound = &bases[ref.ownerID].Compounds[ref.index]
+			} else {
+				compound = &targets[ref.ownerID].Compounds[ref.index]
+			}
+			if compound.Inflection == "" {
+				compound.Inflection = content
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to load compound inflections: %w", err)
+		}
+	}
+
+	// derivations and their inflections
+	derivations := make(map[int64]childRef)
+	err = d.eachRow(ctx, `
+		SELECT id, base_lang_id, target_lang_id, content, original_id, description
+		FROM derivations
+		`+where+`
+		ORDER BY position, id
+	`, ids, func(rows *sql.Rows) error {
+		var id int64
+		var baseID, targetID sql.NullInt64
+		var derivation Derivation
+		var content, description sql.NullString
+		if err := rows.Scan(&id, &baseID, &targetID, &content, &derivation.ID, &description); err != nil {
+			return err
+		}
+		derivation.Content = content.String
+		derivation.Description = description.String
+		if baseID.Valid {
+			base := bases[baseID.Int64]
+			derivations[id] = childRef{base: true, ownerID: baseID.Int64, index: len(base.Derivations)}
+			base.Derivations = append(base.Derivations, derivation)
+		} else {
+			target := targets[targetID.Int64]
+			derivations[id] = childRef{ownerID: targetID.Int64, index: len(target.Derivations)}
+			target.Derivations = append(target.Derivations, derivation)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load derivations: %w", err)
+	}
+
+	if len(derivations) > 0 {
+		err = d.eachRow(ctx, `
+			SELECT derivation_id, content
+			FROM derivation_inflections
+			WHERE derivation_id IN (%s)
+			ORDER BY derivation_id, position, id
+		`, [][]int64{refIDs(derivations)}, func(rows *sql.Rows) error {
+			var derivationID int64
+			var content string
+			if err := rows.Scan(&derivationID, &content); err != nil {
+				return err
+			}
+			ref := derivations[derivationID]
+			var derivation *Derivation
+			if ref.base {
+				derivation = &bases[ref.ownerID].Derivations[ref.index]
+			} else {
+				derivation = &targets[ref.ownerID].Derivations[ref.index]
+			}
+			if derivation.Inflection == "" {
+				derivation.Inflection = content
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to load derivation inflections: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// eachRow runs query with one "IN (%s)" placeholder list per entry of ids
+// and calls fn for every row
+func (d *DB) eachRow(ctx context.Context, query string, ids [][]int64, fn func(*sql.Rows) error) error {
+	lists := make([]interface{}, 0, len(ids))
+	var args []interface{}
+	for _, list := range ids {
+		lists = append(lists, placeholders(len(list)))
+		for _, id := range list {
+			args = append(args, id)
+		}
+	}
+
+	rows, err := d.db.GetDB().QueryContext(ctx, fmt.Sprintf(query, lists...), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := fn(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// placeholders returns n comma separated question marks
+func placeholders(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strings.Repeat("?, ", n-1) + "?"
+}
+
+// orNone returns ids, or a list holding an id that never exists when ids is
+// empty, so that it can still be used in an IN clause
+func orNone(ids []int64) []int64 {
+	if len(ids) == 0 {
+		return []int64{0}
+	}
+	return ids
+}
+
+// refIDs returns the keys of a childRef map
+func refIDs(refs map[int64]childRef) []int64 {
+	ids := make([]int64, 0, len(refs))
+	for id := range refs {
+		ids = append(ids, id)
+	}
+	return ids
+}
@@ -0,0 +1,318 @@
+package lexin
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// hydrateDocument has a word with two senses, each with its own target
+// language, examples, idioms, compounds and inflection variants
+const hydrateDocument = `<?xml version="1.0" encoding="UTF-8"?>
+<Dictionary BaseLang="swe" TargetLang="eng" Version="1">
+<Word Value="hus" Variant="" Type="subst." ID="1" VariantID="1">
+  <BaseLang>
+    <Meaning MatchingID="m1">byggnad för boende</Meaning>
+    <Reference TYPE="see" VALUE="bostad"/>
+    <Comment>vanligt</Comment>
+    <Usage>om bostäder</Usage>
+    <Phonetic File="hus.mp3">hu:s</Phonetic>
+    <Inflection>huset<Variant Description="plural">husen</Variant><Variant Description="genitiv">husets</Variant></Inflection>
+    <Graminfo>ett</Graminfo>
+    <Example ID="10">ett stort hus</Example>
+    <Example ID="11">bo i hus</Example>
+    <Idiom ID="20">hålla hus</Idiom>
+    <Compound ID="30" Description="fordon">hus~bil<Inflection>husbilen</Inflection></Compound>
+    <Derivation ID="40">hus~lig<Inflection>husligt</Inflection></Derivation>
+    <Index Value="hus" type="prefix"/>
+  </BaseLang>
+  <BaseLang>
+    <Meaning>hushåll</Meaning>
+    <Antonym Value="ensamhushåll"/>
+    <Inflection>huset</Inflection>
+    <Example ID="12">hela huset sov</Example>
+  </BaseLang>
+  <TargetLang>
+    <Translation>house</Translation>
+    <Translation>building</Translation>
+    <Synonym>dwelling</Synonym>
+    <Example MatchingID="10">a big house</Example>
+    <Example MatchingID="11">live in a house</Example>
+    <Idiom MatchingID="20">keep house</Idiom>
+    <Compound MatchingID="30">camper</Compound>
+  </TargetLang>
+  <TargetLang Comment="figurativt">
+    <Translation>household</Translation>
+    <Explanation>the people in a home</Explanation>
+    <Example MatchingID="12">the whole household slept</Example>
+  </TargetLang>
+</Word>
+<Word Value="bostad" Type="subst." ID="2" VariantID="1"/>
+</Dictionary>
+`
+
+// wantHydrated is the hus entry of hydrateDocument without its row IDs
+var wantHydrated = Entry{
+	Value:      "hus",
+	Type:       "subst.",
+	OriginalID: "1",
+	VariantID:  "1",
+	BaseLangs: []BaseLang{
+		{
+			Meaning:    Meaning{Content: "byggnad för boende", MatchingID: "m1"},
+			References: []Reference{{Type: "see", Value: "bostad"}},
+			Comments:   []Comment{{Content: "vanligt"}},
+			Usages:     []Usage{{Content: "om bostäder"}},
+			Phonetic:   &Phonetic{Content: "hu:s", File: "hus.mp3"},
+			Inflections: []Inflection{{
+				Content: "huset",
+				Variants: []Variant{
+					{Content: "husen", Description: "plural"},
+					{Content: "husets", Description: "genitiv"},
+				},
+			}},
+			Graminfo: "ett",
+			Examples: []Example{
+				{Content: "ett stort hus", ID: "10"},
+				{Content: "bo i hus", ID: "11"},
+			},
+			Idioms:      []Idiom{{Content: "hålla hus", ID: "20"}},
+			Compounds:   []Compound{{Content: "hus~bil", ID: "30", Description: "fordon", Inflection: "husbilen"}},
+			Derivations: []Derivation{{Content: "hus~lig", ID: "40", Inflection: "husligt"}},
+			Indexes:     []Index{{Value: "hus", Type: "prefix"}},
+		},
+		{
+			Meaning:     Meaning{Content: "hushåll"},
+			Antonyms:    []Antonym{{Value: "ensamhushåll"}},
+			Inflections: []Inflection{{Content: "huset"}},
+			Examples:    []Example{{Content: "hela huset sov", ID: "12"}},
+		},
+	},
+	TargetLangs: []TargetLang{
+		{
+			Translations: []string{"house", "building"},
+			Synonyms:     []string{"dwelling"},
+			Examples: []Example{
+				{Content: "a big house", MatchingID: "10"},
+				{Content: "live in a house", MatchingID: "11"},
+			},
+			Idioms:    []Idiom{{Content: "keep house", MatchingID: "20"}},
+			Compounds: []Compound{{Content: "camper", MatchingID: "30"}},
+		},
+		{
+			Comment:      "figurativt",
+			Translations: []string{"household"},
+			Explanations: []string{"the people in a home"},
+			Examples:     []Example{{Content: "the whole household slept", MatchingID: "12"}},
+		},
+	},
+}
+
+// withoutRowIDs returns a copy of entry with the database IDs and links
+// cleared, leaving what was read from the document
+func withoutRowIDs(entry Entry) Entry {
+	entry.ID, entry.DictionaryID = 0, 0
+	entry.Form = nil
+	bases := make([]BaseLang, len(entry.BaseLangs))
+	for i, base := range entry.BaseLangs {
+		base.ID, base.MatchedTargetLangID = 0, 0
+		base.References = clearRows(base.References, func(r *Reference) { r.TargetWordID = 0 })
+		base.Antonyms = clearRows(base.Antonyms, func(a *Antonym) { a.TargetWordID = 0 })
+		base.Examples = clearRows(base.Examples, func(e *Example) { e.RowID, e.MatchedID = 0, 0 })
+		base.Idioms = clearRows(base.Idioms, func(i *Idiom) { i.RowID, i.MatchedID = 0, 0 })
+		base.Compounds = clearRows(base.Compounds, func(c *Compound) { c.RowID, c.MatchedID = 0, 0 })
+		bases[i] = base
+	}
+	entry.BaseLangs = bases
+	targets := make([]TargetLang, len(entry.TargetLangs))
+	for i, target := range entry.TargetLangs {
+		target.ID = 0
+		target.Examples = clearRows(target.Examples, func(e *Example) { e.RowID, e.MatchedID = 0, 0 })
+		target.Idioms = clearRows(target.Idioms, func(i *Idiom) { i.RowID, i.MatchedID = 0, 0 })
+		target.Compounds = clearRows(target.Compounds, func(c *Compound) { c.RowID, c.MatchedID = 0, 0 })
+		targets[i] = target
+	}
+	entry.TargetLangs = targets
+	return entry
+}
+
+// clearRows returns a copy of items with clear applied to each
+func clearRows[T any](items []T, clear func(*T)) []T {
+	if items == nil {
+		return nil
+	}
+	out := make([]T, len(items))
+	for i := range items {
+		out[i] = items[i]
+		clear(&out[i])
+	}
+	return out
+}
+
+// checkHydrated compares a hydrated hus entry with wantHydrated field by
+// field, then checks the links between its two language sides
+func checkHydrated(t *testing.T, got Entry, bostadID int64) {
+	t.Helper()
+
+	plain := withoutRowIDs(got)
+	if plain.Value != wantHydrated.Value || plain.Type != wantHydrated.Type ||
+		plain.OriginalID != wantHydrated.OriginalID || plain.VariantID != wantHydrated.VariantID {
+		t.Errorf("word = %s %s %s/%s, want %s %s %s/%s", plain.Value, plain.Type, plain.OriginalID, plain.VariantID,
+			wantHydrated.Value, wantHydrated.Type, wantHydrated.OriginalID, wantHydrated.VariantID)
+	}
+	if len(plain.BaseLangs) != len(wantHydrated.BaseLangs) || len(plain.TargetLangs) != len(wantHydrated.TargetLangs) {
+		t.Fatalf("got %d base and %d target languages, want %d and %d",
+			len(plain.BaseLangs), len(plain.TargetLangs), len(wantHydrated.BaseLangs), len(wantHydrated.TargetLangs))
+	}
+	for i := range wantHydrated.BaseLangs {
+		compareFields(t, "BaseLangs", i, plain.BaseLangs[i], wantHydrated.BaseLangs[i])
+	}
+	for i := range wantHydrated.TargetLangs {
+		compareFields(t, "TargetLangs", i, plain.TargetLangs[i], wantHydrated.TargetLangs[i])
+	}
+
+	// Every row has an ID, and the links point across the sides
+	for i, base := range got.BaseLangs {
+		if base.ID == 0 || got.TargetLangs[i].ID == 0 {
+			t.Errorf("sense %d has no row IDs", i)
+		}
+		if base.MatchedTargetLangID != got.TargetLangs[i].ID {
+			t.Errorf("BaseLangs[%d].MatchedTargetLangID = %d, want %d", i, base.MatchedTargetLangID, got.TargetLangs[i].ID)
+		}
+		for j, example := range base.Examples {
+			target := got.TargetLangs[i].Examples[j]
+			if example.RowID == 0 || example.MatchedID != target.RowID || target.MatchedID != example.RowID {
+				t.Errorf("BaseLangs[%d].Examples[%d] row %d matched %d, target row %d matched %d",
+					i, j, example.RowID, example.MatchedID, target.RowID, target.MatchedID)
+			}
+		}
+	}
+	base, target := got.BaseLangs[0], got.TargetLangs[0]
+	if base.Idioms[0].MatchedID != target.Idioms[0].RowID || base.Compounds[0].MatchedID != target.Compounds[0].RowID {
+		t.Errorf("idiom and compound are not matched with their translations")
+	}
+	if base.References[0].TargetWordID != bostadID {
+		t.Errorf("reference target = %d, want %d", base.References[0].TargetWordID, bostadID)
+	}
+}
+
+// compareFields reports each field of got that differs from want
+func compareFields[T any](t *testing.T, name string, index int, got, want T) {
+	t.Helper()
+	g, w := reflect.ValueOf(got), reflect.ValueOf(want)
+	for i := 0; i < g.NumField(); i++ {
+		if !reflect.DeepEqual(g.Field(i).Interface(), w.Field(i).Interface()) {
+			t.Errorf("%s[%d].%s = %+v, want %+v", name, index, g.Type().Field(i).Name, g.Field(i).Interface(), w.Field(i).Interface())
+		}
+	}
+}
+
+func TestHydrateMultiSenseEntry(t *testing.T) {
+	ctx := context.Background()
+	db, dict := openTestDB(t, hydrateDocument)
+
+	bostad, err := db.Entry(ctx, dict, "2", "1")
+	if err != nil || bostad == nil {
+		t.Fatalf("failed to load bostad: %v", err)
+	}
+
+	t.Run("Lookup", func(t *testing.T) {
+		entries, err := db.Lookup(ctx, dict, "hus")
+		if err != nil {
+			t.Fatalf("lookup failed: %v", err)
+		}
+		if len(entries) != 1 {
+			t.Fatalf("got %d entries, want 1", len(entries))
+		}
+		if entries[0].Form != nil {
+			t.Errorf("headword lookup reports form %+v", entries[0].Form)
+		}
+		checkHydrated(t, entries[0], bostad.ID)
+	})
+
+	t.Run("Lookup by variant", func(t *testing.T) {
+		entries, err := db.Lookup(ctx, dict, "Husets")
+		if err != nil {
+			t.Fatalf("lookup failed: %v", err)
+		}
+		if len(entries) != 1 {
+			t.Fatalf("got %d entries, want 1", len(entries))
+		}
+		want := FormMatch{Form: "husets", Source: "variant", Description: "genitiv"}
+		if entries[0].Form == nil || *entries[0].Form != want {
+			t.Errorf("form = %+v, want %+v", entries[0].Form, want)
+		}
+		checkHydrated(t, entries[0], bostad.ID)
+	})
+
+	t.Run("EachEntry", func(t *testing.T) {
+		var got []Entry
+		err := db.EachEntry(ctx, dict, EntryFilter{}, func(entry Entry) error {
+			got = append(got, entry)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("EachEntry failed: %v", err)
+		}
+		if len(got) != 2 || got[0].Value != "hus" || got[1].Value != "bostad" {
+			t.Fatalf("got %d entries, want hus and bostad in import order", len(got))
+		}
+		checkHydrated(t, got[0], bostad.ID)
+		if len(got[1].BaseLangs) != 0 || len(got[1].TargetLangs) != 0 {
+			t.Errorf("bostad = %+v, want no languages", got[1])
+		}
+	})
+}
+
+func TestHydrateFollowsPosition(t *testing.T) {
+	ctx := context.Background()
+	db, dict := openTestDB(t, hydrateDocument)
+
+	// Reverse the stored order of the senses, the translations of the
+	// first TargetLang and the inflection variants, so that the row IDs
+	// disagree with the positions
+	for _, query := range []string{
+		`UPDATE base_langs SET position = 1 - position`,
+		`UPDATE target_langs SET position = 1 - position`,
+		`UPDATE translations SET position = 1 - position
+		 WHERE target_lang_id = (SELECT MIN(id) FROM target_langs)`,
+		`UPDATE examples SET position = 1 - position
+		 WHERE base_lang_id = (SELECT MIN(id) FROM base_langs)`,
+		`UPDATE inflection_variants SET position = 1 - position`,
+	} {
+		if _, err := db.db.GetDB().Exec(query); err != nil {
+			t.Fatalf("failed to reorder rows: %v", err)
+		}
+	}
+
+	entries, err := db.Lookup(ctx, dict, "hus")
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("lookup = %d entries, %v", len(entries), err)
+	}
+	entry := entries[0]
+
+	if got := []string{entry.BaseLangs[0].Meaning.Content, entry.BaseLangs[1].Meaning.Content}; !reflect.DeepEqual(got, []string{"hushåll", "byggnad för boende"}) {
+		t.Errorf("meanings = %q, want them in position order", got)
+	}
+	if got := entry.TargetLangs[1].Translations; !reflect.DeepEqual(got, []string{"building", "house"}) {
+		t.Errorf("translations = %q, want them in position order", got)
+	}
+	first := entry.BaseLangs[1]
+	var examples []string
+	for _, example := range first.Examples {
+		examples = append(examples, example.Content)
+	}
+	if !reflect.DeepEqual(examples, []string{"bo i hus", "ett stort hus"}) {
+		t.Errorf("examples = %q, want them in position order", examples)
+	}
+	if len(first.Inflections) == 0 {
+		t.Fatal("the first sense has no inflections")
+	}
+	var variants []string
+	for _, variant := range first.Inflections[0].Variants {
+		variants = append(variants, variant.Content)
+	}
+	if !reflect.DeepEqual(variants, []string{"husets", "husen"}) {
+		t.Errorf("inflection variants = %q, want them in position order", variants)
+	}
+}
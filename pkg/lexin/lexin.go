@@ -0,0 +1,135 @@
+// Package lexin reads dictionaries imported by lexin-sqlite. It assembles
+// complete entries from the normalised tables so that callers do not have to
+// write the joins themselves.
+package lexin
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"lexin-sqlite/internal/database"
+	"lexin-sqlite/internal/search"
+)
+
+// DB is a handle to a lexin-sqlite database
+type DB struct {
+	db *database.DB
+}
+
+// Dictionary describes one imported dictionary
+type Dictionary struct {
+	ID         int64     `json:"id"`
+	BaseLang   string    `json:"base_lang"`
+	TargetLang string    `json:"target_lang"`
+	Version    string    `json:"version"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Name returns the name of the dictionary made of its languages, such as
+// "swe-eng"
+func (d Dictionary) Name() string {
+	return d.BaseLang + "-" + d.TargetLang
+}
+
+// SearchHit is a full-text search match
+type SearchHit = search.Hit
+
+// SearchOptions narrows a full-text search
+type SearchOptions = search.Options
+
+// Open opens a database, applying pending schema migrations
+func Open(path string) (*DB, error) {
+	db, err := database.New(path)
+	if err != nil {
+		return nil, err
+	}
+	return &DB{db: db}, nil
+}
+
+// OpenReadOnly opens an existing database for reading only, as servers do.
+// It fails when the schema has pending migrations, since they cannot be
+// applied without write access.
+func OpenReadOnly(path string) (*DB, error) {
+	db, err := database.OpenReadOnly(path)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses, err := db.MigrationStatus(context.Background())
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	for _, status := range statuses {
+		if !status.Applied {
+			db.Close()
+			return nil, fmt.Errorf("database schema is out of date, migration %d (%s) is pending", status.Version, status.Name)
+		}
+	}
+
+	return &DB{db: db}, nil
+}
+
+// Close closes the database
+func (d *DB) Close() error {
+	return d.db.Close()
+}
+
+// Dictionaries lists every dictionary in the database
+func (d *DB) Dictionaries(ctx context.Context) ([]Dictionary, error) {
+	rows, err := d.db.GetDB().QueryContext(ctx, `
+		SELECT id, base_lang, target_lang, version, created_at
+		FROM dictionaries
+		ORDER BY base_lang, target_lang
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dictionaries: %w", err)
+	}
+	defer rows.Close()
+
+	dictionaries := []Dictionary{}
+	for rows.Next() {
+		var dict Dictionary
+		if err := rows.Scan(&dict.ID, &dict.BaseLang, &dict.TargetLang, &dict.Version, &dict.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to list dictionaries: %w", err)
+		}
+		dictionaries = append(dictionaries, dict)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list dictionaries: %w", err)
+	}
+
+	return dictionaries, nil
+}
+
+// Dictionary finds a dictionary by its languages. It returns nil and no
+// error when there is no such dictionary.
+func (d *DB) Dictionary(ctx context.Context, baseLang, targetLang string) (*Dictionary, error) {
+	var dict Dictionary
+	err := d.db.GetDB().QueryRowContext(ctx, `
+		SELECT id, base_lang, target_lang, version, created_at
+		FROM dictionaries
+		WHERE base_lang = ? AND target_lang = ?
+		LIMIT 1
+	`, baseLang, targetLang).Scan(&dict.ID, &dict.BaseLang, &dict.TargetLang, &dict.Version, &dict.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find dictionary: %w", err)
+	}
+
+	return &dict, nil
+}
+
+// Search runs a full-text search over meanings, examples, idioms,
+// explanations and translations of dict, or of every dictionary when dict
+// is nil
+func (d *DB) Search(ctx context.Context, dict *Dictionary, query string, opts SearchOptions) ([]SearchHit, error) {
+	if dict != nil {
+		opts.DictionaryID = dict.ID
+	}
+	return search.New(d.db).Search(ctx, query, opts)
+}
@@ -0,0 +1,75 @@
+package lexin
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"lexin-sqlite/internal/database"
+	"lexin-sqlite/internal/parser"
+	"lexin-sqlite/internal/repository"
+)
+
+// openTestDB imports a Lexin XML document into a database in a temporary
+// directory, the way lexin import does, and returns its dictionary
+func openTestDB(t *testing.T, document string) (*DB, *Dictionary) {
+	t.Helper()
+
+	db, err := Open(filepath.Join(t.TempDir(), "lexin.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db, importTestXML(t, db, document)
+}
+
+// importTestXML imports a Lexin XML document into db and links it
+func importTestXML(t *testing.T, db *DB, document string) *Dictionary {
+	t.Helper()
+	ctx := context.Background()
+
+	src, err := parser.NewReader(strings.NewReader(document))
+	if err != nil {
+		t.Fatalf("failed to read XML: %v", err)
+	}
+	repo := repository.New(db.db)
+	if _, err := repo.UpsertSource(ctx, src); err != nil {
+		t.Fatalf("failed to import: %v", err)
+	}
+
+	header := src.Header()
+	dict, err := db.Dictionary(ctx, header.BaseLang, header.TargetLang)
+	if err != nil || dict == nil {
+		t.Fatalf("failed to find dictionary: %v", err)
+	}
+	if _, err := repo.LinkDictionary(ctx, dict.ID); err != nil {
+		t.Fatalf("failed to link: %v", err)
+	}
+
+	return dict
+}
+
+func TestOpenReadOnlyReportsPendingMigrations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "legacy.db")
+
+	// A database from before migrations has tables but no
+	// schema_migrations
+	db, err := database.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	if _, err := db.GetDB().Exec(`CREATE TABLE words (id INTEGER PRIMARY KEY, value TEXT)`); err != nil {
+		t.Fatalf("failed to create table: %v", err)
+	}
+	db.Close()
+
+	_, err = OpenReadOnly(path)
+	if err == nil {
+		t.Fatal("OpenReadOnly accepted a database with pending migrations")
+	}
+	if !strings.Contains(err.Error(), "schema is out of date") {
+		t.Errorf("OpenReadOnly error = %q, want a pending migration error", err)
+	}
+}
@@ -0,0 +1,148 @@
+package lexin
+
+import (
+	"context"
+	"maps"
+	"testing"
+)
+
+// linkDocument has two base languages reusing the same example ID, so that
+// only the link, which prefers the TargetLang at the same position, tells
+// their translations apart
+const linkDocument = `<?xml version="1.0" encoding="UTF-8"?>
+<Dictionary BaseLang="swe" TargetLang="eng" Version="1">
+<Word Value="hus" Type="subst." ID="1" VariantID="1">
+  <BaseLang>
+    <Meaning>byggnad</Meaning>
+    <Reference TYPE="see" VALUE="bostad"/>
+    <Reference TYPE="compare" VALUE="saknas"/>
+    <Reference TYPE="phonetic" VALUE="hus.mp3"/>
+    <Antonym Value="stugor"/>
+    <Example ID="10">ett stort hus</Example>
+    <Idiom ID="20">hålla hus</Idiom>
+    <Compound ID="30">hus~bil</Compound>
+  </BaseLang>
+  <BaseLang>
+    <Meaning>hushåll</Meaning>
+    <Example ID="10">hela huset</Example>
+  </BaseLang>
+  <TargetLang>
+    <Translation>house</Translation>
+    <Example MatchingID="10">a big house</Example>
+    <Idiom MatchingID="20">keep house</Idiom>
+    <Compound MatchingID="30">camper</Compound>
+  </TargetLang>
+  <TargetLang>
+    <Translation>household</Translation>
+    <Example MatchingID="10">the whole household</Example>
+  </TargetLang>
+</Word>
+<Word Value="bostad" Type="subst." ID="2" VariantID="1"/>
+<Word Value="stuga" Type="subst." ID="3" VariantID="1">
+  <BaseLang><Inflection>stugor</Inflection></BaseLang>
+</Word>
+</Dictionary>
+`
+
+func TestEntryLinks(t *testing.T) {
+	ctx := context.Background()
+	db, dict := openTestDB(t, linkDocument)
+
+	entry, err := db.Entry(ctx, dict, "1", "1")
+	if err != nil || entry == nil {
+		t.Fatalf("failed to load entry: %v", err)
+	}
+	bostad, err := db.Entry(ctx, dict, "2", "1")
+	if err != nil || bostad == nil {
+		t.Fatalf("failed to load entry: %v", err)
+	}
+	stuga, err := db.Entry(ctx, dict, "3", "1")
+	if err != nil || stuga == nil {
+		t.Fatalf("failed to load entry: %v", err)
+	}
+
+	first, second := entry.BaseLangs[0], entry.BaseLangs[1]
+	target1, target2 := entry.TargetLangs[0], entry.TargetLangs[1]
+
+	// Meanings are matched with the TargetLang at their position
+	if first.MatchedTargetLangID != target1.ID || second.MatchedTargetLangID != target2.ID {
+		t.Errorf("matched target langs = %d, %d, want %d, %d",
+			first.MatchedTargetLangID, second.MatchedTargetLangID, target1.ID, target2.ID)
+	}
+
+	// References and antonyms point at headwords, or at the headword of an
+	// inflected form; missing words and files stay unlinked
+	refs := map[string]int64{}
+	for _, ref := range first.References {
+		refs[ref.Value] = ref.TargetWordID
+	}
+	if want := map[string]int64{"bostad": bostad.ID, "saknas": 0, "hus.mp3": 0}; !maps.Equal(refs, want) {
+		t.Errorf("reference links = %v, want %v", refs, want)
+	}
+	if got := first.Antonyms[0].TargetWordID; got != stuga.ID {
+		t.Errorf("antonym link = %d, want %d", got, stuga.ID)
+	}
+
+	// Examples, idioms and compounds are linked in both directions, each
+	// to the one in the TargetLang at the same position
+	tests := []struct {
+		name          string
+		base, target  int64
+		baseMatched   int64
+		targetMatched int64
+	}{
+		{"first example", first.Examples[0].RowID, target1.Examples[0].RowID, first.Examples[0].MatchedID, target1.Examples[0].MatchedID},
+		{"second example", second.Examples[0].RowID, target2.Examples[0].RowID, second.Examples[0].MatchedID, target2.Examples[0].MatchedID},
+		{"idiom", first.Idioms[0].RowID, target1.Idioms[0].RowID, first.Idioms[0].MatchedID, target1.Idioms[0].MatchedID},
+		{"compound", first.Compounds[0].RowID, target1.Compounds[0].RowID, first.Compounds[0].MatchedID, target1.Compounds[0].MatchedID},
+	}
+	for _, tt := range tests {
+		if tt.base == 0 || tt.target == 0 {
+			t.Errorf("%s: row ids not loaded", tt.name)
+			continue
+		}
+		if tt.baseMatched != tt.target || tt.targetMatched != tt.base {
+			t.Errorf("%s: base %d -> %d, target %d -> %d", tt.name, tt.base, tt.baseMatched, tt.target, tt.targetMatched)
+		}
+	}
+}
+
+func TestLinksFoldCapitalisedSwedishLetters(t *testing.T) {
+	ctx := context.Background()
+	db, dict := openTestDB(t, `<Dictionary BaseLang="swe" TargetLang="eng" Version="1">
+<Word Value="ägg" Type="subst." ID="1" VariantID="1">
+  <BaseLang><Inflection>ägget äggen</Inflection></BaseLang>
+</Word>
+<Word Value="över" Type="prep." ID="2" VariantID="1"/>
+<Word Value="ordet" Type="subst." ID="3" VariantID="1">
+  <BaseLang>
+    <Reference TYPE="see" VALUE="Ägg"/>
+    <Reference TYPE="compare" VALUE="ÄGGET"/>
+    <Antonym Value="Över"/>
+  </BaseLang>
+</Word>
+</Dictionary>`)
+
+	entry, err := db.Entry(ctx, dict, "3", "1")
+	if err != nil || entry == nil {
+		t.Fatalf("failed to load entry: %v", err)
+	}
+	agg, err := db.Entry(ctx, dict, "1", "1")
+	if err != nil || agg == nil {
+		t.Fatalf("failed to load entry: %v", err)
+	}
+	over, err := db.Entry(ctx, dict, "2", "1")
+	if err != nil || over == nil {
+		t.Fatalf("failed to load entry: %v", err)
+	}
+
+	base := entry.BaseLangs[0]
+	for _, ref := range base.References {
+		if ref.TargetWordID != agg.ID {
+			t.Errorf("reference %q links to %d, want %d", ref.Value, ref.TargetWordID, agg.ID)
+		}
+	}
+	if got := base.Antonyms[0].TargetWordID; got != over.ID {
+		t.Errorf("antonym Över links to %d, want %d", got, over.ID)
+	}
+}
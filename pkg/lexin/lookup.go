@@ -0,0 +1,165 @@
+package lexin
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"lexin-sqlite/internal/parser"
+)
+
+// Lookup returns the entries whose headword is word, in dict or in every
+// dictionary when dict is nil. When no headword matches, word is resolved
+// through the inflected forms index ("huset" finds "hus", "gick" finds
+// "gå") and each entry reports the form that matched in Entry.Form.
+func (d *DB) Lookup(ctx context.Context, dict *Dictionary, word string) ([]Entry, error) {
+	query := `
+		SELECT id, dictionary_id, value, variant, type, original_id, variant_id, matching_id
+		FROM words
+		WHERE value = ?`
+	args := []interface{}{word}
+	if dict != nil {
+		query += ` AND dictionary_id = ?`
+		args = append(args, dict.ID)
+	}
+	query += ` ORDER BY dictionary_id, id`
+
+	entries, err := d.queryEntries(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up %q: %w", word, err)
+	}
+	if len(entries) > 0 {
+		return entries, nil
+	}
+
+	entries, err = d.lookupForm(ctx, dict, word)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up %q: %w", word, err)
+	}
+
+	return entries, nil
+}
+
+// lookupForm returns the entries that have word as an inflected, compound
+// or derived form
+func (d *DB) lookupForm(ctx context.Context, dict *Dictionary, word string) ([]Entry, error) {
+	query := `
+		SELECT f.word_id, f.form, f.source, f.description
+		FROM word_forms f
+		JOIN words w ON w.id = f.word_id
+		WHERE f.form = ?`
+	args := []interface{}{parser.NormalizeForm(word)}
+	if dict != nil {
+		query += ` AND w.dictionary_id = ?`
+		args = append(args, dict.ID)
+	}
+
+	rows, err := d.db.GetDB().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	matches := make(map[int64]*FormMatch)
+	var ids []int64
+	for rows.Next() {
+		var wordID int64
+		var match FormMatch
+		var description sql.NullString
+		if err := rows.Scan(&wordID, &match.Form, &match.Source, &description); err != nil {
+			return nil, err
+		}
+		match.Description = description.String
+		matches[wordID] = &match
+		ids = append(ids, wordID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if len(ids) == 0 {
+		return []Entry{}, nil
+	}
+
+	entries, err := d.entriesByID(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		entries[i].Form = matches[entries[i].ID]
+	}
+
+	return entries, nil
+}
+
+// Entry returns the entry with the given Lexin ID and VariantID, or nil when
+// there is none
+func (d *DB) Entry(ctx context.Context, dict *Dictionary, originalID, variantID string) (*Entry, error) {
+	entries, err := d.queryEntries(ctx, `
+		SELECT id, dictionary_id, value, variant, type, original_id, variant_id, matching_id
+		FROM words
+		WHERE dictionary_id = ? AND original_id = ? AND variant_id = ?
+		ORDER BY id
+		LIMIT 1
+	`, dict.ID, originalID, variantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load entry %s/%s: %w", originalID, variantID, err)
+	}
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	return &entries[0], nil
+}
+
+// EntriesByOriginalID returns every variant of the entry with the given
+// Lexin ID, in dict or in every dictionary when dict is nil
+func (d *DB) EntriesByOriginalID(ctx context.Context, dict *Dictionary, originalID string) ([]Entry, error) {
+	query := `
+		SELECT id, dictionary_id, value, variant, type, original_id, variant_id, matching_id
+		FROM words
+		WHERE original_id = ?`
+	args := []interface{}{originalID}
+	if dict != nil {
+		query += ` AND dictionary_id = ?`
+		args = append(args, dict.ID)
+	}
+	query += ` ORDER BY dictionary_id, variant_id, id`
+
+	entries, err := d.queryEntries(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load entry %s: %w", originalID, err)
+	}
+
+	return entries, nil
+}
+
+// queryEntries runs a query selecting the words columns in the order used by
+// scanWord and returns the fully hydrated entries
+func (d *DB) queryEntries(ctx context.Context, query string, args ...interface{}) ([]Entry, error) {
+	rows, err := d.db.GetDB().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []Entry{}
+	for rows.Next() {
+		entry, err := scanWord(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if err := d.hydrate(ctx, entries); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,299 @@
+package lexin
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MatchKind tells how well a reverse lookup term matched
+type MatchKind int
+
+// Match kinds, best first
+const (
+	// MatchExact means the whole translation, or one of its comma or
+	// semicolon separated alternatives, equals the term
+	MatchExact MatchKind = iota + 1
+	// MatchPrefix means the translation or an alternative starts with the term
+	MatchPrefix
+	// MatchToken means the term occurs as a word inside the translation
+	MatchToken
+)
+
+// String returns the name of the match kind
+func (k MatchKind) String() string {
+	switch k {
+	case MatchExact:
+		return "exact"
+	case MatchPrefix:
+		return "prefix"
+	case MatchToken:
+		return "token"
+	}
+	return fmt.Sprintf("MatchKind(%d)", int(k))
+}
+
+// MarshalText encodes the match kind by name
+func (k MatchKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// ParseMatchKind parses the name of a match kind
+func ParseMatchKind(name string) (MatchKind, error) {
+	for _, kind := range []MatchKind{MatchExact, MatchPrefix, MatchToken} {
+		if kind.String() == name {
+			return kind, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown match kind: %s", name)
+}
+
+// ReverseHit is a Swedish entry found through one of its translations
+type ReverseHit struct {
+	Entry Entry     `json:"entry"`
+	Match MatchKind `json:"match"`
+	// Source is "translation" or "synonym"
+	Source string `json:"source"`
+	// Matched is the stored translation or synonym that matched
+	Matched string `json:"matched"`
+}
+
+// ReverseOptions narrows a reverse lookup
+type ReverseOptions struct {
+	// Match is the loosest kind of match returned, MatchToken when zero
+	Match MatchKind
+	// Limit caps the number of hits, 20 when zero
+	Limit int
+	// Offset skips the first hits for pagination
+	Offset int
+}
+
+// reverseCandidateLimit caps the rows taken from each full-text index and
+// each prefix scan, so that very common words cannot make a lookup scan the
+// whole dictionary
+const reverseCandidateLimit = 500
+
+// reverseCandidate is the best hit found for a word before it is hydrated
+type reverseCandidate struct {
+	hit    ReverseHit
+	wordID int64
+	value  string
+}
+
+// ReverseLookup finds Swedish entries through their target language
+// translations and synonyms. Hits are ordered by match kind, then by how
+// short the matched text is, so "house" ranks an entry translated as
+// "house" above one translated as "house of cards".
+func (d *DB) ReverseLookup(ctx context.Context, dict *Dictionary, term string, opts ReverseOptions) ([]ReverseHit, error) {
+	term = strings.TrimSpace(term)
+	if term == "" {
+		return []ReverseHit{}, nil
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = 20
+	}
+	if opts.Match == 0 {
+		opts.Match = MatchToken
+	}
+
+	// Best hit per word
+	best := make(map[int64]*reverseCandidate)
+	for _, src := range []struct{ name, table string }{
+		{"translation", "translations"},
+		{"synonym", "synonyms"},
+	} {
+		// Candidates are gathered for every kind and classified below; an
+		// exact match on one alternative of "walk, go" is only found
+		// through the full-text index. The capped scans filter on the
+		// dictionary before their LIMIT, so that other dictionaries cannot
+		// use up the candidates.
+		likeScan := `SELECT id FROM ` + src.table + ` WHERE content LIKE ? ESCAPE '\'`
+		ftsScan := `SELECT rowid FROM ` + src.table + `_fts WHERE ` + src.table + `_fts MATCH ?`
+		phrase := `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+		args := []interface{}{term, escapeLike(term) + "%"}
+		if dict != nil {
+			scope := `
+					JOIN target_langs xt ON xt.id = x.target_lang_id
+					JOIN words xw ON xw.id = xt.word_id`
+			likeScan = `SELECT x.id FROM ` + src.table + ` x` + scope + `
+					WHERE x.content LIKE ? ESCAPE '\' AND xw.dictionary_id = ?`
+			ftsScan = `SELECT x.id FROM ` + src.table + `_fts
+					JOIN ` + src.table + ` x ON x.id = ` + src.table + `_fts.rowid` + scope + `
+					WHERE ` + src.table + `_fts MATCH ? AND xw.dictionary_id = ?`
+			args = append(args, dict.ID, phrase, dict.ID)
+		} else {
+			args = append(args, phrase)
+		}
+		conditions := []string{
+			`c.content = ? COLLATE NOCASE`,
+			fmt.Sprintf(`c.id IN (%s LIMIT %d)`, likeScan, reverseCandidateLimit),
+			fmt.Sprintf(`c.id IN (%s LIMIT %d)`, ftsScan, reverseCandidateLimit),
+		}
+
+		query := `
+			SELECT c.content, t.word_id, w.value
+			FROM ` + src.table + ` c
+			JOIN target_langs t ON t.id = c.target_lang_id
+			JOIN words w ON w.id = t.word_id
+			WHERE (` + strings.Join(conditions, " OR ") + `)`
+		if dict != nil {
+			query += ` AND w.dictionary_id = ?`
+			args = append(args, dict.ID)
+		}
+
+		rows, err := d.db.GetDB().QueryContext(ctx, query, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up %s: %w", src.table, err)
+		}
+		for rows.Next() {
+			var content, value string
+			var wordID int64
+			if err := rows.Scan(&content, &wordID, &value); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to look up %s: %w", src.table, err)
+			}
+
+			kind := classifyMatch(content, term)
+			if kind > opts.Match {
+				continue
+			}
+			hit := ReverseHit{Match: kind, Source: src.name, Matched: content}
+			if current, ok := best[wordID]; !ok || betterHit(&hit, &current.hit) {
+				best[wordID] = &reverseCandidate{hit: hit, wordID: wordID, value: value}
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up %s: %w", src.table, err)
+		}
+	}
+
+	// Rank and page before hydrating, so that only the returned entries
+	// are loaded
+	candidates := make([]*reverseCandidate, 0, len(best))
+	for _, candidate := range best {
+		candidates = append(candidates, candidate)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if betterHit(&a.hit, &b.hit) {
+			return true
+		}
+		if betterHit(&b.hit, &a.hit) {
+			return false
+		}
+		if a.value != b.value {
+			return a.value < b.value
+		}
+		return a.wordID < b.wordID
+	})
+
+	if opts.Offset >= len(candidates) {
+		return []ReverseHit{}, nil
+	}
+	candidates = candidates[opts.Offset:]
+	if len(candidates) > opts.Limit {
+		candidates = candidates[:opts.Limit]
+	}
+
+	ids := make([]int64, len(candidates))
+	for i, candidate := range candidates {
+		ids[i] = candidate.wordID
+	}
+	entries, err := d.entriesByID(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int64]Entry, len(entries))
+	for _, entry := range entries {
+		byID[entry.ID] = entry
+	}
+
+	hits := make([]ReverseHit, 0, len(candidates))
+	for _, candidate := range candidates {
+		entry, ok := byID[candidate.wordID]
+		if !ok {
+			continue
+		}
+		hit := candidate.hit
+		hit.Entry = entry
+		hits = append(hits, hit)
+	}
+	return hits, nil
+}
+
+// entriesByID loads fully hydrated entries by word id
+func (d *DB) entriesByID(ctx context.Context, ids []int64) ([]Entry, error) {
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+
+	entries, err := d.queryEntries(ctx, `
+		SELECT id, dictionary_id, value, variant, type, original_id, variant_id, matching_id
+		FROM words
+		WHERE id IN (`+placeholders(len(ids))+`)
+		ORDER BY id
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+// betterHit reports whether a ranks above b
+func betterHit(a, b *ReverseHit) bool {
+	if a.Match != b.Match {
+		return a.Match < b.Match
+	}
+	if len(a.Matched) != len(b.Matched) {
+		return len(a.Matched) < len(b.Matched)
+	}
+	// Translations are the primary rendering, synonyms come second
+	return a.Source == "translation" && b.Source != "translation"
+}
+
+// classifyMatch decides how content matched term. Lexin often lists several
+// renderings in one translation ("walk, go"), so each alternative is
+// compared on its own.
+func classifyMatch(content, term string) MatchKind {
+	content = strings.ToLower(strings.TrimSpace(content))
+	term = strings.ToLower(term)
+
+	alternatives := strings.FieldsFunc(content, func(r rune) bool {
+		return r == ',' || r == ';' || r == '/'
+	})
+	for i := range alternatives {
+		alternatives[i] = strings.TrimSpace(alternatives[i])
+	}
+
+	if content == term {
+		return MatchExact
+	}
+	for _, alt := range alternatives {
+		if alt == term {
+			return MatchExact
+		}
+	}
+
+	if strings.HasPrefix(content, term) {
+		return MatchPrefix
+	}
+	for _, alt := range alternatives {
+		if strings.HasPrefix(alt, term) {
+			return MatchPrefix
+		}
+	}
+
+	return MatchToken
+}
+
+// escapeLike escapes the LIKE wildcards in s, using \ as escape character
+func escapeLike(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `%`, `\%`)
+	return strings.ReplaceAll(s, `_`, `\_`)
+}
@@ -0,0 +1,111 @@
+package lexin
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// reverseTestXML holds words translated by phrases around "house"
+func reverseTestXML() string {
+	translations := []string{
+		"house of cards", "house", "home, house", "housing", "a house",
+		"the white house", "greenhouse", "house boat", "houses", "walk",
+	}
+	var b strings.Builder
+	b.WriteString(`<Dictionary BaseLang="swe" TargetLang="eng" Version="1">`)
+	for i, translation := range translations {
+		fmt.Fprintf(&b, `<Word Value="ord%02d" Type="subst." ID="%d" VariantID="1">
+			<BaseLang><Meaning>m</Meaning></BaseLang>
+			<TargetLang><Translation>%s</Translation></TargetLang>
+		</Word>`, i, i+1, translation)
+	}
+	b.WriteString(`</Dictionary>`)
+	return b.String()
+}
+
+func TestReverseLookupRanksAndPages(t *testing.T) {
+	ctx := context.Background()
+	db, dict := openTestDB(t, reverseTestXML())
+
+	all, err := db.ReverseLookup(ctx, dict, "house", ReverseOptions{Limit: 100})
+	if err != nil {
+		t.Fatalf("ReverseLookup failed: %v", err)
+	}
+
+	var got []string
+	for _, hit := range all {
+		got = append(got, fmt.Sprintf("%s %s", hit.Match, hit.Matched))
+	}
+	want := []string{
+		"exact house",
+		"exact home, house",
+		"prefix houses",
+		"prefix house boat",
+		"prefix house of cards",
+		"token a house",
+		"token the white house",
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("hits =\n  %s\nwant\n  %s", strings.Join(got, "\n  "), strings.Join(want, "\n  "))
+	}
+
+	// Pages must be slices of the full ranking, each hydrated
+	for offset := 0; offset < len(all); offset += 3 {
+		page, err := db.ReverseLookup(ctx, dict, "house", ReverseOptions{Limit: 3, Offset: offset})
+		if err != nil {
+			t.Fatalf("ReverseLookup failed: %v", err)
+		}
+		for i, hit := range page {
+			if hit.Entry.ID != all[offset+i].Entry.ID {
+				t.Errorf("offset %d hit %d = %s, want %s", offset, i, hit.Entry.Value, all[offset+i].Entry.Value)
+			}
+			if len(hit.Entry.TargetLangs) == 0 {
+				t.Errorf("offset %d hit %d is not hydrated", offset, i)
+			}
+		}
+	}
+
+	page, err := db.ReverseLookup(ctx, dict, "house", ReverseOptions{Offset: len(all)})
+	if err != nil {
+		t.Fatalf("ReverseLookup failed: %v", err)
+	}
+	if len(page) != 0 {
+		t.Errorf("page past the end has %d hits", len(page))
+	}
+}
+
+func TestReverseLookupScansOnlyTheDictionary(t *testing.T) {
+	ctx := context.Background()
+
+	// A dictionary imported first whose translations fill every capped
+	// scan for "house"
+	var b strings.Builder
+	b.WriteString(`<Dictionary BaseLang="swe" TargetLang="deu" Version="1">`)
+	for i := 0; i < reverseCandidateLimit+100; i++ {
+		fmt.Fprintf(&b, `<Word Value="ord%04d" Type="subst." ID="%d" VariantID="1">
+			<TargetLang><Translation>house %04d</Translation></TargetLang>
+		</Word>`, i, i+1, i)
+	}
+	b.WriteString(`</Dictionary>`)
+	db, _ := openTestDB(t, b.String())
+	dict := importTestXML(t, db, reverseTestXML())
+
+	hits, err := db.ReverseLookup(ctx, dict, "house", ReverseOptions{Limit: 100})
+	if err != nil {
+		t.Fatalf("ReverseLookup failed: %v", err)
+	}
+	var got []string
+	for _, hit := range hits {
+		if hit.Entry.DictionaryID != dict.ID {
+			t.Errorf("hit %s is from another dictionary", hit.Entry.Value)
+		}
+		got = append(got, hit.Matched)
+	}
+	want := []string{"house", "home, house", "houses", "house boat", "house of cards", "a house", "the white house"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("hits = %q, want %q", got, want)
+	}
+
+}
@@ -0,0 +1,74 @@
+package lexin
+
+import (
+	"context"
+	"fmt"
+)
+
+// DictionaryStats counts what is stored for one dictionary
+type DictionaryStats struct {
+	Dictionary   Dictionary `json:"dictionary"`
+	Words        int64      `json:"words"`
+	Headwords    int64      `json:"headwords"`
+	Meanings     int64      `json:"meanings"`
+	Translations int64      `json:"translations"`
+	Examples     int64      `json:"examples"`
+	Idioms       int64      `json:"idioms"`
+	Compounds    int64      `json:"compounds"`
+	Forms        int64      `json:"forms"`
+}
+
+// Stats returns statistics for every dictionary in the database
+func (d *DB) Stats(ctx context.Context) ([]DictionaryStats, error) {
+	dictionaries, err := d.Dictionaries(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make([]DictionaryStats, 0, len(dictionaries))
+	for _, dict := range dictionaries {
+		s := DictionaryStats{Dictionary: dict}
+		err := d.db.GetDB().QueryRowContext(ctx, `
+			SELECT
+				(SELECT COUNT(*) FROM words WHERE dictionary_id = ?1),
+				(SELECT COUNT(DISTINCT value) FROM words WHERE dictionary_id = ?1),
+				(SELECT COUNT(*) FROM base_langs b
+					JOIN words w ON w.id = b.word_id
+					WHERE w.dictionary_id = ?1),
+				(SELECT COUNT(*) FROM translations t
+					JOIN target_langs tl ON tl.id = t.target_lang_id
+					JOIN words w ON w.id = tl.word_id
+					WHERE w.dictionary_id = ?1),
+				(SELECT COUNT(*) FROM examples e
+					JOIN base_langs b ON b.id = e.base_lang_id
+					JOIN words w ON w.id = b.word_id
+					WHERE w.dictionary_id = ?1),
+				(SELECT COUNT(*) FROM idioms i
+					JOIN base_langs b ON b.id = i.base_lang_id
+					JOIN words w ON w.id = b.word_id
+					WHERE w.dictionary_id = ?1),
+				(SELECT COUNT(*) FROM compounds c
+					JOIN base_langs b ON b.id = c.base_lang_id
+					JOIN words w ON w.id = b.word_id
+					WHERE w.dictionary_id = ?1),
+				(SELECT COUNT(*) FROM word_forms f
+					JOIN words w ON w.id = f.word_id
+					WHERE w.dictionary_id = ?1)
+		`, dict.ID).Scan(
+			&s.Words,
+			&s.Headwords,
+			&s.Meanings,
+			&s.Translations,
+			&s.Examples,
+			&s.Idioms,
+			&s.Compounds,
+			&s.Forms,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count %s-%s: %w", dict.BaseLang, dict.TargetLang, err)
+		}
+		stats = append(stats, s)
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,155 @@
+package lexin
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"lexin-sqlite/internal/fuzzy"
+)
+
+// Suggestion is a headword close to a misspelled lookup
+type Suggestion struct {
+	WordID       int64   `json:"word_id"`
+	DictionaryID int64   `json:"dictionary_id"`
+	Value        string  `json:"value"`
+	Type         string  `json:"type"`
+	Distance     float64 `json:"distance"`
+}
+
+// SuggestOptions tunes suggestions
+type SuggestOptions struct {
+	// Limit caps the suggestions returned per dictionary, 5 when zero
+	Limit int
+	// MaxDistance is the largest edit distance accepted. When zero it
+	// depends on the length of the word: 1 up to four letters, 2 above.
+	MaxDistance float64
+}
+
+// suggestCandidateLimit caps the words of each dictionary taken from the
+// trigram index before distances are computed
+const suggestCandidateLimit = 500
+
+// Suggest returns "did you mean" headwords for word, ranked by edit
+// distance. Missing or confused diacritics (vag, väg, våg) cost a quarter of
+// a normal edit. Candidates come from the trigram index, and for short words
+// the deletion index, so the dictionary is never scanned in full.
+func (d *DB) Suggest(ctx context.Context, dict *Dictionary, word string, opts SuggestOptions) ([]Suggestion, error) {
+	if opts.Limit <= 0 {
+		opts.Limit = 5
+	}
+	if opts.MaxDistance <= 0 {
+		opts.MaxDistance = fuzzy.MaxDistance(word)
+	}
+
+	trigrams := fuzzy.Trigrams(word)
+	if len(trigrams) == 0 {
+		return []Suggestion{}, nil
+	}
+
+	args := make([]interface{}, 0, len(trigrams)+2)
+	for _, trigram := range trigrams {
+		args = append(args, trigram)
+	}
+	candidates := `
+			SELECT word_id, COUNT(*) AS shared
+			FROM word_trigrams
+			WHERE trigram IN (` + placeholders(len(trigrams)) + `)
+			GROUP BY word_id`
+
+	// A typo in a short word can leave only the edge trigram shared, a tie
+	// with every word of the same first letter. Words one edit away share
+	// a deletion, and rank above every trigram candidate.
+	if len([]rune(fuzzy.Fold(word))) < fuzzy.DeleteIndexLength {
+		deletes := fuzzy.Deletes(word)
+		candidates += `
+			UNION ALL
+			SELECT DISTINCT word_id, ` + fmt.Sprint(len(trigrams)+1) + `
+			FROM word_deletes
+			WHERE variant IN (` + placeholders(len(deletes)) + `)`
+		for _, variant := range deletes {
+			args = append(args, variant)
+		}
+	}
+
+	// Candidates are ranked and capped within each dictionary, so that a
+	// large dictionary cannot crowd the others out
+	filter := ""
+	if dict != nil {
+		filter = ` WHERE w.dictionary_id = ?`
+		args = append(args, dict.ID)
+	}
+	query := `
+		SELECT id, dictionary_id, value, type
+		FROM (
+			SELECT w.id, w.dictionary_id, w.value, w.type,
+				ROW_NUMBER() OVER (
+					PARTITION BY w.dictionary_id ORDER BY t.shared DESC, w.id
+				) AS rank
+			FROM (
+				SELECT word_id, MAX(shared) AS shared
+				FROM (` + candidates + `
+				)
+				GROUP BY word_id
+			) t
+			JOIN words w ON w.id = t.word_id` + filter + `
+		)
+		WHERE rank <= ?
+		ORDER BY dictionary_id, rank`
+	args = append(args, suggestCandidateLimit)
+
+	rows, err := d.db.GetDB().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find suggestions for %q: %w", word, err)
+	}
+	defer rows.Close()
+
+	type key struct {
+		dictionaryID int64
+		value        string
+	}
+	seen := make(map[key]bool)
+	suggestions := []Suggestion{}
+	for rows.Next() {
+		var s Suggestion
+		if err := rows.Scan(&s.WordID, &s.DictionaryID, &s.Value, &s.Type); err != nil {
+			return nil, fmt.Errorf("failed to find suggestions for %q: %w", word, err)
+		}
+
+		// Homographs are suggested once per dictionary
+		k := key{s.DictionaryID, s.Value}
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+
+		s.Distance = fuzzy.Distance(word, s.Value)
+		if s.Distance > opts.MaxDistance {
+			continue
+		}
+		suggestions = append(suggestions, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to find suggestions for %q: %w", word, err)
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		if suggestions[i].Distance != suggestions[j].Distance {
+			return suggestions[i].Distance < suggestions[j].Distance
+		}
+		return suggestions[i].Value < suggestions[j].Value
+	})
+
+	// Keep the best suggestions of each dictionary
+	perDictionary := make(map[int64]int)
+	limited := suggestions[:0]
+	for _, s := range suggestions {
+		if perDictionary[s.DictionaryID] >= opts.Limit {
+			continue
+		}
+		perDictionary[s.DictionaryID]++
+		limited = append(limited, s)
+	}
+
+	return limited, nil
+}
@@ -0,0 +1,94 @@
+package lexin
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSuggestFindsShortTranspositions(t *testing.T) {
+	// More short h-words than the candidate limit, stored before "hus", that
+	// share only the edge trigram with "hsu" and are far from it
+	letters := "abcdefgijk"
+	var b strings.Builder
+	b.WriteString(`<Dictionary BaseLang="swe" TargetLang="eng" Version="1">`)
+	id := 0
+	word := func(value string) {
+		id++
+		fmt.Fprintf(&b, `<Word Value="%s" Type="subst." ID="%d" VariantID="1"><BaseLang><Meaning>m</Meaning></BaseLang></Word>`, value, id)
+	}
+	for _, x := range letters {
+		for _, y := range letters {
+			for _, z := range letters {
+				word("h" + string(x) + string(y) + string(z))
+			}
+		}
+	}
+	word("hus")
+	b.WriteString(`</Dictionary>`)
+	if id <= suggestCandidateLimit {
+		t.Fatalf("only %d words, the test needs more than %d", id, suggestCandidateLimit)
+	}
+
+	db, dict := openTestDB(t, b.String())
+
+	for _, tc := range []struct {
+		query string
+		want  string
+	}{
+		{"hsu", "hus"},   // transposition
+		{"hu", "hus"},    // deletion
+		{"huss", "hus"},  // insertion
+		{"hös", "hus"},   // substitution
+		{"hacd", "hacd"}, // exact
+	} {
+		suggestions, err := db.Suggest(context.Background(), dict, tc.query, SuggestOptions{})
+		if err != nil {
+			t.Fatalf("Suggest(%q) failed: %v", tc.query, err)
+		}
+		if len(suggestions) == 0 || suggestions[0].Value != tc.want {
+			t.Errorf("Suggest(%q) = %v, want %s first", tc.query, suggestions, tc.want)
+		}
+	}
+}
+
+func TestSuggestLimitsCandidatesPerDictionary(t *testing.T) {
+	ctx := context.Background()
+
+	// More words than the candidate limit sharing as many trigrams with
+	// "huset" as the word one edit away in the second dictionary
+	letters := "abcdefghijklmnopqrsuvwxy"
+	var b strings.Builder
+	b.WriteString(`<Dictionary BaseLang="swe" TargetLang="eng" Version="1">`)
+	id := 0
+	for _, x := range letters {
+		for _, y := range letters {
+			id++
+			fmt.Fprintf(&b, `<Word Value="huse%c%c" Type="subst." ID="%d" VariantID="1"/>`, x, y, id)
+		}
+	}
+	b.WriteString(`</Dictionary>`)
+	if id <= suggestCandidateLimit {
+		t.Fatalf("only %d words, the test needs more than %d", id, suggestCandidateLimit)
+	}
+
+	db, _ := openTestDB(t, b.String())
+	other := importTestXML(t, db, `<Dictionary BaseLang="swe" TargetLang="deu" Version="1">
+		<Word Value="hueset" Type="subst." ID="1" VariantID="1"/>
+	</Dictionary>`)
+
+	suggestions, err := db.Suggest(ctx, nil, "huset", SuggestOptions{})
+	if err != nil {
+		t.Fatalf("Suggest failed: %v", err)
+	}
+	found := false
+	for _, s := range suggestions {
+		if s.DictionaryID == other.ID && s.Value == "hueset" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Suggest(huset) = %+v, want hueset from the second dictionary", suggestions)
+	}
+}